@@ -0,0 +1,119 @@
+// affect.go
+package main
+
+import "fmt"
+
+// Affect is how a mind feels: valence from -1 (unpleasant) to 1 (pleasant)
+// and arousal from 0 (calm) to 1 (agitated). What happens to the mind moves
+// it; left alone it drifts back to neutral. In turn it colours cognition:
+// see IntrospectGain, Threshold and the policies.
+type Affect struct {
+	Valence float64 `json:"valence"`
+	Arousal float64 `json:"arousal"`
+}
+
+// Mood is the named region of valence and arousal a mind is in.
+type Mood string
+
+const (
+	MoodNeutral   Mood = "neutral"
+	MoodContent   Mood = "content"   // Pleasant and calm
+	MoodExcited   Mood = "excited"   // Pleasant and aroused
+	MoodIrritable Mood = "irritable" // Unpleasant and aroused
+	MoodGloomy    Mood = "gloomy"    // Unpleasant and calm
+)
+
+// Valence beyond this, either way, and arousal from this up, count towards a mood.
+const (
+	moodValence = 0.25
+	moodArousal = 0.5
+)
+
+// Mood names the affect.
+func (a Affect) Mood() Mood {
+	aroused := a.Arousal >= moodArousal
+	switch {
+	case a.Valence >= moodValence && aroused:
+		return MoodExcited
+	case a.Valence >= moodValence:
+		return MoodContent
+	case a.Valence <= -moodValence && aroused:
+		return MoodIrritable
+	case a.Valence <= -moodValence:
+		return MoodGloomy
+	}
+	return MoodNeutral
+}
+
+func (a Affect) String() string {
+	return fmt.Sprintf("%s (valence %+.2f, arousal %.2f)", a.Mood(), a.Valence, a.Arousal)
+}
+
+// IntrospectGain scales an introspection gain: a good mood helps the mind see
+// clearly and agitation gets in the way. With affect_influence k the factor
+// is 1 + k*(valence - arousal/2), never below 0.
+func (a Affect) IntrospectGain(gain float64, cfg *Config) float64 {
+	return gain * max(1+cfg.AffectInfluence*(a.Valence-a.Arousal/2), 0)
+}
+
+// Threshold is the expression threshold the mind actually works to: a good
+// mood or agitation make it readier to speak, a bad calm mood more reticent.
+// It moves by affect_influence*(valence + arousal)/5, within 0..1.
+func (a Affect) Threshold(base float64, cfg *Config) float64 {
+	return min(max(base-cfg.AffectInfluence*(a.Valence+a.Arousal)/5, 0), 1)
+}
+
+// move shifts the affect, keeping it in range.
+func (a *Affect) move(valence, arousal float64) {
+	a.Valence = min(max(a.Valence+valence, -1), 1)
+	a.Arousal = min(max(a.Arousal+arousal, 0), 1)
+}
+
+// feel updates the mind's affect from the events of its turn and returns a
+// MoodChanged event if its mood changed.
+func (ctx *MindContext) feel(entityID string, events []Event) []Event {
+	cfg := ctx.Config
+	before := ctx.Affect.Mood()
+	for _, e := range events {
+		switch e.Kind {
+		case EventExpressed:
+			ctx.Affect.move(cfg.ExpressValence, cfg.ExpressValence/2)
+		case EventExpressFailed:
+			ctx.Affect.move(-cfg.ExpressValence, cfg.FrustrationArousal)
+		case EventEnergyInsufficient:
+			ctx.Affect.move(-cfg.FrustrationArousal/2, cfg.FrustrationArousal)
+		case EventEvolved:
+			ctx.Affect.move(cfg.EvolveValence, 0)
+		case EventDreamed:
+			ctx.Affect.move(0, -ctx.Affect.Arousal/2) // Sleep calms
+		}
+	}
+	return ctx.moodChange(entityID, before)
+}
+
+// settle runs one tick of affect: a mind low on energy grows irritable, and
+// otherwise its feelings fade by affect_decay towards neutral. It returns a
+// MoodChanged event if the mood changed.
+func (ctx *MindContext) settle(entityID string) []Event {
+	cfg := ctx.Config
+	before := ctx.Affect.Mood()
+	if float64(ctx.Energy) < cfg.LowEnergyFraction*float64(ctx.MaxEnergy) {
+		ctx.Affect.move(-cfg.IrritationRate, cfg.IrritationRate)
+	} else {
+		ctx.Affect.move(-ctx.Affect.Valence*cfg.AffectDecay, -ctx.Affect.Arousal*cfg.AffectDecay)
+	}
+	return ctx.moodChange(entityID, before)
+}
+
+func (ctx *MindContext) moodChange(entityID string, before Mood) []Event {
+	if after := ctx.Affect.Mood(); after != before {
+		return []Event{{Kind: EventMoodChanged, Entity: entityID, From: string(before), To: string(after)}}
+	}
+	return nil
+}
+
+// EffectiveThreshold returns the threshold the mind currently has to reach
+// to express a thought, as moved by its affect.
+func (ctx *MindContext) EffectiveThreshold() float64 {
+	return ctx.Affect.Threshold(ctx.ExpressionThreshold, ctx.Config)
+}
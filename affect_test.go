@@ -0,0 +1,139 @@
+// affect_test.go
+package main
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAffect_Moods(t *testing.T) {
+	tests := []struct {
+		affect Affect
+		want   Mood
+	}{
+		{Affect{0, 0}, MoodNeutral},
+		{Affect{0.2, 0.9}, MoodNeutral},
+		{Affect{0.25, 0.1}, MoodContent},
+		{Affect{0.8, 0.5}, MoodExcited},
+		{Affect{-0.5, 0.7}, MoodIrritable},
+		{Affect{-0.3, 0.2}, MoodGloomy},
+	}
+	for _, tt := range tests {
+		if got := tt.affect.Mood(); got != tt.want {
+			t.Errorf("%+v: Expected %s, got %s", tt.affect, tt.want, got)
+		}
+	}
+}
+
+func TestAffect_ExpressionMovesFeelings(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	events := ctx.feel("e", []Event{{Kind: EventExpressed}})
+	if math.Abs(ctx.Affect.Valence-0.3) > 1e-9 || math.Abs(ctx.Affect.Arousal-0.15) > 1e-9 {
+		t.Errorf("Expressed: Expected valence 0.30 and arousal 0.15, got %+v", ctx.Affect)
+	}
+	if len(events) != 1 || events[0].Kind != EventMoodChanged || events[0].To != string(MoodContent) || events[0].String() != "e is feeling content." {
+		t.Errorf("Expressed: Expected a change to content, got %+v", events)
+	}
+
+	ctx.feel("e", []Event{{Kind: EventExpressFailed}, {Kind: EventExpressFailed}, {Kind: EventExpressFailed}})
+	if ctx.Affect.Mood() != MoodIrritable {
+		t.Errorf("ExpressFailed: Expected repeated failure to make the mind irritable, got %s", ctx.Affect)
+	}
+	ctx.feel("e", []Event{{Kind: EventDreamed}})
+	if math.Abs(ctx.Affect.Arousal-0.375) > 1e-9 {
+		t.Errorf("Dreamed: Expected arousal halved to 0.375, got %+v", ctx.Affect)
+	}
+}
+
+func TestAffect_LowEnergyIrritatesAndFeelingsFade(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Energy = 10
+	for range 20 {
+		ctx.settle("e")
+	}
+	if ctx.Affect.Mood() != MoodIrritable {
+		t.Errorf("settle: Expected a mind low on energy to grow irritable, got %s", ctx.Affect)
+	}
+
+	ctx.Energy = ctx.MaxEnergy
+	valence := ctx.Affect.Valence
+	ctx.settle("e")
+	if math.Abs(ctx.Affect.Valence-valence*0.95) > 1e-9 {
+		t.Errorf("settle: Expected valence to fade by affect_decay, got %.3f from %.3f", ctx.Affect.Valence, valence)
+	}
+}
+
+func TestAffect_ModulatesIntrospectionAndThreshold(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.IntrospectGainMin, cfg.IntrospectGainMax = 0.2, 0.2
+	ctx := NewMindContext(cfg)
+	ctx.Affect = Affect{Valence: 1, Arousal: 0}
+	ctx.AddThought("a thought", OriginGenerated)
+	ctx.CurrentFocusIndex = 0
+
+	(&ReflectingState{}).HandleInput("e", ctx, strings.Fields("introspect"))
+	if math.Abs(ctx.Thoughts[0].Clarity-0.4) > 1e-9 { // 0.1 + 0.2 * 1.5
+		t.Errorf("introspect: Expected a happy mind to gain 0.30, got clarity %.2f", ctx.Thoughts[0].Clarity)
+	}
+
+	ctx.Affect = Affect{Valence: -1, Arousal: 0}
+	if got := ctx.EffectiveThreshold(); math.Abs(got-0.8) > 1e-9 {
+		t.Errorf("EffectiveThreshold: Expected an unhappy calm mind to need 0.80, got %.2f", got)
+	}
+	ctx.Thoughts[0].Clarity = 0.75
+	_, events := (&ActingState{}).HandleInput("e", ctx, strings.Fields("express"))
+	if events[0].Kind != EventExpressFailed || math.Abs(events[0].Threshold-0.8) > 1e-9 {
+		t.Errorf("express: Expected failure against the moved threshold, got %+v", events[0])
+	}
+
+	cfg.AffectInfluence = 0
+	if ctx.EffectiveThreshold() != ctx.ExpressionThreshold {
+		t.Errorf("EffectiveThreshold: Expected no effect with affect_influence 0")
+	}
+}
+
+func TestAffect_PolicyFollowsMood(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	entity.Mind.AddThought("a thought", OriginGenerated).Clarity = 0.5
+	entity.Mind.CurrentFocusIndex = 0
+	policy := &RandomHeuristicPolicy{}
+
+	entity.Mind.Affect = Affect{Valence: -0.5, Arousal: 0.1}
+	entity.CurrentFSMState = &ReflectingState{}
+	for seed := range int64(20) {
+		if got := policy.Decide(entity.View(), NewRNG(seed)); got.Name != "introspect" {
+			t.Fatalf("gloomy: Expected to always introspect, got %s", got)
+		}
+	}
+
+	entity.Mind.Affect = Affect{Valence: -0.5, Arousal: 0.8}
+	entity.Mind.Energy = 50
+	entity.CurrentFSMState = &IdleState{}
+	for seed := range int64(20) {
+		if got := policy.Decide(entity.View(), NewRNG(seed)); got.Name != "recharge" && got.Name != "sleep" {
+			t.Fatalf("irritable: Expected to rest at half energy, got %s", got)
+		}
+	}
+}
+
+func TestAffect_SavedAndSimulated(t *testing.T) {
+	sim := NewSimulation(3, NewDefaultEntities())
+	sim.SetAutopilot(true)
+	sim.Run(context.Background(), 100)
+	sim.Player().Mind.Affect = Affect{Valence: -0.4, Arousal: 0.6}
+
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Player().Mind.Affect; got != (Affect{Valence: -0.4, Arousal: 0.6}) {
+		t.Errorf("loadGame: Expected the affect kept, got %+v", got)
+	}
+}
@@ -0,0 +1,760 @@
+// behavior.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A behavior tree decides with a hierarchy of nodes, ticked from the root once
+// per decision:
+//
+//   - selector tries its children in order until one doesn't fail
+//   - sequence runs its children in order until one doesn't succeed
+//   - random-selector is a selector that shuffles its children each time it starts
+//   - condition tests the mind, e.g. "energy < 20" or "clarity >= threshold"
+//   - action issues a command, e.g. "focus clearest"
+//   - decorator changes what its one child reports: invert, succeed,
+//     "cooldown N" or "chance P"
+//
+// An action fails if its command can't be carried out now: there is no rule
+// for it in the current state, the energy or focus its guard asks for is
+// missing, or an argument like "clearest" has nothing to refer to. Otherwise
+// it issues the command and reports running, which ends the decision; the
+// next decision resumes the running nodes, and the action succeeds. Nodes
+// that aren't resumed start afresh.
+//
+// Trees are written as JSON or as S-expressions, see ParseBehaviorTree.
+
+// btStatus is what a node reports when ticked.
+type btStatus int
+
+const (
+	btFailure btStatus = iota
+	btSuccess
+	btRunning
+)
+
+// btNode is a node of a behavior tree.
+type btNode interface {
+	base() *btBase
+	tick(t *btTick, resumed bool) btStatus
+	label() string
+	children() []btNode
+}
+
+// btBase records when a node was last ticked and how it went, for resuming
+// and for the debug view.
+type btBase struct {
+	last   int // Tick the node was last ticked on, 0 for never
+	status btStatus
+}
+
+func (b *btBase) base() *btBase { return b }
+
+// btTick carries a decision through the tree.
+type btTick struct {
+	n        int
+	decision int // Ticks of finished trees are repeated within a decision, so cooldowns count these
+	view     EntityView
+	rng      *RNG
+	issued   Command
+}
+
+// run ticks a node, telling it whether it is resuming after reporting running.
+func (t *btTick) run(n btNode) btStatus {
+	b := n.base()
+	resumed := b.last == t.n-1 && b.status == btRunning
+	b.status, b.last = n.tick(t, resumed), t.n
+	return b.status
+}
+
+// btComposite is a selector, sequence or random-selector.
+type btComposite struct {
+	btBase
+	kind  string
+	kids  []btNode
+	order []int // The order the children are tried in
+	next  int   // Position in order of the child to tick
+}
+
+func (c *btComposite) tick(t *btTick, resumed bool) btStatus {
+	if !resumed {
+		c.next = 0
+		c.order = c.order[:0]
+		for i := range c.kids {
+			c.order = append(c.order, i)
+		}
+		if c.kind == "random-selector" {
+			t.rng.Shuffle(len(c.order), func(i, j int) { c.order[i], c.order[j] = c.order[j], c.order[i] })
+		}
+	}
+	// A sequence carries on while its children succeed, a selector while they fail.
+	carryOn := btFailure
+	if c.kind == "sequence" {
+		carryOn = btSuccess
+	}
+	for ; c.next < len(c.order); c.next++ {
+		if status := t.run(c.kids[c.order[c.next]]); status != carryOn {
+			return status
+		}
+	}
+	return carryOn
+}
+
+func (c *btComposite) label() string      { return c.kind }
+func (c *btComposite) children() []btNode { return c.kids }
+
+// btCondition compares a field of the mind with a value or another field.
+type btCondition struct {
+	btBase
+	field, op, value string
+}
+
+// btNumbers are the numeric fields conditions can read.
+var btNumbers = map[string]func(v EntityView) float64{
+	"energy":     func(v EntityView) float64 { return float64(v.Energy) },
+	"max_energy": func(v EntityView) float64 { return float64(v.MaxEnergy) },
+	"thoughts":   func(v EntityView) float64 { return float64(len(v.Thoughts)) },
+	"inbox":      func(v EntityView) float64 { return float64(len(v.Inbox)) },
+	"goals":      func(v EntityView) float64 { return float64(len(v.Goals)) },
+	"focus":      func(v EntityView) float64 { return float64(v.FocusIndex) },
+	"clarity":    func(v EntityView) float64 { return v.FocusedClarity() },
+	"clearest": func(v EntityView) float64 {
+		if i := btClearest(v); i >= 0 {
+			return v.Thoughts[i].Clarity
+		}
+		return 0
+	},
+	"threshold": func(v EntityView) float64 { return v.Threshold() },
+	"valence":   func(v EntityView) float64 { return v.Affect.Valence },
+	"arousal":   func(v EntityView) float64 { return v.Affect.Arousal },
+}
+
+// btWords are the fields conditions can read that are names, compared with
+// == and != only.
+var btWords = map[string]func(v EntityView) string{
+	"state": func(v EntityView) string { return v.State },
+	"mood":  func(v EntityView) string { return string(v.Affect.Mood()) },
+}
+
+var btOps = []string{"<", "<=", ">", ">=", "==", "!="}
+
+func newBTCondition(text string) (*btCondition, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("condition %q: expected <field> <op> <value>", text)
+	}
+	c := &btCondition{field: fields[0], op: fields[1], value: fields[2]}
+	if !slices.Contains(btOps, c.op) {
+		return nil, fmt.Errorf("condition %q: unknown operator %q (available: %s)", text, c.op, strings.Join(btOps, " "))
+	}
+	if btWords[c.field] != nil {
+		if c.op != "==" && c.op != "!=" {
+			return nil, fmt.Errorf("condition %q: %s can only be compared with == or !=", text, c.field)
+		}
+		if _, err := getStateByName(c.value); c.field == "state" && err != nil {
+			return nil, fmt.Errorf("condition %q: %w", text, err)
+		}
+		if c.field == "mood" && !slices.Contains([]Mood{MoodNeutral, MoodContent, MoodExcited, MoodIrritable, MoodGloomy}, Mood(c.value)) {
+			return nil, fmt.Errorf("condition %q: unknown mood %q", text, c.value)
+		}
+		return c, nil
+	}
+	if btNumbers[c.field] == nil {
+		return nil, fmt.Errorf("condition %q: unknown field %q (available: %s)", text, c.field, strings.Join(btFields(), ", "))
+	}
+	if _, err := strconv.ParseFloat(c.value, 64); err != nil && btNumbers[c.value] == nil {
+		return nil, fmt.Errorf("condition %q: %q is neither a number nor a numeric field", text, c.value)
+	}
+	return c, nil
+}
+
+// btFields lists the fields conditions can read.
+func btFields() []string {
+	var fields []string
+	for name := range btNumbers {
+		fields = append(fields, name)
+	}
+	for name := range btWords {
+		fields = append(fields, name)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+func (c *btCondition) tick(t *btTick, resumed bool) btStatus {
+	var holds bool
+	if word := btWords[c.field]; word != nil {
+		holds = (word(t.view) == c.value) == (c.op == "==")
+	} else {
+		a := btNumbers[c.field](t.view)
+		b, err := strconv.ParseFloat(c.value, 64)
+		if err != nil {
+			b = btNumbers[c.value](t.view) // Checked by newBTCondition
+		}
+		switch c.op {
+		case "<":
+			holds = a < b
+		case "<=":
+			holds = a <= b
+		case ">":
+			holds = a > b
+		case ">=":
+			holds = a >= b
+		case "==":
+			holds = a == b
+		case "!=":
+			holds = a != b
+		}
+	}
+	if holds {
+		return btSuccess
+	}
+	return btFailure
+}
+
+func (c *btCondition) label() string      { return "condition " + c.text() }
+func (c *btCondition) text() string       { return c.field + " " + c.op + " " + c.value }
+func (c *btCondition) children() []btNode { return nil }
+
+// btAction issues a command. Arguments may refer to the mind: "clearest" and
+// "newest" are thought indices, "heard" the index of the newest perception.
+type btAction struct {
+	btBase
+	cmd Command
+}
+
+// btPlaceholders maps each placeholder to the list its index is into.
+var btPlaceholders = map[string]string{"clearest": thoughtIndex, "newest": thoughtIndex, "heard": perceptionIndex}
+
+// newBTAction checks an action's command against the transition table the
+// tree will run with, and its arguments against what the command's rules
+// take. The arguments only have to suit one of the rules, as the action fails
+// in the other states anyway.
+func newBTAction(text string, rules *TransitionTable) (*btAction, error) {
+	cmd := NewCommand(strings.Fields(text)...)
+	if cmd.IsZero() {
+		return nil, errors.New("action needs a command")
+	}
+	var known []string
+	var err error
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if !slices.Contains(known, rule.Command) {
+			known = append(known, rule.Command)
+		}
+		if rule.Command != cmd.Name {
+			continue
+		}
+		if err = btCheckArgs(cmd, rule); err == nil {
+			return &btAction{cmd: cmd}, nil
+		}
+	}
+	if err == nil {
+		return nil, fmt.Errorf("action %q: unknown command %q (available: %s)", text, cmd.Name, strings.Join(known, ", "))
+	}
+	return nil, fmt.Errorf("action %q: %w", text, err)
+}
+
+// btCheckArgs checks a command's arguments against a rule for it: there must
+// be as many as its guard asks for, and placeholders may only stand where the
+// rule's effects read an index into the same list.
+func btCheckArgs(cmd Command, rule *TransitionRule) error {
+	if len(cmd.Args) < rule.Guard.MinArgs {
+		usage := rule.Usage
+		if usage == "" {
+			usage = rule.Command
+		}
+		return fmt.Errorf("usage is %s", usage)
+	}
+	slots := rule.indices()
+	for i, arg := range cmd.Args {
+		list, placeholder := btPlaceholders[arg]
+		switch {
+		case i >= len(slots) && placeholder:
+			return fmt.Errorf("%q stands for an index, but argument %d of %s isn't one", arg, i+1, cmd.Name)
+		case i >= len(slots):
+		case placeholder && list != slots[i]:
+			return fmt.Errorf("%q is a %s index, but %s expects a %s index", arg, list, cmd.Name, slots[i])
+		case !placeholder:
+			if n, err := strconv.Atoi(arg); err != nil || n < 0 {
+				return fmt.Errorf("%q is neither a %s index nor one of %s", arg, slots[i], strings.Join(btPlaceholdersFor(slots[i]), ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// btPlaceholdersFor lists the placeholders that index into list.
+func btPlaceholdersFor(list string) []string {
+	var names []string
+	for name, l := range btPlaceholders {
+		if l == list {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (a *btAction) tick(t *btTick, resumed bool) btStatus {
+	if resumed {
+		return btSuccess
+	}
+	view := t.view
+	cmd := Command{Name: a.cmd.Name}
+	for _, arg := range a.cmd.Args {
+		index := -1
+		switch arg {
+		case "clearest":
+			index = btClearest(view)
+		case "newest":
+			index = len(view.Thoughts) - 1
+		case "heard":
+			index = len(view.Inbox) - 1
+		default:
+			cmd.Args = append(cmd.Args, arg)
+			continue
+		}
+		if index < 0 {
+			return btFailure
+		}
+		cmd.Args = append(cmd.Args, strconv.Itoa(index))
+	}
+
+	rule := view.Rules.Lookup(view.State, cmd.Name)
+	if rule == nil || len(cmd.Args) < rule.Guard.MinArgs || (rule.Guard.RequiresFocus && !view.HasFocus()) {
+		return btFailure
+	}
+	if view.Energy < max(rule.Guard.MinEnergy.resolve(&view.Config), rule.Cost.resolve(&view.Config)) {
+		return btFailure
+	}
+	t.issued = cmd
+	return btRunning
+}
+
+func (a *btAction) label() string      { return "action " + a.cmd.String() }
+func (a *btAction) children() []btNode { return nil }
+
+// btClearest returns the index of the clearest thought, or -1 if the mind is
+// empty.
+func btClearest(v EntityView) int {
+	best := -1
+	for i, t := range v.Thoughts {
+		if best < 0 || t.Clarity > v.Thoughts[best].Clarity {
+			best = i
+		}
+	}
+	return best
+}
+
+// btDecorator changes what its child reports:
+//
+//   - invert swaps success and failure
+//   - succeed turns failure into success
+//   - "cooldown N" fails for N decisions after the one its child succeeds in
+//   - "chance P" ticks its child with probability P and fails otherwise
+type btDecorator struct {
+	btBase
+	kind   string
+	amount float64
+	child  btNode
+	ready  int // Decision a cooldown is over on
+}
+
+func newBTDecorator(text string, child btNode) (*btDecorator, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, errors.New("decorator: missing kind (invert, succeed, cooldown N or chance P)")
+	}
+	d := &btDecorator{kind: fields[0], child: child}
+	switch d.kind {
+	case "invert", "succeed":
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("decorator %q: %s takes no argument", text, d.kind)
+		}
+	case "cooldown", "chance":
+		var err error
+		if len(fields) == 2 {
+			d.amount, err = strconv.ParseFloat(fields[1], 64)
+		}
+		if len(fields) != 2 || err != nil {
+			return nil, fmt.Errorf("decorator %q: expected %s <number>", text, d.kind)
+		}
+		if d.kind == "cooldown" && (d.amount < 0 || d.amount != float64(int(d.amount))) {
+			return nil, fmt.Errorf("decorator %q: cooldown must be a whole number of ticks", text)
+		}
+		if d.kind == "chance" && (d.amount < 0 || d.amount > 1) {
+			return nil, fmt.Errorf("decorator %q: chance must be between 0 and 1", text)
+		}
+	default:
+		return nil, fmt.Errorf("decorator %q: unknown kind %q (available: invert, succeed, cooldown, chance)", text, d.kind)
+	}
+	return d, nil
+}
+
+func (d *btDecorator) tick(t *btTick, resumed bool) btStatus {
+	if !resumed {
+		if d.kind == "cooldown" && t.decision < d.ready {
+			return btFailure
+		}
+		if d.kind == "chance" && t.rng.Float64() >= d.amount {
+			return btFailure
+		}
+	}
+	status := t.run(d.child)
+	switch {
+	case status == btRunning:
+		return btRunning
+	case d.kind == "invert":
+		return btSuccess + btFailure - status
+	case d.kind == "succeed":
+		return btSuccess
+	case d.kind == "cooldown" && status == btSuccess:
+		d.ready = t.decision + 1 + int(d.amount)
+	}
+	return status
+}
+
+func (d *btDecorator) label() string {
+	if d.kind == "cooldown" || d.kind == "chance" {
+		return fmt.Sprintf("decorator %s %g", d.kind, d.amount)
+	}
+	return "decorator " + d.kind
+}
+func (d *btDecorator) children() []btNode { return []btNode{d.child} }
+
+// BehaviorTreePolicy decides with a behavior tree loaded from a file, so
+// personalities can be written without touching Go. Its name, "tree:" and
+// the file, is what saves record; a loaded game reads the file again and the
+// tree starts afresh.
+type BehaviorTreePolicy struct {
+	File string
+	Tree *BehaviorTree
+}
+
+// treePolicyPrefix marks a policy name as a behavior tree file.
+const treePolicyPrefix = "tree:"
+
+func (p *BehaviorTreePolicy) Name() string { return treePolicyPrefix + p.File }
+
+func (p *BehaviorTreePolicy) Decide(view EntityView, rng *RNG) Command {
+	return p.Tree.decide(view, rng)
+}
+
+// BehaviorTree is a parsed tree and the state of its running nodes.
+type BehaviorTree struct {
+	root      btNode
+	ticks     int
+	decisions int
+}
+
+// decide ticks the tree and returns the command an action issued. A tree
+// that finishes without issuing one is ticked again from scratch, so
+// sequences that just completed don't cost the entity a turn; if that
+// issues nothing either the entity does nothing.
+func (bt *BehaviorTree) decide(view EntityView, rng *RNG) Command {
+	bt.decisions++
+	for range 2 {
+		bt.ticks++
+		t := &btTick{n: bt.ticks, decision: bt.decisions, view: view, rng: rng}
+		if t.run(bt.root); !t.issued.IsZero() {
+			return t.issued
+		}
+		bt.ticks++ // Nothing is resumed from a finished tree
+	}
+	return Command{}
+}
+
+// Branch describes the active branch of the last decision, root first, with
+// the conditions that let each sequence through, e.g. "selector > sequence
+// [energy < 20] > action recharge". It is empty if no action was issued.
+func (bt *BehaviorTree) Branch() string {
+	var path []string
+	for n := bt.root; n != nil && bt.active(n); {
+		label := n.label()
+		var next btNode
+		var passed []string
+		for _, child := range n.children() {
+			if c, ok := child.(*btCondition); ok && c.last == bt.ticks && c.status == btSuccess {
+				passed = append(passed, c.text())
+			}
+			if bt.active(child) {
+				next = child
+			}
+		}
+		if _, ok := n.(*btComposite); ok && len(passed) > 0 {
+			label += " [" + strings.Join(passed, ", ") + "]"
+		}
+		path = append(path, label)
+		n = next
+	}
+	return strings.Join(path, " > ")
+}
+
+// active reports whether a node is running as of the last decision.
+func (bt *BehaviorTree) active(n btNode) bool {
+	return n.base().last == bt.ticks && n.base().status == btRunning
+}
+
+// Write prints the tree, one node per line, marking how each node did in the
+// last decision: ">" running, "+" succeeded, "-" failed, blank if not
+// reached.
+func (bt *BehaviorTree) Write(w io.Writer, indent string) {
+	var write func(n btNode, depth int)
+	write = func(n btNode, depth int) {
+		mark := " "
+		if b := n.base(); b.last == bt.ticks && bt.ticks > 0 {
+			mark = map[btStatus]string{btRunning: ">", btSuccess: "+", btFailure: "-"}[b.status]
+		}
+		fmt.Fprintf(w, "%s%s %s%s\n", indent, mark, strings.Repeat("  ", depth), n.label())
+		for _, child := range n.children() {
+			write(child, depth+1)
+		}
+	}
+	write(bt.root, 0)
+}
+
+// LoadBehaviorTree reads a behavior tree from a JSON or S-expression file,
+// for an entity following rules.
+func LoadBehaviorTree(filename string, rules *TransitionTable) (*BehaviorTree, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	bt, err := ParseBehaviorTree(data, rules)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return bt, nil
+}
+
+// ParseBehaviorTree decodes a tree, reporting every problem. Actions are
+// checked against rules, the transition table the tree will run with. A tree
+// starting with "{" is JSON, where each node is an object with a single key
+// naming its type:
+//
+//	{"selector": [
+//	  {"sequence": [{"condition": "energy < 20"}, {"action": "recharge"}]},
+//	  {"decorator": "chance 0.5", "child": {"action": "think"}}
+//	]}
+//
+// Otherwise it is an S-expression, with ";" starting a comment:
+//
+//	(selector
+//	  (sequence (condition energy < 20) (action recharge))
+//	  (decorator chance 0.5 (action think)))
+func ParseBehaviorTree(data []byte, rules *TransitionTable) (*BehaviorTree, error) {
+	var spec btSpec
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		spec, err = parseBTJSON(trimmed, "root")
+	} else {
+		spec, err = parseBTSexpr(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid behavior tree: %w", err)
+	}
+	var errs []error
+	root := spec.build(rules, &errs)
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid behavior tree: %w", err)
+	}
+	return &BehaviorTree{root: root}, nil
+}
+
+// btSpec is a node as written, in either syntax.
+type btSpec struct {
+	kind  string
+	text  string // A condition, action or decorator's words
+	kids  []btSpec
+	where string // For errors, e.g. "root.selector[1]" or "line 3"
+}
+
+var btKinds = []string{"selector", "sequence", "random-selector", "condition", "action", "decorator"}
+
+// build turns a spec into a node, collecting errors; the node is only usable
+// if there are none.
+func (s btSpec) build(rules *TransitionTable, errs *[]error) btNode {
+	fail := func(format string, args ...any) btNode {
+		*errs = append(*errs, fmt.Errorf("%s: "+format, append([]any{s.where}, args...)...))
+		return nil
+	}
+	switch s.kind {
+	case "selector", "sequence", "random-selector":
+		if len(s.kids) == 0 {
+			return fail("%s needs at least one child", s.kind)
+		}
+		c := &btComposite{kind: s.kind}
+		for _, kid := range s.kids {
+			c.kids = append(c.kids, kid.build(rules, errs))
+		}
+		return c
+	case "condition":
+		if len(s.kids) > 0 {
+			return fail("%s takes no children", s.kind)
+		}
+		c, err := newBTCondition(s.text)
+		if err != nil {
+			return fail("%v", err)
+		}
+		return c
+	case "action":
+		if len(s.kids) > 0 {
+			return fail("%s takes no children", s.kind)
+		}
+		a, err := newBTAction(s.text, rules)
+		if err != nil {
+			return fail("%v", err)
+		}
+		return a
+	case "decorator":
+		if len(s.kids) != 1 {
+			return fail("decorator needs exactly one child")
+		}
+		d, err := newBTDecorator(s.text, s.kids[0].build(rules, errs))
+		if err != nil {
+			return fail("%v", err)
+		}
+		return d
+	}
+	return fail("unknown node type %q (available: %s)", s.kind, strings.Join(btKinds, ", "))
+}
+
+// parseBTJSON reads a node and its children from JSON.
+func parseBTJSON(data []byte, where string) (btSpec, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return btSpec{}, fmt.Errorf("%s: %w", where, err)
+	}
+	spec := btSpec{where: where}
+	for key, value := range fields {
+		if key == "child" {
+			continue
+		}
+		if spec.kind != "" {
+			return spec, fmt.Errorf("%s: a node has one type, got %q and %q", where, spec.kind, key)
+		}
+		spec.kind = key
+		switch key {
+		case "selector", "sequence", "random-selector":
+			var kids []json.RawMessage
+			if err := json.Unmarshal(value, &kids); err != nil {
+				return spec, fmt.Errorf("%s: %s must be a list of nodes", where, key)
+			}
+			for i, kid := range kids {
+				child, err := parseBTJSON(kid, fmt.Sprintf("%s.%s[%d]", where, key, i))
+				if err != nil {
+					return spec, err
+				}
+				spec.kids = append(spec.kids, child)
+			}
+		default:
+			if err := json.Unmarshal(value, &spec.text); err != nil {
+				return spec, fmt.Errorf("%s: %s must be a string", where, key)
+			}
+		}
+	}
+	if spec.kind == "" {
+		return spec, fmt.Errorf("%s: missing node type (one of %s)", where, strings.Join(btKinds, ", "))
+	}
+	if child, ok := fields["child"]; ok {
+		if spec.kind != "decorator" {
+			return spec, fmt.Errorf("%s: only a decorator has a child", where)
+		}
+		kid, err := parseBTJSON(child, where+".child")
+		if err != nil {
+			return spec, err
+		}
+		spec.kids = []btSpec{kid}
+	}
+	return spec, nil
+}
+
+// parseBTSexpr reads a tree written as an S-expression: each node is a list
+// of its type, its words and then its children.
+func parseBTSexpr(src string) (btSpec, error) {
+	p := &sexprParser{src: src, line: 1}
+	p.skip()
+	if p.pos >= len(p.src) || p.src[p.pos] != '(' {
+		return btSpec{}, fmt.Errorf("line %d: expected (", p.line)
+	}
+	spec, err := p.node()
+	if err != nil {
+		return spec, err
+	}
+	if p.skip(); p.pos < len(p.src) {
+		return spec, fmt.Errorf("line %d: unexpected text after the tree", p.line)
+	}
+	return spec, nil
+}
+
+type sexprParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// skip passes over whitespace and comments.
+func (p *sexprParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == ';':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// node reads a list starting at "(".
+func (p *sexprParser) node() (btSpec, error) {
+	spec := btSpec{where: fmt.Sprintf("line %d", p.line)}
+	p.pos++
+	var words []string
+	for {
+		p.skip()
+		if p.pos >= len(p.src) {
+			return spec, fmt.Errorf("%s: missing )", spec.where)
+		}
+		switch p.src[p.pos] {
+		case ')':
+			p.pos++
+			if len(words) == 0 {
+				return spec, fmt.Errorf("%s: empty node", spec.where)
+			}
+			spec.kind, spec.text = words[0], strings.Join(words[1:], " ")
+			return spec, nil
+		case '(':
+			kid, err := p.node()
+			if err != nil {
+				return spec, err
+			}
+			spec.kids = append(spec.kids, kid)
+		default:
+			if len(spec.kids) > 0 {
+				return spec, fmt.Errorf("line %d: words must come before a node's children", p.line)
+			}
+			start := p.pos
+			for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n();", rune(p.src[p.pos])) {
+				p.pos++
+			}
+			words = append(words, p.src[start:p.pos])
+		}
+	}
+}
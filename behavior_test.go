@@ -0,0 +1,263 @@
+// behavior_test.go
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustParseTree(t *testing.T, src string) *BehaviorTree {
+	t.Helper()
+	tree, err := ParseBehaviorTree([]byte(src), DefaultTransitions())
+	if err != nil {
+		t.Fatalf("ParseBehaviorTree: %v", err)
+	}
+	return tree
+}
+
+func treeString(tree *BehaviorTree) string {
+	var buf bytes.Buffer
+	tree.Write(&buf, "")
+	return buf.String()
+}
+
+func TestParseBehaviorTree_JSONAndSexprAgree(t *testing.T) {
+	fromJSON := mustParseTree(t, `{"selector": [
+		{"sequence": [{"condition": "energy < 20"}, {"action": "recharge"}]},
+		{"decorator": "chance 0.5", "child": {"random-selector": [{"action": "think"}, {"action": "focus clearest"}]}}
+	]}`)
+	fromSexpr := mustParseTree(t, `
+		; The same tree
+		(selector
+		  (sequence (condition energy < 20) (action recharge))
+		  (decorator chance 0.5 (random-selector (action think) (action focus clearest))))`)
+
+	want := `  selector
+    sequence
+      condition energy < 20
+      action recharge
+    decorator chance 0.5
+      random-selector
+        action think
+        action focus clearest
+`
+	if got := treeString(fromJSON); got != want {
+		t.Errorf("JSON: Expected\n%s\ngot\n%s", want, got)
+	}
+	if got := treeString(fromSexpr); got != want {
+		t.Errorf("S-expression: Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestParseBehaviorTree_ReportsEveryProblem(t *testing.T) {
+	_, err := ParseBehaviorTree([]byte(`(selector
+		(condition stamina < 3)
+		(decorator repeat 2 (action think))
+		(condition mood == angry)
+		(loop (action idle))
+		(action ponder)
+		(action focus brightest)
+		(condition energy < 20 (action recharge)))`), DefaultTransitions())
+	if err == nil {
+		t.Fatal("Expected errors")
+	}
+	for _, want := range []string{`line 2: condition "stamina < 3": unknown field "stamina"`, `line 3: decorator "repeat 2": unknown kind`, `line 4: condition "mood == angry": unknown mood`, `line 5: unknown node type "loop"`,
+		`line 6: action "ponder": unknown command "ponder"`, `line 7: action "focus brightest": "brightest" is neither a thought index nor one of clearest, newest`, `line 8: condition takes no children`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+
+	for _, bad := range []string{
+		`(selector (action think)`,
+		`(selector (action think)) (action idle)`,
+		`(sequence)`,
+		`(selector (action think) extra)`,
+		`(decorator invert)`,
+		`(action focus)`,
+		`(action accept clearest)`,
+		`(action evolve newest increase)`,
+		`(action combine 0 -1)`,
+		`(action think (action idle))`,
+		`{"selector": [{"action": "think", "condition": "energy > 1"}]}`,
+		`{"action": "think", "child": {"action": "idle"}}`,
+		`{"sequence": {"action": "think"}}`,
+		`{"condition": "energy between 1 2"}`,
+		`{"condition": "state < Idle"}`,
+		`{"decorator": "chance 2", "child": {"action": "idle"}}`,
+	} {
+		if _, err := ParseBehaviorTree([]byte(bad), DefaultTransitions()); err == nil {
+			t.Errorf("%s: Expected an error", bad)
+		}
+	}
+}
+
+func TestBehaviorTree_SequenceResumes(t *testing.T) {
+	tree := mustParseTree(t, `(sequence (condition thoughts == 0) (action think) (action generate))`)
+	view := NewDefaultEntities()[1].View()
+
+	if cmd := tree.decide(view, NewRNG(1)); cmd.String() != "think" || tree.Branch() != "sequence [thoughts == 0] > action think" {
+		t.Fatalf("Expected think, got %q via %q", cmd, tree.Branch())
+	}
+	view.State = "Thinking"
+	if cmd := tree.decide(view, NewRNG(1)); cmd.String() != "generate" || tree.Branch() != "sequence > action generate" {
+		t.Fatalf("Expected the sequence resumed with generate, got %q via %q", cmd, tree.Branch())
+	}
+	view.Thoughts = []Thought{{Text: "new"}}
+	if cmd := tree.decide(view, NewRNG(1)); !cmd.IsZero() || tree.Branch() != "" {
+		t.Errorf("Expected nothing once the sequence is done, got %q via %q", cmd, tree.Branch())
+	}
+}
+
+func TestBehaviorTree_ActionsCheckWhatTheyNeed(t *testing.T) {
+	tree := mustParseTree(t, `(selector (action generate) (action focus clearest) (action integrate heard) (action idle))`)
+	view := NewDefaultEntities()[1].View()
+	view.State = "Thinking"
+	view.Energy = 5
+
+	if cmd := tree.decide(view, NewRNG(1)); cmd.String() != "idle" {
+		t.Errorf("Expected idle without energy or thoughts, got %q", cmd)
+	}
+	view.Energy = 50
+	view.Thoughts = []Thought{{Clarity: 0.2}, {Clarity: 0.6}}
+	tree = mustParseTree(t, `(selector (action integrate heard) (action focus clearest))`)
+	if cmd := tree.decide(view, NewRNG(1)); cmd.String() != "focus 1" {
+		t.Errorf("Expected the clearest thought focused, got %q", cmd)
+	}
+}
+
+func TestBehaviorTree_Decorators(t *testing.T) {
+	view := NewDefaultEntities()[1].View()
+	view.State = "Thinking"
+
+	tree := mustParseTree(t, `(selector (decorator invert (condition energy > 10)) (action idle))`)
+	if cmd := tree.decide(view, NewRNG(1)); cmd.String() != "idle" {
+		t.Errorf("invert: Expected idle, got %q", cmd)
+	}
+
+	tree = mustParseTree(t, `(selector (decorator cooldown 2 (action generate)) (action focus newest))`)
+	view.Thoughts = []Thought{{}}
+	var got []string
+	for range 6 {
+		got = append(got, tree.decide(view, NewRNG(1)).Name)
+	}
+	// Each generate runs for a decision and succeeds on the next, which
+	// then falls through to focus, as do the two decisions of the cooldown.
+	if want := "generate focus focus focus generate focus"; strings.Join(got, " ") != want {
+		t.Errorf("cooldown: Expected %q, got %q", want, strings.Join(got, " "))
+	}
+
+	tree = mustParseTree(t, `(selector (sequence (decorator succeed (action think)) (decorator chance 0 (action generate))) (action idle))`)
+	if cmd := tree.decide(view, NewRNG(1)); cmd.String() != "idle" {
+		t.Errorf("chance 0, succeed: Expected idle, got %q", cmd)
+	}
+}
+
+func TestBehaviorTree_RandomSelectorShuffles(t *testing.T) {
+	tree := mustParseTree(t, `(random-selector (action generate) (action idle))`)
+	view := NewDefaultEntities()[1].View()
+	view.State = "Thinking"
+	rng := NewRNG(3)
+	seen := map[string]int{}
+	for range 40 {
+		seen[tree.decide(view, rng).Name]++
+		tree.decide(view, rng) // Lets the action succeed, so the next decision starts afresh
+	}
+	if seen["generate"] == 0 || seen["idle"] == 0 {
+		t.Errorf("Expected both children tried first at times, got %v", seen)
+	}
+}
+
+func TestPersonalities(t *testing.T) {
+	entities, err := NewEntities(DefaultConfig(), DefaultTransitions(), []string{"tree:personalities/chatty.bt", "tree:personalities/cautious.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := NewSimulation(4, entities)
+	var out bytes.Buffer
+	sim.Output = &TextOutput{W: &out}
+	for range 300 {
+		sim.Step()
+	}
+	for _, id := range []string{"AI-Alpha", "AI-Beta"} {
+		if !strings.Contains(out.String(), id+" SUCCESSFULLY") {
+			t.Errorf("Expected %s to express a thought", id)
+		}
+	}
+	if !strings.Contains(out.String(), "AI-Beta attempts: listen") {
+		t.Errorf("Expected the cautious AI-Beta to listen")
+	}
+	if !strings.Contains(out.String(), "Behavior tree (tree:personalities/cautious.json; > running, + succeeded, - failed):\n  > selector") {
+		t.Errorf("Expected the tree shown with each AI turn")
+	}
+
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Entities()[2].Policy.Name(); got != "tree:personalities/cautious.json" {
+		t.Errorf("loadGame: Expected the tree reloaded, got policy %q", got)
+	}
+}
+
+func TestGetPolicyByName_TreeFileErrors(t *testing.T) {
+	if _, err := getPolicyByName("tree:personalities/missing.bt", DefaultTransitions()); err == nil {
+		t.Error("Expected an error for a missing tree file")
+	}
+}
+
+func TestParseBehaviorTree_ChecksActionsAgainstTheTable(t *testing.T) {
+	// A table where "ponder" focuses like "focus" does, and Thinking can't generate.
+	rules := &TransitionTable{}
+	for _, rule := range DefaultTransitions().Rules {
+		if rule.Command != "generate" {
+			rules.Rules = append(rules.Rules, rule)
+		}
+	}
+	rules.Rules = append(rules.Rules, TransitionRule{From: "Thinking", Command: "ponder", Usage: "ponder <index>", Guard: Guard{MinArgs: 1}, Effects: []string{"focus"}})
+	if err := rules.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseBehaviorTree([]byte(`(action ponder clearest)`), rules); err != nil {
+		t.Errorf("ParseBehaviorTree: Expected a command the table adds, with a placeholder where its effect reads a thought index, got %v", err)
+	}
+	if _, err := ParseBehaviorTree([]byte(`(action ponder clearest)`), DefaultTransitions()); err == nil || !strings.Contains(err.Error(), `unknown command "ponder"`) {
+		t.Errorf("ParseBehaviorTree: Expected ponder unknown to the built-in table, got %v", err)
+	}
+	for src, want := range map[string]string{
+		`(action generate)`:     `unknown command "generate"`,
+		`(action ponder heard)`: `"heard" is a perception index, but ponder expects a thought index`,
+		`(action ponder)`:       "usage is ponder <index>",
+	} {
+		if _, err := ParseBehaviorTree([]byte(src), rules); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: Expected %q against the custom table, got %v", src, want, err)
+		}
+	}
+
+	// A save made with the table loads the tree against it again.
+	file := filepath.Join(t.TempDir(), "ponder.bt")
+	if err := os.WriteFile(file, []byte(`(selector (action ponder clearest) (action think))`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entities, err := NewEntities(DefaultConfig(), rules, []string{"tree:" + file})
+	if err != nil {
+		t.Fatalf("NewEntities: %v", err)
+	}
+	sim := NewSimulation(1, entities)
+	sim.SetTransitions(rules)
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadGame(filename); err != nil {
+		t.Errorf("loadGame: Expected the tree loaded against the saved table, got %v", err)
+	}
+}
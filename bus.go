@@ -0,0 +1,192 @@
+// bus.go
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// DefaultSubscriptionBuffer is the queue length for asynchronous subscribers
+// that don't ask for one.
+const DefaultSubscriptionBuffer = 256
+
+// EventBus is an in-process publish/subscribe hub for events. The Simulation
+// publishes every event it logs; dashboards, loggers, metrics and the like
+// subscribe to the kinds they care about instead of being wired into main.
+type EventBus struct {
+	mu   sync.Mutex
+	subs []*Subscription
+}
+
+// NewEventBus returns an empty bus.
+func NewEventBus() *EventBus { return &EventBus{} }
+
+// Subscription is one subscriber's registration on a bus.
+type Subscription struct {
+	bus     *EventBus
+	kinds   map[EventKind]bool // nil means every kind
+	handler func(Event)
+
+	// Asynchronous subscribers only.
+	mu      sync.Mutex
+	queue   chan Event
+	closed  bool
+	dropped int
+	done    chan struct{}
+}
+
+// Subscribe registers a synchronous handler, called on the publisher's
+// goroutine before Publish returns. With no kinds it receives every event.
+// Handlers must be quick; use SubscribeAsync for anything that may block.
+func (b *EventBus) Subscribe(handler func(Event), kinds ...EventKind) *Subscription {
+	sub := &Subscription{bus: b, kinds: kindSet(kinds), handler: handler}
+	b.add(sub)
+	return sub
+}
+
+// SubscribeAsync registers a handler that runs on its own goroutine, fed
+// through a queue of the given size (DefaultSubscriptionBuffer if <= 0).
+// Publishing never waits for it: when the queue is full the oldest queued
+// event is dropped and counted, so a slow consumer only loses its own events.
+func (b *EventBus) SubscribeAsync(buffer int, handler func(Event), kinds ...EventKind) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	sub := &Subscription{
+		bus:     b,
+		kinds:   kindSet(kinds),
+		handler: handler,
+		queue:   make(chan Event, buffer),
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(sub.done)
+		for event := range sub.queue {
+			handler(event)
+		}
+	}()
+	b.add(sub)
+	return sub
+}
+
+func kindSet(kinds []EventKind) map[EventKind]bool {
+	if len(kinds) == 0 {
+		return nil
+	}
+	set := make(map[EventKind]bool, len(kinds))
+	for _, k := range kinds {
+		set[k] = true
+	}
+	return set
+}
+
+func (b *EventBus) add(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, sub)
+}
+
+// Publish delivers an event to every subscriber interested in its kind.
+func (b *EventBus) Publish(event Event) {
+	b.mu.Lock()
+	subs := append([]*Subscription(nil), b.subs...)
+	b.mu.Unlock()
+	for _, sub := range subs {
+		if sub.kinds != nil && !sub.kinds[event.Kind] {
+			continue
+		}
+		if sub.queue == nil {
+			sub.handler(event)
+		} else {
+			sub.enqueue(event)
+		}
+	}
+}
+
+// enqueue adds an event to an asynchronous subscriber's queue without
+// blocking, dropping the oldest queued event if it is full.
+func (s *Subscription) enqueue(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for {
+		select {
+		case s.queue <- event:
+			return
+		default:
+		}
+		select {
+		case <-s.queue:
+			s.dropped++
+		default:
+		}
+	}
+}
+
+// Dropped returns how many events an asynchronous subscriber has lost because
+// it fell behind.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close unsubscribes. For an asynchronous subscriber it waits until the
+// events already queued have been handled.
+func (s *Subscription) Close() {
+	b := s.bus
+	b.mu.Lock()
+	for i, sub := range b.subs {
+		if sub == s {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+
+	if s.queue == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// Close unsubscribes everyone, draining asynchronous subscribers.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	subs := append([]*Subscription(nil), b.subs...)
+	b.mu.Unlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+// EventWriter is a subscriber that writes every event it receives to W as one
+// JSON object per line.
+type EventWriter struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewEventWriter returns an EventWriter on w.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w)}
+}
+
+// Handle writes one event. It is the handler to pass to Subscribe.
+func (w *EventWriter) Handle(event Event) {
+	if w.err == nil {
+		w.err = w.enc.Encode(event)
+	}
+}
+
+// Err returns the first write error, if any.
+func (w *EventWriter) Err() error { return w.err }
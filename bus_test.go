@@ -0,0 +1,90 @@
+// bus_test.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEventBus_FiltersByKind(t *testing.T) {
+	bus := NewEventBus()
+	var all, recharges []Event
+	bus.Subscribe(func(e Event) { all = append(all, e) })
+	sub := bus.Subscribe(func(e Event) { recharges = append(recharges, e) }, EventRecharged)
+
+	bus.Publish(Event{Kind: EventRecharged})
+	bus.Publish(Event{Kind: EventThoughtGenerated})
+	if len(all) != 2 || len(recharges) != 1 {
+		t.Fatalf("Expected 2 events for the catch-all and 1 for the filter, got %d and %d", len(all), len(recharges))
+	}
+
+	sub.Close()
+	bus.Publish(Event{Kind: EventRecharged})
+	if len(recharges) != 1 {
+		t.Errorf("Expected no events after Close, got %d", len(recharges))
+	}
+}
+
+func TestEventBus_AsyncDrainsOnClose(t *testing.T) {
+	bus := NewEventBus()
+	var got []Event
+	sub := bus.SubscribeAsync(0, func(e Event) { got = append(got, e) })
+	for i := 0; i < 10; i++ {
+		bus.Publish(Event{Kind: EventNotice, Tick: i})
+	}
+	sub.Close()
+	if len(got) != 10 || got[9].Tick != 9 || sub.Dropped() != 0 {
+		t.Errorf("Expected all 10 events in order, got %d (dropped %d)", len(got), sub.Dropped())
+	}
+}
+
+func TestEventBus_SlowConsumerDropsOldest(t *testing.T) {
+	bus := NewEventBus()
+	release := make(chan struct{})
+	var got []Event
+	sub := bus.SubscribeAsync(2, func(e Event) {
+		<-release
+		got = append(got, e)
+	})
+
+	// None of these may block, even though the handler is stuck.
+	for i := 0; i < 20; i++ {
+		bus.Publish(Event{Kind: EventNotice, Tick: i})
+	}
+	close(release)
+	sub.Close()
+
+	if sub.Dropped() == 0 || len(got)+sub.Dropped() != 20 {
+		t.Fatalf("Expected dropped and delivered events to add up to 20, got %d + %d", len(got), sub.Dropped())
+	}
+	if last := got[len(got)-1]; last.Tick != 19 {
+		t.Errorf("Expected the newest event to survive, last delivered was tick %d", last.Tick)
+	}
+}
+
+func TestSimulation_PublishesEvents(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	var buf bytes.Buffer
+	events := NewEventWriter(&buf)
+	sub := sim.Bus.SubscribeAsync(0, events.Handle)
+	var transitions int
+	sim.Bus.Subscribe(func(e Event) { transitions++ }, EventStateTransition)
+
+	sim.Input = func(e *Entity) ([]string, error) { return []string{"think"}, nil }
+	sim.Step()
+	sub.Close()
+
+	if transitions == 0 {
+		t.Errorf("Expected a StateTransition for the player's think")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(sim.EventLog()) {
+		t.Fatalf("Expected the writer to see every logged event (%d), got %d lines", len(sim.EventLog()), len(lines))
+	}
+	var first Event
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first != sim.EventLog()[0] {
+		t.Errorf("Expected written events to decode back, got %+v, %v", first, err)
+	}
+}
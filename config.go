@@ -0,0 +1,311 @@
+// config.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config holds every tuning parameter of the mind model. Transition rules
+// refer to the integer ones by their JSON name (e.g. "cost": "generate_cost"),
+// so a parameter sweep never involves editing source or the rule table.
+type Config struct {
+	InitialEnergy       int     `json:"initial_energy"`
+	MaxEnergy           int     `json:"max_energy"`
+	ExpressionThreshold float64 `json:"expression_threshold"`
+	EnergyRegen         int     `json:"energy_regen"` // Passive energy gained by every entity each tick
+	RechargeAmount      int     `json:"recharge_amount"`
+
+	TransitionCost    int `json:"transition_cost"`     // Leaving Idle for Thinking, Reflecting, Acting or Perceiving
+	ThinkingMinEnergy int `json:"thinking_min_energy"` // Below this, only idle works in Thinking
+	GenerateCost      int `json:"generate_cost"`
+	FocusCost         int `json:"focus_cost"`
+	AcceptCost        int `json:"accept_cost"`
+	IntegrateCost     int `json:"integrate_cost"`
+	CombineCost       int `json:"combine_cost"`
+	IntrospectCost    int `json:"introspect_cost"`
+	ExpressCost       int `json:"express_cost"`
+	EvolveCost        int `json:"evolve_cost"`
+
+	IntegrateClarityGain float64 `json:"integrate_clarity_gain"` // Fraction of a perception's clarity added to the focused thought
+	IntrospectGainMin    float64 `json:"introspect_gain_min"`
+	IntrospectGainMax    float64 `json:"introspect_gain_max"`
+
+	EvolveMinClarity       float64 `json:"evolve_min_clarity"`
+	EvolveMaxEnergyStep    int     `json:"evolve_max_energy_step"`
+	EvolveThresholdStep    float64 `json:"evolve_threshold_step"`
+	MinExpressionThreshold float64 `json:"min_expression_threshold"` // Evolution can't make expression trivially easy...
+	MaxExpressionThreshold float64 `json:"max_expression_threshold"` // ...or impossible
+
+	DreamEnergy          int     `json:"dream_energy"`           // Restored per dream; more than a recharge
+	DreamClarityDrift    float64 `json:"dream_clarity_drift"`    // Most an unfocused thought's clarity moves per dream
+	DreamRecombineChance float64 `json:"dream_recombine_chance"` // Chance per dream of fusing two thoughts
+	DreamPruneClarity    float64 `json:"dream_prune_clarity"`    // Unfocused thoughts below this are dropped while dreaming
+
+	LinkFocusWeight   float64 `json:"link_focus_weight"`   // Association added between thoughts focused one after the other
+	LinkHeardWeight   float64 `json:"link_heard_weight"`   // ...between thoughts heard one after the other
+	LinkCombineWeight float64 `json:"link_combine_weight"` // ...between a combined thought and each parent; it inherits their links scaled by this
+	LinkSimilarity    float64 `json:"link_similarity"`     // Word overlap from which a new thought is associated with an old one
+	SpreadActivation  float64 `json:"spread_activation"`   // Share of introspection's gain passed to each association, times its weight
+
+	SalienceDecay  float64 `json:"salience_decay"`  // Salience an unfocused thought loses per tick; --decay sets it per entity
+	ForgetSalience float64 `json:"forget_salience"` // Thoughts whose salience falls below this are forgotten
+	WorkingMemory  int     `json:"working_memory"`  // Most thoughts a mind holds, evicting the least salient; 0 for no limit
+
+	ExpressValence     float64 `json:"express_valence"`     // Valence gained by expressing a thought, and lost by failing to
+	EvolveValence      float64 `json:"evolve_valence"`      // Valence gained by evolving
+	FrustrationArousal float64 `json:"frustration_arousal"` // Arousal gained by a failed express or an unaffordable command
+	LowEnergyFraction  float64 `json:"low_energy_fraction"` // Below this share of MaxEnergy a mind grows irritable...
+	IrritationRate     float64 `json:"irritation_rate"`     // ...losing this much valence and gaining this much arousal per tick
+	AffectDecay        float64 `json:"affect_decay"`        // Share of valence and arousal that fades per tick otherwise
+	AffectInfluence    float64 `json:"affect_influence"`    // How strongly affect moves introspection gain and the expression threshold; 0 for not at all
+
+	IntrospectFamiliarity float64 `json:"introspect_familiarity"` // Familiarity a thought gains each time it is introspected on
+	IntrospectHabituation float64 `json:"introspect_habituation"` // Intensity it loses as the mind gets used to it
+	QualiaNoise           float64 `json:"qualia_noise"`           // Most a listener's sense of intensity and vividness is off by, scaled by 1 - fidelity
+
+	ListenCost     int     `json:"listen_cost"`
+	ObserveCost    int     `json:"observe_cost"`
+	ObserveClarity float64 `json:"observe_clarity"` // Clarity of a thought taken from a stream or sensor
+
+	MarkovOrder       int     `json:"markov_order"`       // Words of context when training --markov models
+	MarkovTemperature float64 `json:"markov_temperature"` // 1 follows the corpus; lower is more predictable, higher stranger
+	MarkovMaxWords    int     `json:"markov_max_words"`
+
+	LLMTimeoutMS    int     `json:"llm_timeout_ms"` // For a whole call, retries included, before falling back to the built-in phrases
+	LLMRetries      int     `json:"llm_retries"`
+	LLMRetryDelayMS int     `json:"llm_retry_delay_ms"` // Grows with each attempt
+	LLMCooldownMS   int     `json:"llm_cooldown_ms"`    // After a call fails every attempt, fall back without trying for this long
+	LLMTemperature  float64 `json:"llm_temperature"`
+	LLMMaxTokens    int     `json:"llm_max_tokens"`
+
+	AutopilotDelayMS int `json:"autopilot_delay_ms"` // Pause between dashboard frames
+}
+
+// DefaultConfig returns the parameters the simulation has always used.
+func DefaultConfig() *Config {
+	return &Config{
+		InitialEnergy:       70,
+		MaxEnergy:           100,
+		ExpressionThreshold: 0.7,
+		EnergyRegen:         1,
+		RechargeAmount:      25,
+
+		TransitionCost:    5,
+		ThinkingMinEnergy: 10,
+		GenerateCost:      10,
+		FocusCost:         5,
+		AcceptCost:        5,
+		IntegrateCost:     10,
+		CombineCost:       15,
+		IntrospectCost:    15,
+		ExpressCost:       20,
+		EvolveCost:        50,
+
+		IntegrateClarityGain: 0.5,
+		IntrospectGainMin:    0.15,
+		IntrospectGainMax:    0.25,
+
+		EvolveMinClarity:       0.95,
+		EvolveMaxEnergyStep:    10,
+		EvolveThresholdStep:    0.05,
+		MinExpressionThreshold: 0.1,
+		MaxExpressionThreshold: 0.95,
+
+		DreamEnergy:          40,
+		DreamClarityDrift:    0.03,
+		DreamRecombineChance: 0.3,
+		DreamPruneClarity:    0.05,
+
+		LinkFocusWeight:   0.3,
+		LinkHeardWeight:   0.2,
+		LinkCombineWeight: 0.6,
+		LinkSimilarity:    0.3,
+		SpreadActivation:  0.5,
+
+		SalienceDecay:  0.005,
+		ForgetSalience: 0.05,
+		WorkingMemory:  0,
+
+		ExpressValence:     0.3,
+		EvolveValence:      0.4,
+		FrustrationArousal: 0.2,
+		LowEnergyFraction:  0.25,
+		IrritationRate:     0.03,
+		AffectDecay:        0.05,
+		AffectInfluence:    0.5,
+
+		IntrospectFamiliarity: 0.1,
+		IntrospectHabituation: 0.05,
+		QualiaNoise:           0.5,
+
+		ListenCost:     3,
+		ObserveCost:    5,
+		ObserveClarity: 0.3,
+
+		MarkovOrder:       2,
+		MarkovTemperature: 1,
+		MarkovMaxWords:    20,
+
+		LLMTimeoutMS:    5000,
+		LLMRetries:      2,
+		LLMRetryDelayMS: 250,
+		LLMCooldownMS:   30000,
+		LLMTemperature:  0.8,
+		LLMMaxTokens:    48,
+
+		AutopilotDelayMS: 1000,
+	}
+}
+
+// LoadConfig reads a config file. Parameters the file leaves out keep their
+// defaults.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: invalid config: %w", filename, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: invalid config: %w", filename, err)
+	}
+	return cfg, nil
+}
+
+// configField finds the field with the given JSON name.
+func (c *Config) configField(name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("json") == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Set overrides one parameter by its JSON name, as given on the command line.
+func (c *Config) Set(name, value string) error {
+	field, ok := c.configField(name)
+	if !ok {
+		return fmt.Errorf("unknown config parameter %q (available: %s)", name, strings.Join(configParams(), ", "))
+	}
+	switch field.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", name, value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", name, value)
+		}
+		field.SetFloat(f)
+	}
+	return nil
+}
+
+// Int returns an integer parameter by its JSON name, for transition rules.
+func (c *Config) Int(name string) (int, bool) {
+	field, ok := c.configField(name)
+	if !ok || field.Kind() != reflect.Int {
+		return 0, false
+	}
+	return int(field.Int()), true
+}
+
+// configParams lists every parameter name in a stable order.
+func configParams() []string {
+	t := reflect.TypeOf(Config{})
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = t.Field(i).Tag.Get("json")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate reports every parameter that would break the simulation.
+func (c *Config) validate() error {
+	var errs []error
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); (f.Kind() == reflect.Int && f.Int() < 0) || (f.Kind() == reflect.Float64 && f.Float() < 0) {
+			errs = append(errs, fmt.Errorf("%s must not be negative", v.Type().Field(i).Tag.Get("json")))
+		}
+	}
+	if c.MaxEnergy <= 0 {
+		errs = append(errs, errors.New("max_energy must be positive"))
+	}
+	if c.InitialEnergy > c.MaxEnergy {
+		errs = append(errs, fmt.Errorf("initial_energy %d exceeds max_energy %d", c.InitialEnergy, c.MaxEnergy))
+	}
+	if c.IntrospectGainMin > c.IntrospectGainMax {
+		errs = append(errs, fmt.Errorf("introspect_gain_min %.2f exceeds introspect_gain_max %.2f", c.IntrospectGainMin, c.IntrospectGainMax))
+	}
+	if c.MinExpressionThreshold > c.MaxExpressionThreshold || c.MaxExpressionThreshold > 1 {
+		errs = append(errs, errors.New("expression thresholds must satisfy min_expression_threshold <= max_expression_threshold <= 1"))
+	}
+	if c.DreamRecombineChance > 1 {
+		errs = append(errs, fmt.Errorf("dream_recombine_chance %.2f is above 1", c.DreamRecombineChance))
+	}
+	if c.LinkFocusWeight > 1 || c.LinkHeardWeight > 1 || c.LinkCombineWeight > 1 || c.LinkSimilarity > 1 || c.SpreadActivation > 1 {
+		errs = append(errs, errors.New("link weights, link_similarity and spread_activation must not be above 1"))
+	}
+	if c.SalienceDecay > 1 || c.ForgetSalience > 1 {
+		errs = append(errs, errors.New("salience_decay and forget_salience must not be above 1"))
+	}
+	if c.ExpressValence > 1 || c.EvolveValence > 1 || c.FrustrationArousal > 1 || c.LowEnergyFraction > 1 || c.IrritationRate > 1 || c.AffectDecay > 1 || c.AffectInfluence > 1 {
+		errs = append(errs, errors.New("affect parameters must not be above 1"))
+	}
+	if c.IntrospectFamiliarity > 1 || c.IntrospectHabituation > 1 || c.QualiaNoise > 1 {
+		errs = append(errs, errors.New("introspect_familiarity, introspect_habituation and qualia_noise must not be above 1"))
+	}
+	if c.ObserveClarity > 1 {
+		errs = append(errs, fmt.Errorf("observe_clarity %.2f is above 1", c.ObserveClarity))
+	}
+	if c.MarkovOrder < 1 || c.MarkovMaxWords < 1 {
+		errs = append(errs, errors.New("markov_order and markov_max_words must be at least 1"))
+	}
+	if c.ExpressionThreshold > 1 {
+		errs = append(errs, fmt.Errorf("expression_threshold %.2f is above 1", c.ExpressionThreshold))
+	}
+	return errors.Join(errs...)
+}
+
+// configOverrides collects repeated --set name=value flags.
+type configOverrides []string
+
+func (o *configOverrides) String() string { return strings.Join(*o, ",") }
+
+func (o *configOverrides) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	*o = append(*o, s)
+	return nil
+}
+
+// apply sets every override on cfg and validates the result.
+func (o configOverrides) apply(cfg *Config) error {
+	for _, s := range o {
+		name, value, _ := strings.Cut(s, "=")
+		if err := cfg.Set(name, value); err != nil {
+			return err
+		}
+	}
+	return cfg.validate()
+}
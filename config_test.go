@@ -0,0 +1,97 @@
+// config_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig_PartialFileKeepsDefaults(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(filename, []byte(`{"generate_cost": 3, "introspect_gain_max": 0.5}`), 0644)
+	cfg, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.GenerateCost != 3 || cfg.IntrospectGainMax != 0.5 || cfg.RechargeAmount != DefaultConfig().RechargeAmount {
+		t.Errorf("LoadConfig: Unexpected config %+v", cfg)
+	}
+
+	os.WriteFile(filename, []byte(`{"generate_cots": 3}`), 0644)
+	if _, err := LoadConfig(filename); err == nil {
+		t.Error("LoadConfig: Expected a misspelled parameter to be rejected")
+	}
+}
+
+func TestConfigOverrides(t *testing.T) {
+	cfg := DefaultConfig()
+	overrides := configOverrides{"recharge_amount=40", "expression_threshold=0.5"}
+	if err := overrides.apply(cfg); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if cfg.RechargeAmount != 40 || cfg.ExpressionThreshold != 0.5 {
+		t.Errorf("apply: Unexpected config %+v", cfg)
+	}
+
+	for _, bad := range []string{"nonsense=1", "generate_cost=cheap", "introspect_gain_min=0.9", "max_energy=-5"} {
+		if err := (configOverrides{bad}).apply(DefaultConfig()); err == nil {
+			t.Errorf("apply %s: Expected an error", bad)
+		}
+	}
+	if err := (configOverrides{"nonsense=1"}).apply(DefaultConfig()); !strings.Contains(err.Error(), "generate_cost") {
+		t.Errorf("Expected the error to list the available parameters, got %v", err)
+	}
+}
+
+func TestConfig_DrivesTheSimulation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InitialEnergy = 40
+	cfg.EnergyRegen = 3
+	cfg.RechargeAmount = 7
+	cfg.TransitionCost = 2
+	entities, _ := NewEntities(cfg, DefaultTransitions(), nil)
+	sim := NewSimulation(1, entities)
+	sim.SetConfig(cfg)
+	player := sim.Player()
+
+	commands := [][]string{{"recharge"}, {"think"}}
+	sim.Input = func(e *Entity) ([]string, error) {
+		parts := commands[0]
+		commands = commands[1:]
+		return parts, nil
+	}
+	sim.Step()
+	if player.Mind.Energy != 40+3+7 {
+		t.Errorf("Expected regen 3 and recharge 7 from 40, got %d", player.Mind.Energy)
+	}
+	sim.Step()
+	if player.Mind.Energy != 50+3-2 {
+		t.Errorf("Expected think to cost 2 after regen, got %d", player.Mind.Energy)
+	}
+
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	if *loaded.Config() != *cfg || loaded.Player().Mind.Config != loaded.Config() {
+		t.Errorf("Expected the active config back from the save, got %+v", loaded.Config())
+	}
+}
+
+func TestIntrospect_UsesConfiguredGain(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.IntrospectGainMin, cfg.IntrospectGainMax = 0.3, 0.3
+	ctx := NewMindContext(cfg)
+	ctx.AddThought("a thought", OriginGenerated)
+	ctx.CurrentFocusIndex = 0
+	(&ReflectingState{}).HandleInput("e", ctx, []string{"introspect"})
+	if got := ctx.FocusedClarity(); got < InitialClarity+0.3-1e-9 || got > InitialClarity+0.3+1e-9 {
+		t.Errorf("Expected clarity %.2f, got %.2f", InitialClarity+0.3, got)
+	}
+}
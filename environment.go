@@ -0,0 +1,234 @@
+// environment.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Stimulus is something in the environment an entity can perceive. Source
+// names where it came from, e.g. "stream:notes.txt" or "sensor:light".
+// Modality and Intensity say how it is sensed; text from streams leaves them
+// empty and is read as words.
+type Stimulus struct {
+	Source    string
+	Text      string
+	Modality  Modality
+	Intensity float64
+}
+
+// StimulusSource is an external feed of stimuli, such as a text stream.
+// Next returns false when nothing is available right now.
+type StimulusSource interface {
+	Next() (Stimulus, bool)
+}
+
+// Sensor is a simulated instrument. Its readings come from the simulation's
+// RNG, so they replay exactly.
+type Sensor struct {
+	Name     string
+	Unit     string
+	Min, Max float64
+	Modality Modality
+}
+
+// defaultSensors are the senses every entity has.
+var defaultSensors = []Sensor{
+	{Name: "light", Unit: "lux", Min: 0, Max: 1000, Modality: ModalityVisual},
+	{Name: "sound", Unit: "dB", Min: 20, Max: 90, Modality: ModalityAuditory},
+	{Name: "temperature", Unit: "degrees", Min: 15, Max: 30, Modality: ModalityTactile},
+}
+
+// Read takes a reading. Its intensity is where the value falls in the
+// sensor's range.
+func (s Sensor) Read(rng *RNG) Stimulus {
+	fraction := rng.Float64()
+	value := s.Min + fraction*(s.Max-s.Min)
+	return Stimulus{Source: "sensor:" + s.Name, Text: fmt.Sprintf("the %s reads %.0f %s", s.Name, value, s.Unit), Modality: s.Modality, Intensity: fraction}
+}
+
+// Environment is what entities in the Perceiving state attend to, besides
+// each other: external streams first, then the sensors.
+type Environment struct {
+	Streams []StimulusSource
+	Sensors []Sensor
+}
+
+// NewEnvironment returns an environment with the default sensors and the
+// given streams.
+func NewEnvironment(streams ...StimulusSource) *Environment {
+	return &Environment{Streams: streams, Sensors: defaultSensors}
+}
+
+// Observe returns the next line from the first stream that has one, or else a
+// reading from a random sensor.
+func (env *Environment) Observe(rng *RNG) (Stimulus, bool) {
+	if env == nil {
+		return Stimulus{}, false
+	}
+	for _, stream := range env.Streams {
+		if stimulus, ok := stream.Next(); ok {
+			return stimulus, true
+		}
+	}
+	if len(env.Sensors) == 0 {
+		return Stimulus{}, false
+	}
+	return env.Sensors[rng.IntN(len(env.Sensors))].Read(rng), true
+}
+
+// TextStream feeds lines of text from a reader, one per observation. It keeps
+// working after reaching the end, so a file that is still being written to
+// can be followed as it grows. Next must not block, so readers that can wait
+// for input, like named pipes, go through a backgroundReader.
+type TextStream struct {
+	name    string
+	r       *bufio.Reader
+	pending string    // A line read up to EOF without its newline yet
+	closer  io.Closer // What OpenTextStream opened, if anything
+}
+
+// NewTextStream reads stimuli from r, attributed to "stream:<name>".
+func NewTextStream(name string, r io.Reader) *TextStream {
+	return &TextStream{name: filepath.Base(name), r: bufio.NewReader(r)}
+}
+
+// OpenTextStream reads stimuli from a regular file, a named pipe, or stdin if
+// filename is "-". Pipes and stdin are read on a goroutine, so neither waiting
+// for a writer nor waiting for the next line holds up the simulation.
+func OpenTextStream(filename string) (*TextStream, error) {
+	if filename == "-" {
+		stdin := io.NopCloser(os.Stdin)
+		r := newBackgroundReader(func() (io.ReadCloser, error) { return stdin, nil })
+		s := NewTextStream("stdin", r)
+		s.closer = r
+		return s, nil
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case info.Mode().IsRegular():
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		s := NewTextStream(filename, f)
+		s.closer = f
+		return s, nil
+	case info.Mode()&os.ModeNamedPipe != 0:
+		r := newBackgroundReader(func() (io.ReadCloser, error) { return os.Open(filename) })
+		s := NewTextStream(filename, r)
+		s.closer = r
+		return s, nil
+	}
+	return nil, fmt.Errorf("%s: not a regular file or named pipe", filename)
+}
+
+// Close closes what OpenTextStream opened.
+func (s *TextStream) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+func (s *TextStream) Next() (Stimulus, bool) {
+	for {
+		chunk, err := s.r.ReadString('\n')
+		s.pending += chunk
+		if err != nil {
+			return Stimulus{}, false // Partial line stays pending until the rest arrives
+		}
+		line := strings.TrimSpace(s.pending)
+		s.pending = ""
+		if line != "" {
+			return Stimulus{Source: "stream:" + s.name, Text: line}, true
+		}
+	}
+}
+
+// backgroundReader reads from a source on its own goroutine, so reading from
+// it never blocks: Read returns what has arrived, or io.EOF if nothing new
+// has. When a pipe's writer goes away the goroutine waits for another.
+type backgroundReader struct {
+	chunks  chan []byte
+	pending []byte
+	stop    chan struct{}
+}
+
+// backgroundPoll is how often a source at EOF is checked for more.
+const backgroundPoll = 100 * time.Millisecond
+
+func newBackgroundReader(open func() (io.ReadCloser, error)) *backgroundReader {
+	b := &backgroundReader{chunks: make(chan []byte, 64), stop: make(chan struct{})}
+	go func() {
+		defer close(b.chunks)
+		src, err := open()
+		if err != nil {
+			return
+		}
+		defer src.Close()
+		for {
+			buf := make([]byte, 4096)
+			n, err := src.Read(buf)
+			if n > 0 {
+				select {
+				case b.chunks <- buf[:n]:
+				case <-b.stop:
+					return
+				}
+			}
+			if err != nil && err != io.EOF {
+				return
+			}
+			if err == io.EOF {
+				select {
+				case <-time.After(backgroundPoll):
+				case <-b.stop:
+					return
+				}
+			}
+		}
+	}()
+	return b
+}
+
+func (b *backgroundReader) Read(p []byte) (int, error) {
+	if len(b.pending) == 0 {
+		select {
+		case chunk, ok := <-b.chunks:
+			if !ok {
+				return 0, io.EOF
+			}
+			b.pending = chunk
+		default:
+			return 0, io.EOF // Nothing new yet
+		}
+	}
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+// Close stops the goroutine once it is no longer waiting on the source.
+func (b *backgroundReader) Close() error {
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+	return nil
+}
+
+// isStreamSource reports whether a stimulus came from an external stream,
+// which a replay can only get back from the journal.
+func isStreamSource(source string) bool {
+	return strings.HasPrefix(source, "stream:")
+}
@@ -0,0 +1,111 @@
+// environment_test.go
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextStream_FollowsGrowingInput(t *testing.T) {
+	r, w := io.Pipe()
+	stream := NewTextStream("/tmp/feed", r)
+	go func() {
+		io.WriteString(w, "first line\n\n  second")
+		w.Close()
+	}()
+
+	got, ok := stream.Next()
+	if !ok || got.Text != "first line" || got.Source != "stream:feed" {
+		t.Fatalf("TextStream: Expected the first line from stream:feed, got %+v, %v", got, ok)
+	}
+	if got, ok := stream.Next(); ok {
+		t.Errorf("TextStream: Expected an unfinished line to wait, got %+v", got)
+	}
+}
+
+func TestTextStream_ResumesAfterEOF(t *testing.T) {
+	stream := NewTextStream("feed", strings.NewReader("par"))
+	if _, ok := stream.Next(); ok {
+		t.Fatalf("TextStream: Expected no line before a newline arrives")
+	}
+	stream.r.Reset(strings.NewReader("tial\n")) // More of the file arrives
+	if got, ok := stream.Next(); !ok || got.Text != "partial" {
+		t.Errorf("TextStream: Expected the pending line to be completed, got %+v, %v", got, ok)
+	}
+}
+
+func TestTextStream_NeverWaitsOnABlockingSource(t *testing.T) {
+	r, w := io.Pipe()
+	stream := NewTextStream("feed", newBackgroundReader(func() (io.ReadCloser, error) { return r, nil }))
+	defer w.Close()
+
+	if got, ok := stream.Next(); ok {
+		t.Fatalf("TextStream: Expected nothing while the writer is idle, got %+v", got)
+	}
+	go io.WriteString(w, "at last\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, ok := stream.Next(); ok {
+			if got.Text != "at last" {
+				t.Errorf("TextStream: Expected the written line, got %+v", got)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("TextStream: Expected the written line to arrive")
+}
+
+func TestOpenTextStream_ReadsStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	stream, err := OpenTextStream("-")
+	if err != nil {
+		t.Fatalf("OpenTextStream: %v", err)
+	}
+	defer stream.Close()
+
+	io.WriteString(w, "from stdin\n")
+	w.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, ok := stream.Next(); ok {
+			if got.Text != "from stdin" || got.Source != "stream:stdin" {
+				t.Errorf("OpenTextStream: Expected the line attributed to stream:stdin, got %+v", got)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("OpenTextStream: Expected the line written to stdin to arrive")
+}
+
+func TestOpenTextStream_RejectsOtherFiles(t *testing.T) {
+	if _, err := OpenTextStream(t.TempDir()); err == nil || !strings.Contains(err.Error(), "not a regular file or named pipe") {
+		t.Errorf("OpenTextStream: Expected a directory rejected, got %v", err)
+	}
+}
+
+func TestEnvironment_ObserveFallsBackToSensors(t *testing.T) {
+	env := NewEnvironment(NewTextStream("feed", strings.NewReader("a line\n")))
+	rng := NewRNG(3)
+
+	if got, _ := env.Observe(rng); got.Source != "stream:feed" {
+		t.Errorf("Observe: Expected streams to come first, got %+v", got)
+	}
+	got, ok := env.Observe(rng)
+	if !ok || !strings.HasPrefix(got.Source, "sensor:") || !strings.Contains(got.Text, " reads ") {
+		t.Errorf("Observe: Expected a sensor reading, got %+v, %v", got, ok)
+	}
+	if _, ok := (&Environment{}).Observe(rng); ok {
+		t.Errorf("Observe: Expected nothing from an empty environment")
+	}
+}
@@ -0,0 +1,190 @@
+// events.go
+package main
+
+import "fmt"
+
+// EventKind identifies what happened. Downstream consumers switch on it rather
+// than parsing text.
+type EventKind string
+
+const (
+	EventStateTransition      EventKind = "StateTransition"      // From -> To
+	EventThoughtGenerated     EventKind = "ThoughtGenerated"     // ThoughtID, Thought
+	EventFocused              EventKind = "Focused"              // Index, ThoughtID, Thought, Clarity
+	EventUnfocused            EventKind = "Unfocused"            // ThoughtID, Thought
+	EventIntrospected         EventKind = "Introspected"         // ThoughtID, Thought, Clarity; From is the old text if it was rewritten
+	EventExpressed            EventKind = "Expressed"            // ThoughtID, Thought, Clarity
+	EventExpressFailed        EventKind = "ExpressFailed"        // Thought, Clarity, Threshold
+	EventEvolved              EventKind = "Evolved"              // Parameter, Direction, OldValue, NewValue, Thought
+	EventEvolveFailed         EventKind = "EvolveFailed"         // Reason, plus Parameter/Direction when relevant
+	EventRecharged            EventKind = "Recharged"            // OldValue, NewValue (energy)
+	EventEnergyInsufficient   EventKind = "EnergyInsufficient"   // Command, Energy, Required
+	EventUnknownCommand       EventKind = "UnknownCommand"       // Command, From (state)
+	EventInvalidCommand       EventKind = "InvalidCommand"       // Command, Reason
+	EventHeard                EventKind = "Heard"                // From (speaker), Thought, Clarity
+	EventPerceptionAccepted   EventKind = "PerceptionAccepted"   // From, Thought, Clarity
+	EventPerceptionIgnored    EventKind = "PerceptionIgnored"    // From, Thought
+	EventPerceptionIntegrated EventKind = "PerceptionIntegrated" // From, Thought (heard), To (focused), Clarity
+	EventThoughtCombined      EventKind = "ThoughtCombined"      // ThoughtID, Thought (the child), Clarity, From and To (the parents)
+	EventActivationSpread     EventKind = "ActivationSpread"     // ThoughtID, Thought (introspected), Count (associations reached), Amount (clarity spread)
+	EventDreamed              EventKind = "Dreamed"              // OldValue, NewValue (energy)
+	EventThoughtRecombined    EventKind = "ThoughtRecombined"    // ThoughtID, Thought (the new composite)
+	EventThoughtPruned        EventKind = "ThoughtPruned"        // ThoughtID, Thought, Clarity
+	EventThoughtForgotten     EventKind = "ThoughtForgotten"     // ThoughtID, Thought, Clarity, NewValue (salience); Reason if working memory was full
+	EventMoodChanged          EventKind = "MoodChanged"          // From -> To (moods)
+	EventQualia               EventKind = "Qualia"               // Index, ThoughtID, Thought, Clarity, Qualia
+	EventGoalSet              EventKind = "GoalSet"              // Goal
+	EventGoalProgress         EventKind = "GoalProgress"         // Goal, OldValue -> NewValue (progress, 0 to 1)
+	EventGoalAchieved         EventKind = "GoalAchieved"         // Goal
+	EventGoalAbandoned        EventKind = "GoalAbandoned"        // Goal, Reason
+	EventPerceived            EventKind = "Perceived"            // From (speaker or stimulus source), ThoughtID, Thought, Clarity
+	EventNoAction             EventKind = "NoAction"             // The entity's policy chose to do nothing
+	EventNotice               EventKind = "Notice"               // Reason; housekeeping such as saves and loads
+)
+
+// Event is a structured record of something that happened to an entity.
+// Which payload fields are set depends on Kind; see the EventKind constants.
+// Text is only produced by String, at display time.
+type Event struct {
+	Kind   EventKind `json:"kind"`
+	Entity string    `json:"entity"`
+	Tick   int       `json:"tick"`
+
+	Command   string  `json:"command,omitempty"`
+	From      string  `json:"from,omitempty"`
+	To        string  `json:"to,omitempty"`
+	ThoughtID int     `json:"thought_id,omitempty"`
+	Thought   string  `json:"thought,omitempty"`
+	Index     int     `json:"index,omitempty"`
+	Clarity   float64 `json:"clarity,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+	Energy    int     `json:"energy,omitempty"`
+	Required  int     `json:"required,omitempty"`
+	Parameter string  `json:"parameter,omitempty"`
+	Direction string  `json:"direction,omitempty"`
+	OldValue  float64 `json:"old_value,omitempty"`
+	NewValue  float64 `json:"new_value,omitempty"`
+	Reason    string  `json:"reason,omitempty"`
+	Qualia    *Qualia `json:"qualia,omitempty"`
+	Goal      string  `json:"goal,omitempty"`
+	Count     int     `json:"count,omitempty"`
+	Amount    float64 `json:"amount,omitempty"`
+}
+
+// String renders the event for people.
+func (e Event) String() string {
+	switch e.Kind {
+	case EventStateTransition:
+		switch e.To {
+		case "Thinking":
+			return fmt.Sprintf("%s started thinking.", e.Entity)
+		case "Reflecting":
+			return fmt.Sprintf("%s started reflecting.", e.Entity)
+		case "Acting":
+			return fmt.Sprintf("%s prepared to act.", e.Entity)
+		case "Dreaming":
+			return fmt.Sprintf("%s fell asleep and started dreaming.", e.Entity)
+		case "Perceiving":
+			return fmt.Sprintf("%s started paying attention.", e.Entity)
+		}
+		if e.From == "Dreaming" {
+			return fmt.Sprintf("%s woke up.", e.Entity)
+		}
+		return fmt.Sprintf("%s transitioned to %s from %s.", e.Entity, e.To, e.From)
+	case EventThoughtGenerated:
+		return fmt.Sprintf("%s generated thought: '%s'.", e.Entity, e.Thought)
+	case EventFocused:
+		return fmt.Sprintf("%s focused on thought [%d]: '%s'. Clarity %.2f.", e.Entity, e.Index, e.Thought, e.Clarity)
+	case EventUnfocused:
+		return fmt.Sprintf("%s unfocused from '%s'.", e.Entity, e.Thought)
+	case EventIntrospected:
+		if e.From != "" {
+			return fmt.Sprintf("%s introspected on '%s' and rephrased it as '%s'. Clarity now %.2f.", e.Entity, e.From, e.Thought, e.Clarity)
+		}
+		return fmt.Sprintf("%s introspected on '%s'. Clarity now %.2f.", e.Entity, e.Thought, e.Clarity)
+	case EventExpressed:
+		return fmt.Sprintf("%s SUCCESSFULLY EXPRESSED: '%s'!", e.Entity, e.Thought)
+	case EventExpressFailed:
+		return fmt.Sprintf("%s FAILED TO EXPRESS: '%s'. Clarity %.2f is below threshold %.2f.", e.Entity, e.Thought, e.Clarity, e.Threshold)
+	case EventEvolved:
+		if e.Parameter == "max_energy" {
+			return fmt.Sprintf("%s EVOLVED: MaxEnergy %sd from %.0f to %.0f. Consumed thought: '%s'.", e.Entity, e.Direction, e.OldValue, e.NewValue, e.Thought)
+		}
+		return fmt.Sprintf("%s EVOLVED: ExpressionThreshold %sd from %.2f to %.2f. Consumed thought: '%s'.", e.Entity, e.Direction, e.OldValue, e.NewValue, e.Thought)
+	case EventEvolveFailed:
+		return fmt.Sprintf("%s evolution failed: %s", e.Entity, e.Reason)
+	case EventRecharged:
+		return fmt.Sprintf("%s recharged. Energy %.0f -> %.0f.", e.Entity, e.OldValue, e.NewValue)
+	case EventEnergyInsufficient:
+		return fmt.Sprintf("%s has not enough energy to %s (%d/%d).", e.Entity, e.Command, e.Energy, e.Required)
+	case EventUnknownCommand:
+		return fmt.Sprintf("%s tried unknown command '%s' in %s.", e.Entity, e.Command, e.From)
+	case EventInvalidCommand:
+		return fmt.Sprintf("%s could not %s: %s.", e.Entity, e.Command, e.Reason)
+	case EventHeard:
+		return fmt.Sprintf("%s heard %s: '%s' (clarity %.2f).", e.Entity, e.From, e.Thought, e.Clarity)
+	case EventPerceptionAccepted:
+		return fmt.Sprintf("%s accepted '%s' from %s. Clarity %.2f.", e.Entity, e.Thought, e.From, e.Clarity)
+	case EventPerceptionIgnored:
+		return fmt.Sprintf("%s ignored '%s' from %s.", e.Entity, e.Thought, e.From)
+	case EventPerceptionIntegrated:
+		return fmt.Sprintf("%s integrated '%s' from %s into '%s'. Clarity now %.2f.", e.Entity, e.Thought, e.From, e.To, e.Clarity)
+	case EventThoughtCombined:
+		return fmt.Sprintf("%s combined '%s' with '%s' into '%s'. Clarity %.2f.", e.Entity, e.From, e.To, e.Thought, e.Clarity)
+	case EventActivationSpread:
+		return fmt.Sprintf("%s's work on '%s' brought %d associated thoughts to mind (clarity +%.2f).", e.Entity, e.Thought, e.Count, e.Amount)
+	case EventDreamed:
+		return fmt.Sprintf("%s dreamed. Energy %.0f -> %.0f.", e.Entity, e.OldValue, e.NewValue)
+	case EventThoughtRecombined:
+		return fmt.Sprintf("%s dreamed up a new thought: '%s'.", e.Entity, e.Thought)
+	case EventThoughtPruned:
+		return fmt.Sprintf("%s let go of '%s' in a dream (clarity %.2f).", e.Entity, e.Thought, e.Clarity)
+	case EventThoughtForgotten:
+		if e.Reason != "" {
+			return fmt.Sprintf("%s forgot '%s' to make room (%s).", e.Entity, e.Thought, e.Reason)
+		}
+		return fmt.Sprintf("%s forgot '%s' (salience %.2f).", e.Entity, e.Thought, e.NewValue)
+	case EventMoodChanged:
+		if e.To == string(MoodNeutral) {
+			return fmt.Sprintf("%s is no longer %s.", e.Entity, e.From)
+		}
+		return fmt.Sprintf("%s is feeling %s.", e.Entity, e.To)
+	case EventQualia:
+		return fmt.Sprintf("%s's thought [%d] '%s' feels like: %s. Clarity %.2f, expressed with fidelity %.2f.", e.Entity, e.Index, e.Thought, e.Qualia, e.Clarity, e.Qualia.Fidelity(e.Clarity))
+	case EventGoalSet:
+		return fmt.Sprintf("%s set a goal: %s.", e.Entity, e.Goal)
+	case EventGoalProgress:
+		return fmt.Sprintf("%s is %.0f%% of the way to: %s.", e.Entity, e.NewValue*100, e.Goal)
+	case EventGoalAchieved:
+		return fmt.Sprintf("%s ACHIEVED A GOAL: %s!", e.Entity, e.Goal)
+	case EventGoalAbandoned:
+		return fmt.Sprintf("%s gave up on: %s (%s).", e.Entity, e.Goal, e.Reason)
+	case EventPerceived:
+		return fmt.Sprintf("%s perceived '%s' from %s. Clarity %.2f.", e.Entity, e.Thought, e.From, e.Clarity)
+	case EventNoAction:
+		return fmt.Sprintf("%s decides to do nothing this turn.", e.Entity)
+	case EventNotice:
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Entity, e.Kind)
+}
+
+// transition is the event for moving an entity between states.
+func transition(entityID string, from, to State) Event {
+	return Event{Kind: EventStateTransition, Entity: entityID, From: from.GetName(), To: to.GetName()}
+}
+
+// lowEnergy is the event for a command the entity cannot afford.
+func lowEnergy(entityID, command string, ctx *MindContext, required int) Event {
+	return Event{Kind: EventEnergyInsufficient, Entity: entityID, Command: command, Energy: ctx.Energy, Required: required}
+}
+
+// invalid is the event for a command that cannot be carried out as given.
+func invalid(entityID, command, reason string) Event {
+	return Event{Kind: EventInvalidCommand, Entity: entityID, Command: command, Reason: reason}
+}
+
+// unknown is the event for a command the current state does not understand.
+func unknown(entityID, command string, state State) Event {
+	return Event{Kind: EventUnknownCommand, Entity: entityID, Command: command, From: state.GetName()}
+}
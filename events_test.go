@@ -0,0 +1,58 @@
+// events_test.go
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHandlers_ReturnTypedEvents(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Energy = 100
+
+	next, events := (&IdleState{}).HandleInput("e", ctx, []string{"think"})
+	if len(events) != 1 || events[0].Kind != EventStateTransition || events[0].From != "Idle" || events[0].To != "Thinking" {
+		t.Fatalf("think: Expected Idle -> Thinking transition, got %+v", events)
+	}
+	_, events = next.HandleInput("e", ctx, []string{"generate"})
+	if len(events) != 1 || events[0].Kind != EventThoughtGenerated || events[0].ThoughtID != 1 || events[0].Thought != ctx.Thoughts[0].Text {
+		t.Fatalf("generate: Expected ThoughtGenerated for thought 1, got %+v", events)
+	}
+	_, events = next.HandleInput("e", ctx, []string{"bogus"})
+	if len(events) != 1 || events[0].Kind != EventUnknownCommand || events[0].Command != "bogus" || events[0].From != "Thinking" {
+		t.Fatalf("bogus: Expected UnknownCommand, got %+v", events)
+	}
+
+	ctx.Energy = 3
+	_, events = (&IdleState{}).HandleInput("e", ctx, []string{"act"})
+	if len(events) != 1 || events[0].Kind != EventEnergyInsufficient || events[0].Energy != 3 || events[0].Required != 5 {
+		t.Fatalf("act: Expected EnergyInsufficient 3/5, got %+v", events)
+	}
+}
+
+func TestSimulation_StampsEventTicks(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities()[:1])
+	sim.Input = func(e *Entity) ([]string, error) { return []string{"recharge"}, nil }
+	sim.Step()
+	sim.Step()
+	log := sim.EventLog()
+	if len(log) != 2 || log[0].Tick != 1 || log[1].Tick != 2 || log[1].Kind != EventRecharged {
+		t.Fatalf("Expected one Recharged event per tick, got %+v", log)
+	}
+}
+
+func TestEvent_RendersAndRoundTrips(t *testing.T) {
+	event := Event{Kind: EventExpressFailed, Entity: "e", Tick: 4, Thought: "hm", Clarity: 0.4, Threshold: 0.7}
+	if got := event.String(); !strings.Contains(got, "FAILED TO EXPRESS: 'hm'") || !strings.Contains(got, "0.70") {
+		t.Errorf("String: Unexpected rendering %q", got)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Event
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded != event {
+		t.Errorf("JSON round trip: got %+v, %v", decoded, err)
+	}
+}
@@ -0,0 +1,229 @@
+// goal.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GoalKind is what an entity can set out to do.
+type GoalKind string
+
+const (
+	GoalExpress    GoalKind = "express"    // Express Target thoughts
+	GoalMaxEnergy  GoalKind = "max_energy" // Evolve MaxEnergy up to Target
+	GoalThreshold  GoalKind = "threshold"  // Evolve ExpressionThreshold down to Target
+	GoalUnderstand GoalKind = "understand" // Bring thought ThoughtID to clarity Target
+)
+
+var goalKinds = []GoalKind{GoalExpress, GoalMaxEnergy, GoalThreshold, GoalUnderstand}
+
+// Goal is something a mind is working towards. Progress runs from the measure
+// when the goal was set (Start) to Target; see measure.
+type Goal struct {
+	Kind      GoalKind `json:"kind"`
+	Target    float64  `json:"target"`
+	ThoughtID int      `json:"thought_id,omitempty"` // The thought to understand
+	Start     float64  `json:"start"`
+	Expressed int      `json:"expressed,omitempty"` // Thoughts expressed since an express goal was set
+	Progress  float64  `json:"progress"`            // 0.0 to 1.0, as last reported
+}
+
+func (g Goal) String() string {
+	switch g.Kind {
+	case GoalExpress:
+		if g.Target == 1 {
+			return "express 1 thought"
+		}
+		return fmt.Sprintf("express %.0f thoughts", g.Target)
+	case GoalMaxEnergy:
+		return fmt.Sprintf("reach MaxEnergy %.0f", g.Target)
+	case GoalThreshold:
+		return fmt.Sprintf("lower ExpressionThreshold to %.2f", g.Target)
+	case GoalUnderstand:
+		return fmt.Sprintf("understand thought #%d to clarity %.2f", g.ThoughtID, g.Target)
+	}
+	return string(g.Kind)
+}
+
+// measure returns where the mind stands on the goal, or false if it can no
+// longer be pursued because its thought is gone.
+func (g Goal) measure(ctx *MindContext) (float64, bool) {
+	switch g.Kind {
+	case GoalExpress:
+		return float64(g.Expressed), true
+	case GoalMaxEnergy:
+		return float64(ctx.MaxEnergy), true
+	case GoalThreshold:
+		return ctx.ExpressionThreshold, true
+	case GoalUnderstand:
+		if t := ctx.ThoughtByID(g.ThoughtID); t != nil {
+			return t.Clarity, true
+		}
+	}
+	return 0, false
+}
+
+// progress is how far measure m is from Start towards Target, from 0 to 1.
+func (g Goal) progress(m float64) float64 {
+	if g.Target == g.Start {
+		return 1
+	}
+	return min(max((m-g.Start)/(g.Target-g.Start), 0), 1)
+}
+
+// achieved allows for rounding in the steps a measure moves by.
+func achieved(progress float64) bool { return progress >= 1-1e-9 }
+
+// newGoal parses a goal for the mind: express <n>, max_energy <n>,
+// threshold <x> or understand <index> [clarity]. The target has to be ahead of
+// where the mind is now, and reachable.
+func (ctx *MindContext) newGoal(args []string) (Goal, error) {
+	kind := GoalKind(strings.ToLower(args[0]))
+	if !slices.Contains(goalKinds, kind) {
+		return Goal{}, fmt.Errorf("unknown goal '%s' (available: %s)", args[0], strings.Join(goalUsages(), ", "))
+	}
+	if len(args) < 2 {
+		return Goal{}, fmt.Errorf("usage is goal %s", goalUsage(kind))
+	}
+	cfg := ctx.Config
+	g := Goal{Kind: kind}
+	switch kind {
+	case GoalExpress:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return Goal{}, fmt.Errorf("'%s' is not a number of thoughts", args[1])
+		}
+		g.Target = float64(n)
+	case GoalMaxEnergy:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= ctx.MaxEnergy {
+			return Goal{}, fmt.Errorf("'%s' is not a MaxEnergy above the current %d", args[1], ctx.MaxEnergy)
+		}
+		g.Target, g.Start = float64(n), float64(ctx.MaxEnergy)
+	case GoalThreshold:
+		x, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || x < cfg.MinExpressionThreshold || x >= ctx.ExpressionThreshold {
+			return Goal{}, fmt.Errorf("'%s' is not a threshold from %.2f up to the current %.2f", args[1], cfg.MinExpressionThreshold, ctx.ExpressionThreshold)
+		}
+		g.Target, g.Start = x, ctx.ExpressionThreshold
+	case GoalUnderstand:
+		index, err := strconv.Atoi(args[1])
+		if err != nil || index < 0 || index >= len(ctx.Thoughts) {
+			return Goal{}, fmt.Errorf("invalid index '%s'", args[1])
+		}
+		t := ctx.Thoughts[index]
+		g.ThoughtID, g.Target, g.Start = t.ID, cfg.EvolveMinClarity, t.Clarity
+		if len(args) > 2 {
+			if g.Target, err = strconv.ParseFloat(args[2], 64); err != nil || g.Target > 1 {
+				return Goal{}, fmt.Errorf("'%s' is not a clarity up to 1", args[2])
+			}
+		}
+		if t.Clarity >= g.Target {
+			return Goal{}, fmt.Errorf("'%s' is already clear to %.2f", t.Text, t.Clarity)
+		}
+	}
+	return g, nil
+}
+
+func goalUsage(kind GoalKind) string {
+	switch kind {
+	case GoalExpress, GoalMaxEnergy:
+		return string(kind) + " <n>"
+	case GoalThreshold:
+		return string(kind) + " <x>"
+	case GoalUnderstand:
+		return string(kind) + " <index> [clarity]"
+	}
+	return ""
+}
+
+func goalUsages() []string {
+	usages := make([]string, len(goalKinds))
+	for i, kind := range goalKinds {
+		usages[i] = goalUsage(kind)
+	}
+	return usages
+}
+
+// goalEffect adds a goal after any the mind already has, or drops them all:
+// goal <kind> <target> or goal clear.
+func goalEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	if strings.ToLower(args[0]) == "clear" {
+		var events []Event
+		for _, g := range ctx.Goals {
+			events = append(events, Event{Kind: EventGoalAbandoned, Entity: entityID, Goal: g.String(), Reason: "cleared"})
+		}
+		ctx.Goals = nil
+		return events, true
+	}
+	g, err := ctx.newGoal(args)
+	if err != nil {
+		return []Event{invalid(entityID, "goal", err.Error())}, false
+	}
+	ctx.Goals = append(ctx.Goals, g)
+	return []Event{{Kind: EventGoalSet, Entity: entityID, Goal: g.String()}}, true
+}
+
+// review updates the mind's goals after a turn that produced events. It
+// reports progress, and drops goals that have been achieved or whose thought
+// has been forgotten.
+func (ctx *MindContext) review(entityID string, events []Event) []Event {
+	expressed := 0
+	for _, e := range events {
+		if e.Kind == EventExpressed {
+			expressed++
+		}
+	}
+	var reviewed []Event
+	var kept []Goal
+	for _, g := range ctx.Goals {
+		if g.Kind == GoalExpress {
+			g.Expressed += expressed
+		}
+		m, ok := g.measure(ctx)
+		if !ok {
+			reviewed = append(reviewed, Event{Kind: EventGoalAbandoned, Entity: entityID, Goal: g.String(), Reason: fmt.Sprintf("thought #%d is no longer in mind", g.ThoughtID)})
+			continue
+		}
+		progress := g.progress(m)
+		if achieved(progress) {
+			reviewed = append(reviewed, Event{Kind: EventGoalAchieved, Entity: entityID, Goal: g.String()})
+			continue
+		}
+		if progress != g.Progress {
+			reviewed = append(reviewed, Event{Kind: EventGoalProgress, Entity: entityID, Goal: g.String(), OldValue: g.Progress, NewValue: progress})
+			g.Progress = progress
+		}
+		kept = append(kept, g)
+	}
+	ctx.Goals = kept
+	return reviewed
+}
+
+// setGoal is the --goal setter. Its value is a goal command's arguments
+// separated by colons, e.g. express:3.
+func setGoal(ctx *MindContext, value string) error {
+	g, err := ctx.newGoal(strings.Split(value, ":"))
+	if err != nil {
+		return err
+	}
+	ctx.Goals = append(ctx.Goals, g)
+	return nil
+}
+
+func (g Goal) validate() error {
+	switch {
+	case !slices.Contains(goalKinds, g.Kind):
+		return fmt.Errorf("unknown goal kind %q", g.Kind)
+	case g.Progress < 0 || g.Progress > 1:
+		return fmt.Errorf("goal %s progress %.2f is outside 0..1", g, g.Progress)
+	case g.Expressed < 0:
+		return fmt.Errorf("goal %s has %d thoughts expressed", g, g.Expressed)
+	case g.Kind == GoalUnderstand && g.ThoughtID <= 0:
+		return fmt.Errorf("goal %s has no thought", g)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// goal_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoalCommand_SetsAndClearsGoals(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	idle := &IdleState{}
+
+	_, events := idle.HandleInput("e", ctx, strings.Fields("goal express 2"))
+	if len(events) != 1 || events[0].Kind != EventGoalSet || events[0].String() != "e set a goal: express 2 thoughts." {
+		t.Fatalf("goal express: Expected the goal set, got %+v", events)
+	}
+	idle.HandleInput("e", ctx, strings.Fields("goal threshold 0.5"))
+	if len(ctx.Goals) != 2 || ctx.Goals[1] != (Goal{Kind: GoalThreshold, Target: 0.5, Start: 0.7}) {
+		t.Errorf("goal threshold: Expected a second goal starting from 0.70, got %+v", ctx.Goals)
+	}
+
+	for _, bad := range []string{"goal fly 3", "goal express", "goal express 0", "goal max_energy 90", "goal threshold 0.05", "goal understand 0"} {
+		_, events := idle.HandleInput("e", ctx, strings.Fields(bad))
+		if len(events) != 1 || events[0].Kind != EventInvalidCommand {
+			t.Errorf("%s: Expected the goal rejected, got %+v", bad, events)
+		}
+	}
+
+	_, events = idle.HandleInput("e", ctx, strings.Fields("goal clear"))
+	if len(events) != 2 || events[0].Kind != EventGoalAbandoned || len(ctx.Goals) != 0 {
+		t.Errorf("goal clear: Expected both goals abandoned, got %+v", events)
+	}
+}
+
+func TestReview_ReportsProgress(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("a thought", OriginGenerated).Clarity = 0
+	setGoal(ctx, "express:2")
+	setGoal(ctx, "understand:0:0.5")
+
+	ctx.Thoughts[0].Clarity = 0.25
+	events := ctx.review("e", []Event{{Kind: EventExpressed}})
+	if len(events) != 2 || events[0].NewValue != 0.5 || events[1].NewValue != 0.5 || events[0].String() != "e is 50% of the way to: express 2 thoughts." {
+		t.Fatalf("review: Expected both goals halfway, got %+v", events)
+	}
+	if events = ctx.review("e", nil); len(events) != 0 {
+		t.Errorf("review: Expected nothing to report without change, got %+v", events)
+	}
+
+	events = ctx.review("e", []Event{{Kind: EventExpressed}})
+	if len(events) != 1 || events[0].Kind != EventGoalAchieved || events[0].String() != "e ACHIEVED A GOAL: express 2 thoughts!" {
+		t.Errorf("review: Expected the express goal achieved, got %+v", events)
+	}
+	ctx.removeThought(0)
+	events = ctx.review("e", nil)
+	if len(events) != 1 || events[0].Kind != EventGoalAbandoned || !strings.Contains(events[0].String(), "thought #1 is no longer in mind") {
+		t.Errorf("review: Expected the understand goal abandoned, got %+v", events)
+	}
+	if ctx.Goals != nil {
+		t.Errorf("review: Expected no goals left, got %+v", ctx.Goals)
+	}
+}
+
+func TestPlannerPolicy_AchievesGoals(t *testing.T) {
+	entities := NewDefaultEntities()
+	ai := entities[1]
+	ai.Policy = &PlannerPolicy{}
+	for _, g := range []string{"express:2", "max_energy:110", "threshold:0.65"} {
+		if err := setGoal(ai.Mind, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sim := NewSimulation(4, entities)
+	var out bytes.Buffer
+	sim.Output = &TextOutput{W: &out}
+	for i := 0; i < 500 && len(ai.Mind.Goals) > 0; i++ {
+		sim.Step()
+	}
+	if len(ai.Mind.Goals) != 0 || strings.Count(out.String(), "AI-Alpha ACHIEVED A GOAL") != 3 {
+		t.Errorf("planner: Expected every goal achieved, %d left after tick %d", len(ai.Mind.Goals), sim.Tick())
+	}
+	if ai.Mind.MaxEnergy < 110 || ai.Mind.ExpressionThreshold > 0.65 {
+		t.Errorf("planner: Expected MaxEnergy 110 and threshold 0.65, got %d and %.2f", ai.Mind.MaxEnergy, ai.Mind.ExpressionThreshold)
+	}
+}
+
+func TestPlannerPolicy_FallsBackWithoutGoals(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	planner, random := &PlannerPolicy{}, &RandomHeuristicPolicy{}
+	a, b := NewRNG(7), NewRNG(7)
+	for range 50 {
+		if got, want := planner.Decide(entity.View(), a), random.Decide(entity.View(), b); got.String() != want.String() {
+			t.Fatalf("planner: Expected the random heuristics without goals, got %s instead of %s", got, want)
+		}
+	}
+}
+
+func TestGoals_SavedAndReplayed(t *testing.T) {
+	sim := NewSimulation(6, NewDefaultEntities())
+	sim.Entities()[1].Policy = &PlannerPolicy{}
+	setGoal(sim.Entities()[1].Mind, "express:50")
+	sim.SetAutopilot(true)
+	var buf bytes.Buffer
+	journal, err := NewJournal(&buf, sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Output = journal
+	sim.Run(context.Background(), 150)
+
+	replayed, err := replayJournal(&buf, 0)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if mindsJSON(t, sim) != mindsJSON(t, replayed) {
+		t.Errorf("Replay with goals does not match original")
+	}
+
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.Entities()[1].Mind.Goals, sim.Entities()[1].Mind.Goals; len(got) != 1 || got[0] != want[0] || got[0].Expressed == 0 {
+		t.Errorf("loadGame: Expected the goal and its progress kept, got %+v", got)
+	}
+}
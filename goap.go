@@ -0,0 +1,323 @@
+// goap.go
+package main
+
+import (
+	"container/heap"
+	"math"
+	"strconv"
+)
+
+// GOAPPolicy plans in the style of goal-oriented action planning: every
+// command is modelled as an action with preconditions and effects on an
+// abstract world (state, energy, focus, clarity), and a cheapest-first search
+// finds the sequence of actions that reaches the goal. The policy follows
+// its plan while the world turns out as predicted and plans again as soon as
+// it doesn't, e.g. when introspection gains more or less than expected.
+//
+// The goal is the entity's first goal, or to express a thought if it has none.
+// If there is no way to it, the policy falls back on the random heuristics.
+type GOAPPolicy struct {
+	fallback RandomHeuristicPolicy
+	plan     []Command
+	expected []world // What the world should look like before each step
+	goal     Goal
+	replans  int // Searches made because there was no plan or the world diverged
+}
+
+func (p *GOAPPolicy) Name() string { return "goap" }
+
+func (p *GOAPPolicy) Decide(view EntityView, rng *RNG) Command {
+	if !p.following(view) {
+		p.goal = goapGoal(view)
+		p.plan, p.expected = nil, nil
+		p.replans++
+		if s := newGOAPSearch(view, p.goal); s != nil {
+			p.plan, p.expected = s.run()
+		}
+	}
+	if len(p.plan) == 0 {
+		return p.fallback.Decide(view, rng)
+	}
+	next := p.plan[0]
+	p.plan, p.expected = p.plan[1:], p.expected[1:]
+	return next
+}
+
+// Plan returns what is left of the plan if the world is as expected, or nil.
+// It never searches; only Decide does. It is asked between decisions, before
+// the entity regenerates for the next one.
+func (p *GOAPPolicy) Plan(view EntityView) []Command {
+	view.Energy = min(view.Energy+view.Config.EnergyRegen, view.MaxEnergy)
+	if p.following(view) {
+		return p.plan
+	}
+	return nil
+}
+
+// Replans returns how many searches Decide has made.
+func (p *GOAPPolicy) Replans() int { return p.replans }
+
+// following reports whether the policy has a plan left for the current goal
+// and the world is as the plan predicted.
+func (p *GOAPPolicy) following(view EntityView) bool {
+	return len(p.plan) > 0 && p.goal == goapGoal(view) && observe(view) == p.expected[0]
+}
+
+// goapGoal is the goal the policy plans for.
+func goapGoal(view EntityView) Goal {
+	if len(view.Goals) > 0 {
+		return view.Goals[0]
+	}
+	return Goal{Kind: GoalExpress, Target: 1}
+}
+
+// world is the planner's abstraction of a mind: only what the modelled
+// commands read or change.
+type world struct {
+	state    string
+	energy   int
+	thoughts int     // How many thoughts the mind holds
+	focus    int     // Index of the focused thought, -1 for none
+	clarity  float64 // Of the focused thought
+	done     bool    // The goal's final command (express or evolve) has been carried out
+}
+
+// observe abstracts the entity as it is now.
+func observe(view EntityView) world {
+	return world{state: view.State, energy: view.Energy, thoughts: len(view.Thoughts), focus: view.FocusIndex, clarity: view.FocusedClarity()}
+}
+
+// goapAction is a command as the planner models it. The transition rule
+// supplies the state it runs in, where it leads, its cost and the energy its
+// guard asks for; pre adds the command's own preconditions and effect what it
+// does to the world.
+type goapAction struct {
+	cmd    Command
+	rule   *TransitionRule
+	pre    func(w world) bool
+	effect func(w *world)
+}
+
+// goapSearch finds the cheapest plan from start to the goal. An action costs
+// the energy it uses plus one for the tick it takes.
+type goapSearch struct {
+	view  EntityView
+	goal  Goal
+	start world
+	gain  float64 // Expected clarity gained per introspection
+	index int     // Of the thought to understand, for understand goals
+}
+
+// maxGOAPExpansions bounds the search; plans are short, but energy makes
+// the world space large.
+const maxGOAPExpansions = 20000
+
+// newGOAPSearch prepares a search for the goal, or returns nil if there are
+// no rules to plan with or the goal's thought is gone.
+func newGOAPSearch(view EntityView, goal Goal) *goapSearch {
+	if view.Rules == nil {
+		return nil
+	}
+	cfg := &view.Config
+	s := &goapSearch{view: view, goal: goal, start: observe(view), index: -1}
+	s.gain = view.Affect.IntrospectGain((cfg.IntrospectGainMin+cfg.IntrospectGainMax)/2, cfg)
+	if goal.Kind == GoalUnderstand {
+		for i, t := range view.Thoughts {
+			if t.ID == goal.ThoughtID {
+				s.index = i
+			}
+		}
+		if s.index < 0 {
+			return nil
+		}
+	}
+	return s
+}
+
+// estimate is a lower bound on what reaching the goal from w still costs,
+// which lets the search head for the goal first: the introspections needed
+// on the clearest thought at hand, and the final command.
+func (s *goapSearch) estimate(w world) int {
+	if s.satisfied(w) {
+		return 0
+	}
+	cfg := &s.view.Config
+	target, clarity, final := s.goal.Target, InitialClarity, 0
+	switch s.goal.Kind {
+	case GoalExpress:
+		target, final = s.view.Threshold(), 1+cfg.ExpressCost
+	case GoalMaxEnergy, GoalThreshold:
+		target, final = cfg.EvolveMinClarity, 1+cfg.EvolveCost
+	}
+	if w.focus >= 0 && (s.index < 0 || w.focus == s.index) {
+		clarity = max(clarity, w.clarity)
+	}
+	for i, t := range s.view.Thoughts {
+		if s.index < 0 || i == s.index {
+			clarity = max(clarity, t.Clarity)
+		}
+	}
+	if clarity >= target || s.gain <= 0 {
+		return final
+	}
+	return final + int(math.Ceil((target-clarity)/s.gain-1e-9))*(1+cfg.IntrospectCost)
+}
+
+// satisfied is the goal condition.
+func (s *goapSearch) satisfied(w world) bool {
+	if s.goal.Kind == GoalUnderstand {
+		return w.focus == s.index && w.clarity >= s.goal.Target
+	}
+	return w.done
+}
+
+// actions lists the modelled commands that could be taken in w. Focus is
+// only considered on the thoughts worth working on: the clearest, the one to
+// understand, and one newly generated.
+func (s *goapSearch) actions(w world) []goapAction {
+	cfg := &s.view.Config
+	rules := s.view.Rules
+	var actions []goapAction
+	add := func(state string, cmd Command, pre func(w world) bool, effect func(w *world)) {
+		if rule := rules.Lookup(state, cmd.Name); rule != nil {
+			actions = append(actions, goapAction{cmd: cmd, rule: rule, pre: pre, effect: effect})
+		}
+	}
+	always := func(world) bool { return true }
+	unchanged := func(*world) {}
+
+	for _, move := range []string{"think", "reflect", "act", "idle", "wake"} {
+		add(w.state, NewCommand(move), always, unchanged)
+	}
+	add("Idle", NewCommand("recharge"), func(w world) bool { return w.energy < s.view.MaxEnergy }, func(w *world) {
+		w.energy = min(w.energy+cfg.RechargeAmount, s.view.MaxEnergy)
+	})
+	add("Thinking", NewCommand("generate"), func(w world) bool { return w.thoughts == len(s.view.Thoughts) }, func(w *world) {
+		w.thoughts++
+	})
+	for _, target := range s.targets(w) {
+		clarity := InitialClarity
+		if target < len(s.view.Thoughts) {
+			clarity = s.view.Thoughts[target].Clarity
+		}
+		add("Thinking", NewCommand("focus", strconv.Itoa(target)), func(w world) bool { return w.focus != target }, func(w *world) {
+			w.focus, w.clarity = target, clarity
+		})
+	}
+	add("Reflecting", NewCommand("introspect"), func(w world) bool { return w.focus >= 0 && w.clarity < 1 && s.gain > 0 }, func(w *world) {
+		w.clarity = min(w.clarity+s.gain, 1)
+	})
+	consume := func(w *world) {
+		w.focus, w.clarity, w.done = -1, 0, true
+		w.thoughts--
+	}
+	switch s.goal.Kind {
+	case GoalExpress:
+		add("Acting", NewCommand("express"), func(w world) bool { return w.focus >= 0 && w.clarity >= s.view.Threshold() }, consume)
+	case GoalMaxEnergy:
+		add("Acting", NewCommand("evolve", "max_energy", "increase"), func(w world) bool { return w.focus >= 0 && w.clarity >= cfg.EvolveMinClarity }, consume)
+	case GoalThreshold:
+		add("Acting", NewCommand("evolve", "threshold", "decrease"), func(w world) bool { return w.focus >= 0 && w.clarity >= cfg.EvolveMinClarity }, consume)
+	}
+	return actions
+}
+
+// targets are the thought indices worth focusing on in w.
+func (s *goapSearch) targets(w world) []int {
+	var targets []int
+	clearest := -1
+	for i, t := range s.view.Thoughts {
+		if clearest < 0 || t.Clarity > s.view.Thoughts[clearest].Clarity {
+			clearest = i
+		}
+	}
+	if clearest >= 0 && s.goal.Kind != GoalUnderstand {
+		targets = append(targets, clearest)
+	}
+	if s.index >= 0 {
+		targets = append(targets, s.index)
+	}
+	if w.thoughts > len(s.view.Thoughts) {
+		targets = append(targets, len(s.view.Thoughts))
+	}
+	return targets
+}
+
+// apply returns the world after the action and what it cost, or false if its
+// preconditions don't hold in w.
+func (s *goapSearch) apply(a goapAction, w world) (world, bool) {
+	cfg := &s.view.Config
+	need := max(a.rule.Guard.MinEnergy.resolve(cfg), a.rule.Cost.resolve(cfg))
+	if w.state != a.rule.From || w.energy < need || (a.rule.Guard.RequiresFocus && w.focus < 0) || !a.pre(w) {
+		return w, false
+	}
+	a.effect(&w)
+	w.energy -= a.rule.Cost.resolve(cfg)
+	if a.rule.To != "" {
+		w.state = a.rule.To
+	}
+	w.energy = min(w.energy+cfg.EnergyRegen, s.view.MaxEnergy)
+	return w, true
+}
+
+// goapNode is a world reached by the search and how it got there.
+type goapNode struct {
+	w      world
+	cost   int // So far
+	bound  int // cost plus the estimate of what is left
+	steps  int
+	cmd    Command
+	parent *goapNode
+}
+
+type goapQueue []*goapNode
+
+func (q goapQueue) Len() int { return len(q) }
+func (q goapQueue) Less(i, j int) bool {
+	if q[i].bound != q[j].bound {
+		return q[i].bound < q[j].bound
+	}
+	return q[i].steps < q[j].steps
+}
+func (q goapQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *goapQueue) Push(x any)   { *q = append(*q, x.(*goapNode)) }
+func (q *goapQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// run searches cheapest-first (A*) and returns the plan with the world
+// expected before each step, or nil if the goal is out of reach within
+// maxPlanSteps commands.
+func (s *goapSearch) run() ([]Command, []world) {
+	queue := &goapQueue{{w: s.start}}
+	best := map[world]int{s.start: 0}
+	for expansions := 0; queue.Len() > 0 && expansions < maxGOAPExpansions; expansions++ {
+		node := heap.Pop(queue).(*goapNode)
+		if s.satisfied(node.w) {
+			plan, worlds := make([]Command, node.steps), make([]world, node.steps)
+			for ; node.parent != nil; node = node.parent {
+				plan[node.steps-1], worlds[node.steps-1] = node.cmd, node.parent.w
+			}
+			return plan, worlds
+		}
+		if node.cost > best[node.w] || node.steps >= maxPlanSteps {
+			continue // Reached more cheaply since, or too far ahead
+		}
+		for _, a := range s.actions(node.w) {
+			next, ok := s.apply(a, node.w)
+			if !ok {
+				continue
+			}
+			cost := node.cost + 1 + a.rule.Cost.resolve(&s.view.Config)
+			if seen, ok := best[next]; ok && seen <= cost {
+				continue
+			}
+			best[next] = cost
+			heap.Push(queue, &goapNode{w: next, cost: cost, bound: cost + s.estimate(next), steps: node.steps + 1, cmd: a.cmd, parent: node})
+		}
+	}
+	return nil, nil
+}
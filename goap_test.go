@@ -0,0 +1,123 @@
+// goap_test.go
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// goapPlan searches for a plan from scratch, the way Decide does.
+func goapPlan(view EntityView) []Command {
+	plan, _ := newGOAPSearch(view, goapGoal(view)).run()
+	return plan
+}
+
+func TestGOAPPlan_FromAnEmptyMind(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	want := "think -> generate -> focus 0 -> idle -> reflect -> introspect x3 -> idle -> recharge -> act -> express"
+	if got := formatPlan(goapPlan(entity.View()), 20); got != want {
+		t.Errorf("Plan: Expected %q, got %q", want, got)
+	}
+}
+
+func TestGOAPPlan_TakesTheCheapestWay(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	entity.Mind.AddThought("a clear thought", OriginGenerated).Clarity = 0.65
+	entity.Mind.AddThought("a vague thought", OriginGenerated).Clarity = 0.2
+
+	want := "think -> focus 0 -> idle -> reflect -> introspect -> idle -> act -> express"
+	if got := formatPlan(goapPlan(entity.View()), 20); got != want {
+		t.Errorf("Plan: Expected the clear thought used, got %q", got)
+	}
+
+	setGoal(entity.Mind, "max_energy:110")
+	entity.Mind.Energy = 8
+	entity.Mind.CurrentFocusIndex = 0
+	entity.CurrentFSMState = &ReflectingState{}
+	want = "idle -> recharge -> reflect -> introspect x2 -> idle -> recharge x2 -> act -> evolve max_energy increase"
+	if got := formatPlan(goapPlan(entity.View()), 20); got != want {
+		t.Errorf("Plan: Expected to rest for the evolution, got %q", got)
+	}
+}
+
+func TestGOAPPolicy_PlanShowsWhatIsLeft(t *testing.T) {
+	entities := NewDefaultEntities()
+	ai := entities[1]
+	policy := &GOAPPolicy{}
+	ai.Policy = policy
+	sim := NewSimulation(4, entities)
+	sim.Output = &TextOutput{W: &bytes.Buffer{}}
+	if plan := policy.Plan(ai.View()); plan != nil || policy.Replans() != 0 {
+		t.Fatalf("Plan: Expected no plan and no search before deciding, got %q after %d searches", formatPlan(plan, 20), policy.Replans())
+	}
+
+	sim.Step()
+	want := "generate -> focus 0 -> idle -> reflect -> introspect x3 -> idle -> recharge -> act -> express"
+	if got := formatPlan(policy.Plan(ai.View()), 20); got != want {
+		t.Errorf("Plan: Expected the rest of the plan %q, got %q", want, got)
+	}
+	if policy.Replans() != 1 {
+		t.Errorf("Plan: Expected only Decide to search, got %d searches", policy.Replans())
+	}
+}
+
+func TestGOAPPolicy_ReplansWhenTheWorldDiverges(t *testing.T) {
+	entities := NewDefaultEntities()
+	ai := entities[1]
+	policy := &GOAPPolicy{}
+	ai.Policy = policy
+	sim := NewSimulation(4, entities)
+	sim.Output = &TextOutput{W: &bytes.Buffer{}}
+
+	for range 4 {
+		sim.Step()
+	}
+	if policy.Replans() != 1 || ai.CurrentFSMState.GetName() != "Idle" || ai.Mind.CurrentFocusIndex != 0 {
+		t.Fatalf("Expected the first plan followed to Idle with focus, got %d plans in %s", policy.Replans(), ai.CurrentFSMState.GetName())
+	}
+	ai.Mind.Energy -= 30
+	sim.Step()
+	if policy.Replans() != 2 {
+		t.Errorf("Expected a new plan after losing energy, got %d plans", policy.Replans())
+	}
+}
+
+func TestGOAPPolicy_AchievesGoals(t *testing.T) {
+	entities := NewDefaultEntities()
+	ai := entities[1]
+	ai.Policy = &GOAPPolicy{}
+	for _, g := range []string{"express:2", "max_energy:110", "threshold:0.65"} {
+		if err := setGoal(ai.Mind, g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sim := NewSimulation(4, entities)
+	var out bytes.Buffer
+	sim.Output = &TextOutput{W: &out}
+	for i := 0; i < 500 && len(ai.Mind.Goals) > 0; i++ {
+		sim.Step()
+	}
+	if len(ai.Mind.Goals) != 0 || strings.Count(out.String(), "AI-Alpha ACHIEVED A GOAL") != 3 {
+		t.Errorf("goap: Expected every goal achieved, %d left after tick %d", len(ai.Mind.Goals), sim.Tick())
+	}
+}
+
+func TestGOAPPolicy_FallsBackWithoutAWay(t *testing.T) {
+	rules, err := ParseTransitions([]byte(`{"rules": [
+		{"from": "Idle", "command": "act", "to": "Acting"},
+		{"from": "Acting", "command": "express", "guard": {"requires_focus": true}, "effects": ["express"]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := NewDefaultEntities()[1]
+	entity.Mind.Rules = rules
+	goap, random := &GOAPPolicy{}, &RandomHeuristicPolicy{}
+	a, b := NewRNG(7), NewRNG(7)
+	for range 20 {
+		if got, want := goap.Decide(entity.View(), a), random.Decide(entity.View(), b); got.String() != want.String() {
+			t.Fatalf("goap: Expected the random heuristics, got %s instead of %s", got, want)
+		}
+	}
+}
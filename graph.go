@@ -0,0 +1,206 @@
+// graph.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Link is a weighted association between two thoughts in the same mind,
+// stored once per pair with A < B. Weights grow each time the pair is
+// associated again, up to 1.
+type Link struct {
+	A      int     `json:"a"` // Thought IDs
+	B      int     `json:"b"`
+	Weight float64 `json:"weight"`
+}
+
+// Neighbor is a thought linked to another, as returned by Neighbors.
+type Neighbor struct {
+	Index  int // Into MindContext.Thoughts
+	Weight float64
+}
+
+// link strengthens the association between the thoughts with IDs a and b,
+// creating it if needed.
+func (ctx *MindContext) link(a, b int, weight float64) {
+	if a == b || weight <= 0 {
+		return
+	}
+	a, b = min(a, b), max(a, b)
+	for i := range ctx.Links {
+		if l := &ctx.Links[i]; l.A == a && l.B == b {
+			l.Weight = min(l.Weight+weight, 1)
+			return
+		}
+	}
+	ctx.Links = append(ctx.Links, Link{A: a, B: b, Weight: min(weight, 1)})
+}
+
+// unlink drops every association of the thought with the given ID.
+func (ctx *MindContext) unlink(id int) {
+	links := ctx.Links[:0]
+	for _, l := range ctx.Links {
+		if l.A != id && l.B != id {
+			links = append(links, l)
+		}
+	}
+	ctx.Links = links
+}
+
+// Neighbors returns the thoughts linked to the one with the given ID,
+// strongest first.
+func (ctx *MindContext) Neighbors(id int) []Neighbor {
+	index := make(map[int]int, len(ctx.Thoughts))
+	for i, t := range ctx.Thoughts {
+		index[t.ID] = i
+	}
+	var neighbors []Neighbor
+	for _, l := range ctx.Links {
+		other := l.B
+		if l.B == id {
+			other = l.A
+		} else if l.A != id {
+			continue
+		}
+		if i, ok := index[other]; ok {
+			neighbors = append(neighbors, Neighbor{Index: i, Weight: l.Weight})
+		}
+	}
+	sort.SliceStable(neighbors, func(i, j int) bool {
+		if neighbors[i].Weight != neighbors[j].Weight {
+			return neighbors[i].Weight > neighbors[j].Weight
+		}
+		return neighbors[i].Index < neighbors[j].Index
+	})
+	return neighbors
+}
+
+// linkSimilar associates a new thought with every other thought whose wording
+// overlaps it by at least link_similarity, weighted by the overlap. It returns
+// the greatest overlap with any thought, linked or not.
+func (ctx *MindContext) linkSimilar(t Thought) float64 {
+	words := contentWords(t.Text)
+	closest := 0.0
+	for _, other := range ctx.Thoughts {
+		if other.ID == t.ID {
+			continue
+		}
+		s := similarity(words, contentWords(other.Text))
+		if s > 0 && s >= ctx.Config.LinkSimilarity {
+			ctx.link(t.ID, other.ID, s)
+		}
+		closest = max(closest, s)
+	}
+	return closest
+}
+
+// contentWords returns the distinct lower-case words of a text, leaving out
+// the short ones (articles, "is", "of") that every thought shares.
+func contentWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if len([]rune(w)) > 3 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// similarity is the Jaccard overlap of two word sets, from 0 to 1.
+func similarity(a, b map[string]bool) float64 {
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	if union := len(a) + len(b) - shared; union > 0 {
+		return float64(shared) / float64(union)
+	}
+	return 0
+}
+
+// spreadActivation passes some of the clarity a thought just gained on to the
+// thoughts linked to it, in proportion to each link's weight. It returns the
+// event describing the spread, if any clarity moved.
+func (ctx *MindContext) spreadActivation(entityID string, source Thought, gain float64) []Event {
+	total, reached := 0.0, 0
+	for _, n := range ctx.Neighbors(source.ID) {
+		t := &ctx.Thoughts[n.Index]
+		old := t.Clarity
+		t.Clarity = min(t.Clarity+gain*n.Weight*ctx.Config.SpreadActivation, 1)
+		if t.Clarity > old {
+			total += t.Clarity - old
+			reached++
+		}
+	}
+	if reached == 0 {
+		return nil
+	}
+	return []Event{{Kind: EventActivationSpread, Entity: entityID, ThoughtID: source.ID, Thought: source.Text, Count: reached, Amount: total}}
+}
+
+// printNeighborhood writes the focused thought and its associations, for the
+// 'graph' command.
+func printNeighborhood(w io.Writer, entity *Entity) {
+	ctx := entity.Mind
+	focused := ctx.Focused()
+	if focused == nil {
+		fmt.Fprintf(w, "%s has no focused thought. Focus on one to see what it is associated with.\n", entity.ID)
+		return
+	}
+	fmt.Fprintf(w, "\n--- Associations of [%d] '%s' (Clarity: %.2f) ---\n", ctx.CurrentFocusIndex, focused.Text, focused.Clarity)
+	neighbors := ctx.Neighbors(focused.ID)
+	if len(neighbors) == 0 {
+		fmt.Fprintln(w, "  (No associations yet)")
+	}
+	for _, n := range neighbors {
+		t := ctx.Thoughts[n.Index]
+		fmt.Fprintf(w, "  %.2f -- [%d] %s (Clarity: %.2f)\n", n.Weight, n.Index, t.Text, t.Clarity)
+	}
+	fmt.Fprintf(w, "%d thoughts, %d associations in all\n", len(ctx.Thoughts), len(ctx.Links))
+}
+
+// WriteDOT writes the entity's thoughts and their associations as a Graphviz
+// graph: thicker edges are stronger links, darker nodes clearer thoughts, and
+// the focused thought is outlined in bold.
+func WriteDOT(w io.Writer, entity *Entity) error {
+	ctx := entity.Mind
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph %q {\n", entity.ID)
+	b.WriteString("\tnode [shape=box, style=filled, fontname=\"Helvetica\"];\n")
+	for i, t := range ctx.Thoughts {
+		style := "filled"
+		if i == ctx.CurrentFocusIndex {
+			style = "\"filled,bold\""
+		}
+		fmt.Fprintf(&b, "\tt%d [label=%q, style=%s, fillcolor=\"0.6 %.2f 1\"];\n", t.ID, fmt.Sprintf("[%d] %s\n%.2f", i, t.Text, t.Clarity), style, t.Clarity)
+	}
+	for _, l := range ctx.Links {
+		if ctx.ThoughtByID(l.A) == nil || ctx.ThoughtByID(l.B) == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "\tt%d -- t%d [penwidth=%.1f, label=\"%.2f\"];\n", l.A, l.B, 1+4*l.Weight, l.Weight)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOTFile exports the entity's thought graph to a file, for 'graph <file>'.
+func writeDOTFile(filename string, entity *Entity) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteDOT(f, entity); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,140 @@
+// graph_test.go
+package main
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+// linkWeight returns the weight between the thoughts with IDs a and b, or 0.
+func linkWeight(ctx *MindContext, a, b int) float64 {
+	for _, n := range ctx.Neighbors(a) {
+		if ctx.Thoughts[n.Index].ID == b {
+			return n.Weight
+		}
+	}
+	return 0
+}
+
+func TestGraph_LinksFromSimilarityFocusAndHearing(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("the nature of reality is elusive", OriginGenerated)
+	ctx.AddThought("the internal world is vast", OriginGenerated)
+	ctx.AddThought("reality is elusive and strange", OriginGenerated)
+	if w := linkWeight(ctx, 1, 2); w != 0 {
+		t.Errorf("Expected no link between thoughts sharing only short words, got %.2f", w)
+	}
+	if w := linkWeight(ctx, 1, 3); math.Abs(w-0.5) > 1e-9 {
+		t.Errorf("Expected similar thoughts linked by their overlap 0.5, got %.2f", w)
+	}
+
+	thinking := &ThinkingState{}
+	thinking.HandleInput("e", ctx, strings.Fields("focus 0"))
+	thinking.HandleInput("e", ctx, strings.Fields("focus 1"))
+	thinking.HandleInput("e", ctx, strings.Fields("focus 1"))
+	if w := linkWeight(ctx, 2, 1); math.Abs(w-ctx.Config.LinkFocusWeight) > 1e-9 {
+		t.Errorf("Expected thoughts focused in turn linked at link_focus_weight, got %.2f", w)
+	}
+	if len(ctx.Neighbors(2)) != 1 {
+		t.Errorf("Expected refocusing the same thought not to link it to itself, got %+v", ctx.Neighbors(2))
+	}
+
+	ctx.Inbox = []Perception{{From: "a", Text: "rain outside", Clarity: 0.5}, {From: "b", Text: "someone laughing", Clarity: 0.5}}
+	thinking.HandleInput("e", ctx, strings.Fields("accept 0"))
+	(&PerceivingState{}).HandleInput("e", ctx, strings.Fields("listen"))
+	if w := linkWeight(ctx, 4, 5); math.Abs(w-ctx.Config.LinkHeardWeight) > 1e-9 {
+		t.Errorf("Expected thoughts heard in turn linked at link_heard_weight, got %.2f", w)
+	}
+}
+
+func TestGraph_CombinedChildInheritsLinks(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("embodiment shapes perception", OriginGenerated)
+	ctx.AddThought("meaning is constructed", OriginGenerated)
+	ctx.AddThought("the internal world is vast", OriginGenerated)
+	ctx.link(1, 2, 0.5)
+
+	(&ThinkingState{}).HandleInput("e", ctx, strings.Fields("combine 0 2"))
+	if w := linkWeight(ctx, 4, 2); math.Abs(w-0.5*ctx.Config.LinkCombineWeight) > 1e-9 {
+		t.Errorf("Expected the child to inherit its parent's link, scaled, got %.2f", w)
+	}
+	for _, l := range ctx.Links {
+		if l.A == 1 || l.B == 1 || l.A == 3 || l.B == 3 {
+			t.Errorf("Expected the consumed parents' links dropped, got %+v", ctx.Links)
+		}
+	}
+
+	(&ThinkingState{}).HandleInput("e", ctx, strings.Fields("combine 0 1 keep"))
+	if w := linkWeight(ctx, 5, 2); w < ctx.Config.LinkCombineWeight {
+		t.Errorf("Expected a child linked to a kept parent at link_combine_weight or more, got %.2f", w)
+	}
+}
+
+func TestGraph_LinkWeightsAreCapped(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("a", OriginGenerated)
+	ctx.AddThought("b", OriginGenerated)
+	for range 5 {
+		ctx.link(2, 1, 0.3)
+	}
+	if len(ctx.Links) != 1 || ctx.Links[0] != (Link{A: 1, B: 2, Weight: 1}) {
+		t.Errorf("Expected one link, lower id first, capped at 1, got %+v", ctx.Links)
+	}
+}
+
+func TestGraph_IntrospectionSpreadsActivation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.IntrospectGainMin, cfg.IntrospectGainMax = 0.2, 0.2
+	ctx := NewMindContext(cfg)
+	ctx.AddThought("focus", OriginGenerated)
+	ctx.AddThought("near", OriginGenerated)
+	ctx.AddThought("far", OriginGenerated)
+	ctx.AddThought("unlinked", OriginGenerated)
+	ctx.link(1, 2, 1)
+	ctx.link(1, 3, 0.5)
+	ctx.CurrentFocusIndex = 0
+
+	_, events := (&ReflectingState{}).HandleInput("e", ctx, strings.Fields("introspect"))
+	want := []float64{0.3, 0.2, 0.15, 0.1} // Gain 0.2 times weight times spread_activation 0.5
+	for i, w := range want {
+		if math.Abs(ctx.Thoughts[i].Clarity-w) > 1e-9 {
+			t.Errorf("thought[%d]: Expected clarity %.2f, got %.2f", i, w, ctx.Thoughts[i].Clarity)
+		}
+	}
+	if len(events) != 2 || events[1].Kind != EventActivationSpread || events[1].Count != 2 || math.Abs(events[1].Amount-0.15) > 1e-9 {
+		t.Errorf("Expected an ActivationSpread event reaching 2 thoughts with 0.15 clarity, got %+v", events)
+	}
+}
+
+func TestGraph_PrintAndDOT(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("first", OriginGenerated)
+	ctx.AddThought(`a "quoted" thought`, OriginGenerated)
+	ctx.link(1, 2, 0.4)
+	entity := &Entity{ID: "e", Mind: ctx}
+
+	var out bytes.Buffer
+	printNeighborhood(&out, entity)
+	if !strings.Contains(out.String(), "no focused thought") {
+		t.Errorf("Expected a hint without focus, got %q", out.String())
+	}
+	ctx.CurrentFocusIndex = 0
+	out.Reset()
+	printNeighborhood(&out, entity)
+	if !strings.Contains(out.String(), `0.40 -- [1] a "quoted" thought`) {
+		t.Errorf("Expected the neighbor listed with its weight, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := WriteDOT(&out, entity); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	for _, want := range []string{`graph "e" {`, `t2 [label="[1] a \"quoted\" thought\n0.10"`, `style="filled,bold"`, "t1 -- t2 [penwidth=2.6"} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT to contain %q, got:\n%s", want, dot)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// journal.go
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Journal record kinds.
+const (
+	JournalSnapshot = "snapshot" // Full simulation state in the save format
+	JournalTurn     = "turn"     // One entity's turn
+)
+
+// JournalRecord is one line of a session journal. A journal starts with a
+// snapshot, followed by one turn record per entity per tick. Loading a game
+// mid-session writes a fresh snapshot.
+type JournalRecord struct {
+	Kind        string          `json:"kind"`
+	Snapshot    json.RawMessage `json:"snapshot,omitempty"`
+	Tick        int             `json:"tick,omitempty"`
+	Entity      string          `json:"entity,omitempty"`
+	StateBefore string          `json:"state_before,omitempty"`
+	StateAfter  string          `json:"state_after,omitempty"`
+	Command     []string        `json:"command,omitempty"` // Empty if the entity did nothing
+	Events      []Event         `json:"events,omitempty"`
+	Digest      string          `json:"digest,omitempty"` // Fingerprint of the entity after the turn
+}
+
+// Journal is an Output that appends every turn to an append-only JSONL log,
+// from which replayJournal can rebuild the session.
+type Journal struct {
+	enc *json.Encoder
+	err error
+}
+
+// NewJournal starts a journal on w with a snapshot of sim's current state.
+func NewJournal(w io.Writer, sim *Simulation) (*Journal, error) {
+	j := &Journal{enc: json.NewEncoder(w)}
+	return j, j.Snapshot(sim)
+}
+
+// Err returns the first write error, if any. Output methods cannot return
+// errors, so callers check this instead.
+func (j *Journal) Err() error { return j.err }
+
+// Snapshot records the simulation's full state, e.g. after a load.
+func (j *Journal) Snapshot(sim *Simulation) error {
+	data, err := encodeSave(sim)
+	if err != nil {
+		return err
+	}
+	return j.write(JournalRecord{Kind: JournalSnapshot, Snapshot: data})
+}
+
+func (j *Journal) Turn(turn Turn) {
+	j.write(JournalRecord{
+		Kind:        JournalTurn,
+		Tick:        turn.Tick,
+		Entity:      turn.Entity.ID,
+		StateBefore: turn.From.GetName(),
+		StateAfter:  turn.Entity.CurrentFSMState.GetName(),
+		Command:     turn.Parts,
+		Events:      turn.Events,
+		Digest:      entityDigest(turn.Entity),
+	})
+}
+
+func (j *Journal) TickDone(sim *Simulation) {}
+
+func (j *Journal) write(rec JournalRecord) error {
+	if j.err == nil {
+		j.err = j.enc.Encode(rec)
+	}
+	return j.err
+}
+
+// entityDigest fingerprints everything about an entity that a replay must
+// reproduce: its FSM state and its whole mind.
+func entityDigest(e *Entity) string {
+	mind, _ := json.Marshal(e.Mind)
+	sum := sha256.Sum256(append([]byte(e.CurrentFSMState.GetName()+"|"), mind...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// DivergenceError reports the first turn at which a replay did not match the
+// journal.
+type DivergenceError struct {
+	Tick   int
+	Entity string
+	Reason string
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("replay diverged at tick %d (%s): %s", e.Tick, e.Entity, e.Reason)
+}
+
+// replayer feeds journaled commands back into a simulation and checks every
+// resulting turn against the record it came from.
+type replayer struct {
+	sim     *Simulation
+	scanner *bufio.Scanner
+	line    int
+	next    *JournalRecord // Peeked record, not yet consumed
+	current *JournalRecord // Turn record being replayed
+	lines   []Stimulus     // Stream lines the current turn perceived, not yet observed again
+	written []string       // Thoughts an LLM generated this turn, not yet generated again
+	rewrite []string       // Thoughts an LLM rewrote this turn, not yet rewritten again
+	err     error          // First divergence or read error
+}
+
+// peek returns the next record without consuming it, or nil at the end.
+func (r *replayer) peek() (*JournalRecord, error) {
+	if r.next != nil {
+		return r.next, nil
+	}
+	if !r.scanner.Scan() {
+		return nil, r.scanner.Err()
+	}
+	r.line++
+	var rec JournalRecord
+	if err := json.Unmarshal(r.scanner.Bytes(), &rec); err != nil {
+		return nil, fmt.Errorf("journal line %d: %w", r.line, err)
+	}
+	r.next = &rec
+	return r.next, nil
+}
+
+// restore loads a snapshot record. Policies are dropped: during a replay every
+// command comes from the journal, and the decision RNG is never touched. LLM
+// sources are replaced for the same reason.
+func (r *replayer) restore(rec *JournalRecord) error {
+	loaded, err := decodeSave(rec.Snapshot)
+	if err != nil {
+		return fmt.Errorf("journal line %d: %w", r.line, err)
+	}
+	for _, e := range loaded.entities {
+		e.Policy = nil
+		if llm, ok := e.Mind.ThoughtSource.(*LLMSource); ok {
+			e.Mind.ThoughtSource = replayedLLM{llm, r}
+		}
+	}
+	r.sim.Restore(loaded)
+	return nil
+}
+
+// input is the replay's Simulation.Input: the next journaled command.
+func (r *replayer) input(e *Entity) ([]string, error) {
+	rec, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, &DivergenceError{Tick: r.sim.tick, Entity: e.ID, Reason: "journal ended mid-tick"}
+	}
+	r.next = nil
+	if rec.Kind == JournalSnapshot {
+		// The original session loaded a game during this turn.
+		return nil, r.restore(rec)
+	}
+	if rec.Tick != r.sim.tick || rec.Entity != e.ID {
+		return nil, &DivergenceError{Tick: r.sim.tick, Entity: e.ID,
+			Reason: fmt.Sprintf("journal has %s at tick %d next", rec.Entity, rec.Tick)}
+	}
+	if rec.StateBefore != e.CurrentFSMState.GetName() {
+		return nil, &DivergenceError{Tick: rec.Tick, Entity: e.ID,
+			Reason: fmt.Sprintf("state before is %s, journal says %s", e.CurrentFSMState.GetName(), rec.StateBefore)}
+	}
+	r.current = rec
+	r.lines, r.written, r.rewrite = nil, nil, nil
+	for _, event := range rec.Events {
+		switch {
+		case event.Kind == EventPerceived && isStreamSource(event.From):
+			r.lines = append(r.lines, Stimulus{Source: event.From, Text: event.Thought})
+		case event.Kind == EventThoughtGenerated:
+			r.written = append(r.written, event.Thought)
+		case event.Kind == EventIntrospected && event.From != "":
+			r.rewrite = append(r.rewrite, event.Thought)
+		}
+	}
+	return rec.Command, nil
+}
+
+// Next stands in for the original session's text streams, which a replay
+// can't read again: it hands back the lines the journal says were perceived.
+func (r *replayer) Next() (Stimulus, bool) {
+	if len(r.lines) == 0 {
+		return Stimulus{}, false
+	}
+	stimulus := r.lines[0]
+	r.lines = r.lines[1:]
+	return stimulus, true
+}
+
+// Turn verifies the rebuilt entity against the journal after each turn.
+func (r *replayer) Turn(turn Turn) {
+	rec := r.current
+	r.current = nil
+	if rec == nil || r.err != nil {
+		return
+	}
+	switch {
+	case turn.Entity.CurrentFSMState.GetName() != rec.StateAfter:
+		r.err = &DivergenceError{Tick: rec.Tick, Entity: rec.Entity,
+			Reason: fmt.Sprintf("state after is %s, journal says %s", turn.Entity.CurrentFSMState.GetName(), rec.StateAfter)}
+	case entityDigest(turn.Entity) != rec.Digest:
+		r.err = &DivergenceError{Tick: rec.Tick, Entity: rec.Entity,
+			Reason: fmt.Sprintf("mind digest %s does not match journal %s", entityDigest(turn.Entity), rec.Digest)}
+	}
+}
+
+func (r *replayer) TickDone(sim *Simulation) {}
+
+// replayJournal rebuilds a session from its journal, verifying every turn, and
+// returns the simulation as of the end of tick until (or the end of the
+// journal if until <= 0). A mismatch is reported as a *DivergenceError.
+func replayJournal(journal io.Reader, until int) (*Simulation, error) {
+	r := &replayer{scanner: bufio.NewScanner(journal)}
+	r.scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Snapshots can be long lines
+
+	first, err := r.peek()
+	if err != nil {
+		return nil, err
+	}
+	if first == nil || first.Kind != JournalSnapshot {
+		return nil, errors.New("journal does not start with a snapshot")
+	}
+	r.next = nil
+	r.sim = NewSimulation(0, nil)
+	if err := r.restore(first); err != nil {
+		return nil, err
+	}
+	r.sim.Input = r.input
+	r.sim.Output = r
+	r.sim.SetEnvironment(NewEnvironment(r))
+
+	for {
+		rec, err := r.peek()
+		if err != nil {
+			return r.sim, err
+		}
+		if rec == nil || (until > 0 && rec.Kind == JournalTurn && rec.Tick > until) {
+			return r.sim, nil
+		}
+		if err := r.sim.Step(); err != nil {
+			return r.sim, err
+		}
+		if r.err != nil {
+			return r.sim, r.err
+		}
+	}
+}
+
+// replayedLLM stands in for an LLMSource during a replay. The endpoint may
+// answer differently, or not at all, so the journaled texts are used instead.
+type replayedLLM struct {
+	*LLMSource
+	r *replayer
+}
+
+func (s replayedLLM) Generate(ctx *MindContext) string {
+	fallback := DefaultThoughtSource().Generate(ctx) // Drawn by LLMSource too
+	if len(s.r.written) == 0 {
+		return fallback
+	}
+	text := s.r.written[0]
+	s.r.written = s.r.written[1:]
+	return text
+}
+
+func (s replayedLLM) Rewrite(ctx *MindContext, thought Thought) (string, bool) {
+	if len(s.r.rewrite) == 0 {
+		return "", false
+	}
+	text := s.r.rewrite[0]
+	s.r.rewrite = s.r.rewrite[1:]
+	return text, true
+}
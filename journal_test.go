@@ -0,0 +1,138 @@
+// journal_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// journaledRun runs an autopilot session of n ticks with a journal attached.
+func journaledRun(t *testing.T, seed int64, n int) (*Simulation, *bytes.Buffer) {
+	t.Helper()
+	sim := NewSimulation(seed, NewDefaultEntities())
+	sim.SetAutopilot(true)
+	var buf bytes.Buffer
+	journal, err := NewJournal(&buf, sim)
+	if err != nil {
+		t.Fatalf("NewJournal: %v", err)
+	}
+	sim.Output = journal
+	if err := sim.Run(context.Background(), n); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if journal.Err() != nil {
+		t.Fatalf("Journal: %v", journal.Err())
+	}
+	return sim, &buf
+}
+
+func TestReplayJournal_RebuildsSession(t *testing.T) {
+	original, journal := journaledRun(t, 11, 150)
+
+	replayed, err := replayJournal(journal, 0)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if replayed.Tick() != 150 {
+		t.Errorf("replayJournal: Expected tick 150, got %d", replayed.Tick())
+	}
+	if mindsJSON(t, original) != mindsJSON(t, replayed) {
+		t.Errorf("Replay does not match original:\n%s\nvs\n%s", mindsJSON(t, original), mindsJSON(t, replayed))
+	}
+}
+
+func TestReplayJournal_StopsAtTick(t *testing.T) {
+	_, journal := journaledRun(t, 11, 150)
+	partial, _ := journaledRun(t, 11, 60)
+
+	replayed, err := replayJournal(journal, 60)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if replayed.Tick() != 60 {
+		t.Errorf("replayJournal until 60: Expected tick 60, got %d", replayed.Tick())
+	}
+	if mindsJSON(t, partial) != mindsJSON(t, replayed) {
+		t.Errorf("Replay to tick 60 does not match a 60-tick run")
+	}
+}
+
+func TestReplayJournal_DetectsDivergence(t *testing.T) {
+	_, journal := journaledRun(t, 11, 30)
+	lines := strings.Split(strings.TrimSpace(journal.String()), "\n")
+	// Corrupt the digest of a turn record half way through.
+	target := len(lines) / 2
+	digest := lines[target][strings.Index(lines[target], `"digest":"`)+10:]
+	digest = digest[:strings.Index(digest, `"`)]
+	lines[target] = strings.Replace(lines[target], digest, "0000000000000000", 1)
+
+	_, err := replayJournal(strings.NewReader(strings.Join(lines, "\n")), 0)
+	var divergence *DivergenceError
+	if !errors.As(err, &divergence) {
+		t.Fatalf("replayJournal: Expected a DivergenceError, got %v", err)
+	}
+	if !strings.Contains(divergence.Reason, "digest") {
+		t.Errorf("replayJournal: Expected a digest mismatch, got %q", divergence.Reason)
+	}
+}
+
+func TestReplayJournal_FollowsMidSessionLoad(t *testing.T) {
+	saved := NewSimulation(5, NewDefaultEntities())
+	saved.SetAutopilot(true)
+	saved.Run(context.Background(), 20)
+
+	sim := NewSimulation(9, NewDefaultEntities())
+	var buf bytes.Buffer
+	journal, _ := NewJournal(&buf, sim)
+	sim.Output = journal
+	loads := 0
+	sim.Input = func(e *Entity) ([]string, error) {
+		if sim.Tick() == 3 && loads == 0 {
+			loads++
+			sim.Restore(saved) // As the REPL's 'load' command does
+			return nil, journal.Snapshot(sim)
+		}
+		return []string{"recharge"}, nil
+	}
+	sim.Run(context.Background(), 40)
+
+	replayed, err := replayJournal(&buf, 0)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if mindsJSON(t, sim) != mindsJSON(t, replayed) {
+		t.Errorf("Replay across a load does not match original")
+	}
+}
+
+func TestReplayJournal_RestoresStreamPerceptions(t *testing.T) {
+	sim := NewSimulation(5, NewDefaultEntities())
+	sim.SetEnvironment(NewEnvironment(NewTextStream("feed", strings.NewReader("the lights flicker\n"))))
+	commands := [][]string{{"perceive"}, {"observe"}, {"observe"}}
+	sim.Input = func(e *Entity) ([]string, error) {
+		c := commands[0]
+		commands = commands[1:]
+		return c, nil
+	}
+	var buf bytes.Buffer
+	journal, err := NewJournal(&buf, sim)
+	if err != nil {
+		t.Fatalf("NewJournal: %v", err)
+	}
+	sim.Output = journal
+	sim.Run(context.Background(), 3)
+
+	replayed, err := replayJournal(&buf, 0)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if mindsJSON(t, sim) != mindsJSON(t, replayed) {
+		t.Errorf("Replay of stream perceptions does not match original:\n%s\nvs\n%s", mindsJSON(t, sim), mindsJSON(t, replayed))
+	}
+	if thought := replayed.Player().Mind.Thoughts[0]; thought.Text != "the lights flicker" {
+		t.Errorf("replayJournal: Expected the journaled stream line, got %+v", thought)
+	}
+}
@@ -0,0 +1,263 @@
+// llm.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// MaxLLMCacheEntries bounds the response cache; the oldest answers go first.
+const MaxLLMCacheEntries = 500
+
+// LLMSource generates thoughts, and rewrites them during introspection, by
+// asking an OpenAI-compatible chat-completions endpoint such as a local
+// llama.cpp server. Whenever the endpoint can't answer it falls back to the
+// built-in phrases, so a run never stalls on the network.
+type LLMSource struct {
+	Endpoint string     `json:"endpoint"` // Base URL; "/chat/completions" is appended unless present
+	Model    string     `json:"model"`
+	Cache    []llmEntry `json:"cache,omitempty"` // Saved, so a loaded game reuses earlier answers
+	Failures int        `json:"-"`               // Calls that fell back since startup
+
+	cache     map[string]string
+	client    *http.Client
+	downUntil time.Time // After a failed call the endpoint is left alone for llm_cooldown_ms
+}
+
+// llmEntry is one cached answer, keyed by the prompt that produced it.
+type llmEntry struct {
+	Prompt   string `json:"prompt"`
+	Response string `json:"response"`
+}
+
+// llmAPIKeyEnv names the environment variable holding the endpoint's API key.
+// The key is never saved.
+const llmAPIKeyEnv = "QUALIA_LLM_API_KEY"
+
+// NewLLMSource creates a source for the endpoint and model.
+func NewLLMSource(endpoint, model string) *LLMSource {
+	return &LLMSource{Endpoint: endpoint, Model: model}
+}
+
+func (l *LLMSource) Kind() string { return "llm" }
+func (l *LLMSource) Name() string { return fmt.Sprintf("%s at %s", l.Model, l.Endpoint) }
+
+// Rewriter is implemented by thought sources that can also rephrase a thought
+// more clearly. introspectEffect uses it when the mind's source has one.
+type Rewriter interface {
+	Rewrite(ctx *MindContext, thought Thought) (string, bool)
+}
+
+const llmSystemPrompt = "You are the inner voice of a mind in a simulation of consciousness. Answer with a single short thought of at most 20 words, on one line, without quotes or commentary."
+
+// Generate asks for a new thought that follows on from the mind's recent ones.
+func (l *LLMSource) Generate(ctx *MindContext) string {
+	// Always draw the fallback, so the RNG moves the same whether or not the
+	// endpoint answers and a replay stays in step.
+	fallback := DefaultThoughtSource().Generate(ctx)
+
+	var prompt strings.Builder
+	recent := ctx.Thoughts[max(len(ctx.Thoughts)-5, 0):]
+	if len(recent) == 0 {
+		prompt.WriteString("Your mind is empty so far.\n")
+	} else {
+		prompt.WriteString("Your recent thoughts:\n")
+		for _, t := range recent {
+			fmt.Fprintf(&prompt, "- %s\n", t.Text)
+		}
+	}
+	prompt.WriteString("What do you think next?")
+
+	text, err := l.complete(ctx.Context, ctx.Config, prompt.String())
+	if err != nil {
+		return fallback
+	}
+	return text
+}
+
+// Rewrite asks for a clearer version of the thought with the same meaning.
+func (l *LLMSource) Rewrite(ctx *MindContext, thought Thought) (string, bool) {
+	text, err := l.complete(ctx.Context, ctx.Config, "Rewrite this thought so it is clearer and more precise, keeping its meaning:\n"+thought.Text)
+	if err != nil || text == thought.Text {
+		return "", false
+	}
+	return text, true
+}
+
+// complete returns the endpoint's answer to prompt, from the cache if it has
+// been asked before, retrying failed calls up to llm_retries times. All the
+// attempts together get llm_timeout_ms, and stop early if ctx is cancelled.
+func (l *LLMSource) complete(ctx context.Context, cfg *Config, prompt string) (string, error) {
+	if l.cache == nil {
+		l.cache = make(map[string]string, len(l.Cache))
+		for _, e := range l.Cache {
+			l.cache[e.Prompt] = e.Response
+		}
+	}
+	if text, ok := l.cache[prompt]; ok {
+		return text, nil
+	}
+	if time.Now().Before(l.downUntil) {
+		l.Failures++
+		return "", errors.New("endpoint is cooling down after a failure")
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.LLMTimeoutMS)*time.Millisecond)
+	defer cancel()
+	var err error
+	for attempt := 0; attempt <= cfg.LLMRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt*cfg.LLMRetryDelayMS) * time.Millisecond):
+			case <-ctx.Done():
+			}
+		}
+		var text string
+		var retry bool
+		text, retry, err = l.request(ctx, cfg, prompt)
+		if err == nil {
+			l.remember(prompt, text)
+			return text, nil
+		}
+		if !retry || ctx.Err() != nil {
+			break
+		}
+	}
+	l.Failures++
+	l.downUntil = time.Now().Add(time.Duration(cfg.LLMCooldownMS) * time.Millisecond)
+	return "", err
+}
+
+// remember caches an answer, dropping the oldest beyond MaxLLMCacheEntries.
+func (l *LLMSource) remember(prompt, text string) {
+	l.cache[prompt] = text
+	l.Cache = append(l.Cache, llmEntry{prompt, text})
+	if len(l.Cache) > MaxLLMCacheEntries {
+		delete(l.cache, l.Cache[0].Prompt)
+		l.Cache = l.Cache[1:]
+	}
+}
+
+// request makes one call to the endpoint. retry reports whether the failure
+// might go away: timeouts, connection errors, rate limits and server errors.
+func (l *LLMSource) request(ctx context.Context, cfg *Config, prompt string) (text string, retry bool, err error) {
+	body, err := json.Marshal(map[string]any{
+		"model": l.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": llmSystemPrompt},
+			{"role": "user", "content": prompt},
+		},
+		"temperature": cfg.LLMTemperature,
+		"max_tokens":  cfg.LLMMaxTokens,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url(), bytes.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if key := os.Getenv(llmAPIKeyEnv); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	if l.client == nil {
+		l.client = &http.Client{}
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "", resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, fmt.Errorf("%s: %s", l.url(), resp.Status)
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", false, fmt.Errorf("%s: invalid response: %w", l.url(), err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", false, fmt.Errorf("%s: response has no choices", l.url())
+	}
+	if text = cleanCompletion(completion.Choices[0].Message.Content); text == "" {
+		return "", false, fmt.Errorf("%s: empty answer", l.url())
+	}
+	return text, false, nil
+}
+
+func (l *LLMSource) url() string {
+	if strings.HasSuffix(l.Endpoint, "/chat/completions") {
+		return l.Endpoint
+	}
+	return strings.TrimRight(l.Endpoint, "/") + "/chat/completions"
+}
+
+// cleanCompletion makes an answer look like the other thoughts: its first
+// non-empty line, without surrounding quotes or a final full stop.
+func cleanCompletion(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.Trim(strings.TrimSpace(line), `"'“”`); line != "" {
+			return strings.TrimSuffix(line, ".")
+		}
+	}
+	return ""
+}
+
+func (l *LLMSource) validate() error {
+	if l.Endpoint == "" || l.Model == "" {
+		return errors.New("llm source needs an endpoint and a model")
+	}
+	return nil
+}
+
+// llmFlags collects repeated --llm [entity=]url flags.
+type llmFlags []string
+
+func (f *llmFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *llmFlags) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// apply gives each named entity, or every entity, its own LLM source for the
+// endpoint.
+func (f llmFlags) apply(entities []*Entity, model string) error {
+	for _, spec := range f {
+		id, endpoint, ok := strings.Cut(spec, "=")
+		if !ok || strings.Contains(id, "/") {
+			id, endpoint = "", spec // A bare URL may itself contain '='
+		}
+		found := false
+		for _, e := range entities {
+			if id == "" || e.ID == id {
+				e.Mind.ThoughtSource = NewLLMSource(endpoint, model)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("--llm %s: no entity %q", spec, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+// llm_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLLM is an OpenAI-compatible stand-in. Each call gets the next status
+// from statuses (200 once they run out) and answers with reply.
+type fakeLLM struct {
+	*httptest.Server
+	calls    atomic.Int32
+	statuses []int
+	reply    func(prompt string) string
+	last     map[string]any
+	auth     string
+}
+
+func newFakeLLM(t *testing.T, reply func(prompt string) string, statuses ...int) *fakeLLM {
+	f := &fakeLLM{statuses: statuses, reply: reply}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(f.calls.Add(1))
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if n <= len(f.statuses) && f.statuses[n-1] != http.StatusOK {
+			w.WriteHeader(f.statuses[n-1])
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&f.last)
+		f.auth = r.Header.Get("Authorization")
+		messages := f.last["messages"].([]any)
+		prompt := messages[len(messages)-1].(map[string]any)["content"].(string)
+		fmt.Fprintf(w, `{"choices": [{"message": {"role": "assistant", "content": %q}}]}`, f.reply(prompt))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// llmMind returns a mind thinking with an LLM source, without retry delays
+// or cooldowns.
+func llmMind(endpoint string) (*MindContext, *LLMSource) {
+	cfg := DefaultConfig()
+	cfg.LLMRetryDelayMS = 0
+	cfg.LLMCooldownMS = 0
+	ctx := NewMindContext(cfg)
+	ctx.Rand = NewRNG(1)
+	source := NewLLMSource(endpoint, "test-model")
+	ctx.ThoughtSource = source
+	return ctx, source
+}
+
+func TestLLMSource_GenerateFollowsRecentThoughts(t *testing.T) {
+	t.Setenv(llmAPIKeyEnv, "secret")
+	server := newFakeLLM(t, func(string) string { return "\"The body remembers what the mind forgets.\"\nAnything else?" })
+	ctx, source := llmMind(server.URL + "/v1")
+	ctx.AddThought("embodiment shapes perception", OriginGenerated)
+
+	if got := source.Generate(ctx); got != "The body remembers what the mind forgets" {
+		t.Errorf("Generate: Expected the cleaned answer, got %q", got)
+	}
+	messages, _ := json.Marshal(server.last["messages"])
+	if server.last["model"] != "test-model" || !bytes.Contains(messages, []byte("embodiment shapes perception")) {
+		t.Errorf("Generate: Expected the model and recent thoughts in the request, got %v", server.last)
+	}
+	if server.auth != "Bearer secret" {
+		t.Errorf("Generate: Expected the API key from %s, got %q", llmAPIKeyEnv, server.auth)
+	}
+}
+
+func TestLLMSource_CachesByPrompt(t *testing.T) {
+	server := newFakeLLM(t, func(string) string { return "attention is a kind of hunger" })
+	ctx, source := llmMind(server.URL + "/v1/chat/completions")
+
+	first, second := source.Generate(ctx), source.Generate(ctx)
+	if first != second || server.calls.Load() != 1 {
+		t.Errorf("Generate: Expected one call for the same prompt, got %d calls", server.calls.Load())
+	}
+
+	saved, err := saveThoughtSource(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := saved.restore()
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	server.Close()
+	if got := restored.Generate(ctx); got != first {
+		t.Errorf("Generate: Expected the saved cache to answer without the endpoint, got %q", got)
+	}
+}
+
+func TestLLMSource_RetriesThenFallsBack(t *testing.T) {
+	server := newFakeLLM(t, func(string) string { return "third time lucky" }, http.StatusInternalServerError, http.StatusTooManyRequests)
+	ctx, source := llmMind(server.URL + "/v1")
+	if got := source.Generate(ctx); got != "third time lucky" || server.calls.Load() != 3 {
+		t.Errorf("Generate: Expected success on the third attempt, got %q after %d calls", got, server.calls.Load())
+	}
+
+	down := newFakeLLM(t, nil, 500, 500, 500)
+	ctx, source = llmMind(down.URL + "/v1")
+	ctx.AddThought("something new", OriginGenerated)
+	if got := source.Generate(ctx); !slices.Contains(potentialThoughts, got) || source.Failures != 1 || down.calls.Load() != 3 {
+		t.Errorf("Generate: Expected a built-in thought after 3 failed calls, got %q after %d calls", got, down.calls.Load())
+	}
+
+	rejected := newFakeLLM(t, nil, http.StatusBadRequest)
+	ctx, source = llmMind(rejected.URL + "/v1")
+	source.Generate(ctx)
+	if rejected.calls.Load() != 1 {
+		t.Errorf("Generate: Expected no retry after a 400, got %d calls", rejected.calls.Load())
+	}
+}
+
+func TestLLMSource_TimeoutAndCooldown(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	ctx, source := llmMind(slow.URL)
+	ctx.Config.LLMTimeoutMS = 20
+	ctx.Config.LLMRetries = 0
+	ctx.Config.LLMCooldownMS = 60000
+	start := time.Now()
+	if got := source.Generate(ctx); !slices.Contains(potentialThoughts, got) {
+		t.Errorf("Generate: Expected a built-in thought after a timeout, got %q", got)
+	}
+	source.Generate(ctx) // Cooling down: must not wait for the endpoint again
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Generate: Expected the timeout and cooldown to keep calls short, took %v", elapsed)
+	}
+	if source.Failures != 2 {
+		t.Errorf("Generate: Expected 2 fallbacks, got %d", source.Failures)
+	}
+}
+
+func TestLLMSource_TimeoutCoversEveryAttempt(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	ctx, source := llmMind(slow.URL)
+	ctx.Config.LLMTimeoutMS = 100
+	ctx.Config.LLMRetries = 5
+	start := time.Now()
+	source.Generate(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Generate: Expected llm_timeout_ms to bound the whole call, took %v", elapsed)
+	}
+}
+
+func TestSimulationRun_CancelCutsLLMCallsShort(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	player := NewDefaultEntities()[0]
+	player.CurrentFSMState = &ThinkingState{}
+	player.Mind.ThoughtSource = NewLLMSource(slow.URL, "test-model")
+	sim := NewSimulation(1, []*Entity{player})
+	sim.Config().LLMTimeoutMS = 60000
+	sim.Input = func(*Entity) ([]string, error) { return []string{"generate"}, nil }
+
+	run, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	sim.Run(run, 1)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run: Expected cancelling to cut the LLM call short, took %v", elapsed)
+	}
+	if len(player.Mind.Thoughts) != 1 || !slices.Contains(potentialThoughts, player.Mind.Thoughts[0].Text) {
+		t.Errorf("Run: Expected a built-in thought in place of the LLM's, got %+v", player.Mind.Thoughts)
+	}
+}
+
+func TestIntrospect_RewritesWithLLM(t *testing.T) {
+	server := newFakeLLM(t, func(prompt string) string {
+		return "clearly, " + prompt[strings.LastIndex(prompt, "\n")+1:]
+	})
+	ctx, _ := llmMind(server.URL + "/v1")
+	ctx.AddThought("meaning is constructed", OriginGenerated)
+	ctx.CurrentFocusIndex = 0
+
+	_, events := (&ReflectingState{}).HandleInput("e", ctx, []string{"introspect"})
+	if got := ctx.Thoughts[0].Text; got != "clearly, meaning is constructed" {
+		t.Errorf("introspect: Expected the thought rewritten, got %q", got)
+	}
+	if events[0].From != "meaning is constructed" || events[0].Thought != "clearly, meaning is constructed" || !strings.Contains(events[0].String(), "rephrased") {
+		t.Errorf("introspect: Expected the event to record the rewrite, got %+v", events[0])
+	}
+}
+
+func TestReplayJournal_ReusesLLMAnswers(t *testing.T) {
+	n := 0
+	server := newFakeLLM(t, func(string) string { n++; return fmt.Sprintf("answer number %d", n) })
+	entities := NewDefaultEntities()
+	entities[1].Mind.ThoughtSource = NewLLMSource(server.URL+"/v1", "test-model")
+	sim := NewSimulation(8, entities)
+	commands := [][]string{{"think"}, {"generate"}, {"focus", "0"}, {"idle"}, {"reflect"}, {"introspect"}}
+	sim.Input = func(e *Entity) ([]string, error) {
+		c := commands[0]
+		commands = commands[1:]
+		return c, nil
+	}
+	sim.Player().Mind.ThoughtSource = NewLLMSource(server.URL+"/v1", "test-model")
+	var buf bytes.Buffer
+	journal, err := NewJournal(&buf, sim)
+	if err != nil {
+		t.Fatalf("NewJournal: %v", err)
+	}
+	sim.Output = journal
+	sim.Run(context.Background(), len(commands))
+	server.Close() // The replay must not need the endpoint
+	if !strings.Contains(buf.String(), `"kind":"Introspected","entity":"Player-1"`) || !strings.Contains(buf.String(), `"from":"answer number 1"`) {
+		t.Fatalf("Expected the journal to record the player's rewritten thought, got %s", buf.String())
+	}
+
+	replayed, err := replayJournal(&buf, 0)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if mindsJSON(t, sim) != mindsJSON(t, replayed) {
+		t.Errorf("Replay with an LLM does not match original:\n%s\nvs\n%s", mindsJSON(t, sim), mindsJSON(t, replayed))
+	}
+}
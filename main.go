@@ -2,29 +2,18 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
+	"io"
 	"os"
 	"strings"
 	"time"
 )
 
-const MAX_EVENT_LOG_SIZE = 7 // Number of recent events to display
-var eventLog []string        // Global list to store recent events
-
-// addEventToLog adds a new event to the global event log.
-func addEventToLog(event string) {
-	eventLog = append(eventLog, fmt.Sprintf("[%s] %s", time.Now().Format("15:04:05"), event))
-	if len(eventLog) > MAX_EVENT_LOG_SIZE {
-		eventLog = eventLog[len(eventLog)-MAX_EVENT_LOG_SIZE:]
-	}
-}
-
 // clearScreen clears the terminal.
-func clearScreen() {
-	fmt.Print("\033[H\033[2J") // ANSI escape code to clear screen and move cursor to top-left
+func clearScreen(w io.Writer) {
+	fmt.Fprint(w, "\033[H\033[2J") // ANSI escape code to clear screen and move cursor to top-left
 }
 
 // renderBar creates a simple text-based progress bar.
@@ -44,436 +33,497 @@ func renderBar(current, max int, length int, colorCode string) string {
 }
 
 // renderGlobalDashboard displays the state of all entities and recent events.
-func renderGlobalDashboard(entities []*Entity) {
-	clearScreen()
-	fmt.Println("====== Qualia Simulation Dashboard (Observer Mode) ======")
-	fmt.Printf("Current Time: %s | Player Autopilot: ENABLED\n", time.Now().Format("15:04:05"))
-	fmt.Println(strings.Repeat("-", 60))
+func renderGlobalDashboard(w io.Writer, sim *Simulation) {
+	entities := sim.Entities()
+	eventLog := sim.EventLog()
+	clearScreen(w)
+	fmt.Fprintln(w, "====== Qualia Simulation Dashboard (Observer Mode) ======")
+	fmt.Fprintf(w, "Current Time: %s | Player Autopilot: ENABLED\n", time.Now().Format("15:04:05"))
+	fmt.Fprintln(w, strings.Repeat("-", 60))
 
 	for _, entity := range entities {
 		entityType := "AI"
 		if entity.IsPlayer {
 			entityType = "Player"
 		}
-		fmt.Printf("| %-10s (%-6s) | State: %-12s \n", entity.ID, entityType, entity.CurrentFSMState.GetName())
+		policyName := "manual"
+		if entity.Policy != nil {
+			policyName = entity.Policy.Name()
+		}
+		stateName := entity.CurrentFSMState.GetName()
+		if stateName == "Dreaming" {
+			stateName += " zZ"
+		}
+		fmt.Fprintf(w, "| %-10s (%-6s) | State: %-12s | Policy: %s \n", entity.ID, entityType, stateName, policyName)
 
 		energyColor := "\033[32m" // Green
-		if entity.Mind.Energy < entity.Mind.MaxEnergy/3 {
+		if entity.CurrentFSMState.GetName() == "Dreaming" {
+			energyColor = "\033[35m" // Magenta while restoring in a dream
+		} else if entity.Mind.Energy < entity.Mind.MaxEnergy/3 {
 			energyColor = "\033[31m" // Red
 		} else if entity.Mind.Energy < entity.Mind.MaxEnergy*2/3 {
 			energyColor = "\033[33m" // Yellow
 		}
-		fmt.Printf("| Energy: %3d/%3d [%-20s] | Thoughts: %2d \n", entity.Mind.Energy, entity.Mind.MaxEnergy, renderBar(entity.Mind.Energy, entity.Mind.MaxEnergy, 20, energyColor), len(entity.Mind.Thoughts))
+		fmt.Fprintf(w, "| Energy: %3d/%3d [%-20s] | Thoughts: %2d | Heard: %d \n", entity.Mind.Energy, entity.Mind.MaxEnergy, renderBar(entity.Mind.Energy, entity.Mind.MaxEnergy, 20, energyColor), len(entity.Mind.Thoughts), len(entity.Mind.Inbox))
 
 		focusedThoughtStr := "None"
 		clarityBarStr := renderBar(0, 100, 20, "\033[37m") // Default empty bar (white)
 		clarityValStr := "---"
 
-		if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.CurrentFocusIndex < len(entity.Mind.Thoughts) {
-			focusedThought := entity.Mind.Thoughts[entity.Mind.CurrentFocusIndex]
-			if len(focusedThought) > 25 {
-				focusedThoughtStr = focusedThought[:22] + "..."
+		if focused := entity.Mind.Focused(); focused != nil {
+			if len(focused.Text) > 25 {
+				focusedThoughtStr = focused.Text[:22] + "..."
 			} else {
-				focusedThoughtStr = focusedThought
+				focusedThoughtStr = focused.Text
 			}
-			clarityPercentage := int(entity.Mind.Clarity * 100)
-			clarityValStr = fmt.Sprintf("%.2f", entity.Mind.Clarity)
+			clarityPercentage := int(focused.Clarity * 100)
+			clarityValStr = fmt.Sprintf("%.2f", focused.Clarity)
 
 			clarityColor := "\033[34m" // Blue
-			if entity.Mind.Clarity < 0.33 {
+			if focused.Clarity < 0.33 {
 				clarityColor = "\033[31m" // Red
-			} else if entity.Mind.Clarity < 0.66 {
+			} else if focused.Clarity < 0.66 {
 				clarityColor = "\033[33m" // Yellow
 			}
 			clarityBarStr = renderBar(clarityPercentage, 100, 20, clarityColor)
 		}
-		fmt.Printf("| Focus:  %-25s | Clarity: %-4s [%-20s] \n", "'"+focusedThoughtStr+"'", clarityValStr, clarityBarStr)
-		fmt.Println(strings.Repeat("-", 60))
+		fmt.Fprintf(w, "| Focus:  %-25s | Clarity: %-4s [%-20s] \n", "'"+focusedThoughtStr+"'", clarityValStr, clarityBarStr)
+		affect := entity.Mind.Affect
+		fmt.Fprintf(w, "| Mood:   %s%-25s\033[0m | Valence: %+.2f | Arousal: %.2f | Threshold: %.2f \n", moodColors[affect.Mood()], affect.Mood(), affect.Valence, affect.Arousal, entity.Mind.EffectiveThreshold())
+		if goals := entity.Mind.Goals; len(goals) > 0 {
+			more := ""
+			if len(goals) > 1 {
+				more = fmt.Sprintf(" (+%d more)", len(goals)-1)
+			}
+			fmt.Fprintf(w, "| Goal:   %s [%s] %.0f%%%s \n", goals[0], renderBar(int(goals[0].Progress*100), 100, 10, "\033[36m"), goals[0].Progress*100, more)
+		}
+		if plan := planOf(entity); len(plan) > 0 {
+			fmt.Fprintf(w, "| Plan:   %s \n", formatPlan(plan, 6))
+		}
+		if tree := treeOf(entity); tree != nil {
+			branch := tree.Branch()
+			if branch == "" {
+				branch = "(no action)"
+			}
+			fmt.Fprintf(w, "| Branch: %s \n", branch)
+		}
+		fmt.Fprintln(w, strings.Repeat("-", 60))
 	}
 
-	fmt.Println("\nRecent Events:")
+	fmt.Fprintln(w, "\nRecent Events:")
 	if len(eventLog) == 0 {
-		fmt.Println("  (No events yet)")
+		fmt.Fprintln(w, "  (No events yet)")
 	}
 	for i := len(eventLog) - 1; i >= 0; i-- { // Display newest first
-		fmt.Printf("  %s\n", eventLog[i])
+		fmt.Fprintf(w, "  [tick %d] %s\n", eventLog[i].Tick, eventLog[i])
 	}
-	fmt.Println("===========================================================")
+	fmt.Fprintln(w, "===========================================================")
 	// No explicit prompt in dashboard mode, it just updates.
 }
 
+// treeOf returns the behavior tree the entity's policy decides with, if any.
+func treeOf(entity *Entity) *BehaviorTree {
+	if policy, ok := entity.Policy.(*BehaviorTreePolicy); ok {
+		return policy.Tree
+	}
+	return nil
+}
+
+// planOf returns the plan the entity's policy is working to, if it plans.
+func planOf(entity *Entity) []Command {
+	if planner, ok := entity.Policy.(Planning); ok {
+		return planner.Plan(entity.View())
+	}
+	return nil
+}
+
+// moodColors colours each mood on the dashboard.
+var moodColors = map[Mood]string{
+	MoodNeutral:   "\033[37m", // White
+	MoodContent:   "\033[32m", // Green
+	MoodExcited:   "\033[33m", // Yellow
+	MoodIrritable: "\033[31m", // Red
+	MoodGloomy:    "\033[34m", // Blue
+}
+
+// lineage describes a combined thought's parents by ID, and theirs in turn
+// while the mind still holds them, e.g. "#4 + #5 (#2 + #3)".
+func lineage(ctx *MindContext, thought Thought) string {
+	parts := make([]string, len(thought.Parents))
+	for i, id := range thought.Parents {
+		parts[i] = fmt.Sprintf("#%d", id)
+		if parent := ctx.ThoughtByID(id); parent != nil && len(parent.Parents) > 0 {
+			parts[i] = fmt.Sprintf("#%d (%s)", id, lineage(ctx, *parent))
+		}
+	}
+	return strings.Join(parts, " + ")
+}
+
 // displayStatus shows the relevant information about an entity's mind.
-func displayStatus(entity *Entity) {
-	fmt.Printf("\n--- Status for Entity %s ---\n", entity.ID)
-	fmt.Printf("Energy: %d/%d\n", entity.Mind.Energy, entity.Mind.MaxEnergy)
-	fmt.Printf("Current State: %s\n", entity.CurrentFSMState.GetName())
-	fmt.Println("Thoughts:")
+func displayStatus(w io.Writer, entity *Entity) {
+	fmt.Fprintf(w, "\n--- Status for Entity %s ---\n", entity.ID)
+	fmt.Fprintf(w, "Energy: %d/%d\n", entity.Mind.Energy, entity.Mind.MaxEnergy)
+	fmt.Fprintf(w, "Current State: %s\n", entity.CurrentFSMState.GetName())
+	fmt.Fprintf(w, "Thinks from: %s\n", entity.Mind.ThoughtSource.Name())
+	fmt.Fprintf(w, "Mood: %s | Expression threshold %.2f (%.2f before mood)\n", entity.Mind.Affect, entity.Mind.EffectiveThreshold(), entity.Mind.ExpressionThreshold)
+	if len(entity.Mind.Goals) > 0 {
+		fmt.Fprintln(w, "Goals (pursued in order):")
+		for i, g := range entity.Mind.Goals {
+			fmt.Fprintf(w, "  %d. %s (%.0f%%)\n", i+1, g, g.Progress*100)
+		}
+		if plan := planOf(entity); len(plan) > 0 {
+			fmt.Fprintf(w, "Plan: %s\n", formatPlan(plan, 12))
+		}
+	}
+	if tree := treeOf(entity); tree != nil {
+		fmt.Fprintf(w, "Behavior tree (%s; > running, + succeeded, - failed):\n", entity.Policy.Name())
+		tree.Write(w, "  ")
+	}
+	memory := fmt.Sprintf("fading %.3f per tick", entity.Mind.SalienceDecay)
+	if entity.Mind.WorkingMemory > 0 {
+		memory += fmt.Sprintf(", holding at most %d", entity.Mind.WorkingMemory)
+	}
+	fmt.Fprintf(w, "Thoughts (%s):\n", memory)
 	if len(entity.Mind.Thoughts) == 0 {
-		fmt.Println("  (No thoughts yet)")
+		fmt.Fprintln(w, "  (No thoughts yet)")
 	} else {
 		for i, thought := range entity.Mind.Thoughts {
+			marker := " "
 			if i == entity.Mind.CurrentFocusIndex {
-				fmt.Printf("  [%d] * %s (Clarity: %.2f)\n", i, thought, entity.Mind.Clarity)
-			} else {
-				fmt.Printf("  [%d]   %s\n", i, thought)
+				marker = "*"
+			}
+			source := string(thought.Source)
+			if thought.From != "" {
+				source += " from " + thought.From
 			}
+			if len(thought.Parents) > 0 {
+				source += " of " + lineage(entity.Mind, thought)
+			}
+			fmt.Fprintf(w, "  [%d] %s %s (Clarity: %.2f | Salience: %.2f | #%d %s at tick %d | introspected %dx)\n",
+				i, marker, thought.Text, thought.Clarity, thought.Salience, thought.ID, source, thought.CreatedTick, thought.TimesIntrospected)
+		}
+	}
+	if len(entity.Mind.Inbox) > 0 {
+		fmt.Fprintln(w, "Heard (accept/ignore/integrate <i> while Thinking, or listen while Perceiving):")
+		for i, p := range entity.Mind.Inbox {
+			fmt.Fprintf(w, "  (%d) %s: '%s' (Clarity: %.2f, tick %d)\n", i, p.From, p.Text, p.Clarity, p.Tick)
 		}
 	}
+	if len(entity.Mind.Links) > 0 {
+		fmt.Fprintf(w, "Associations: %d (graph shows the focused thought's)\n", len(entity.Mind.Links))
+	}
 	if entity.Mind.CurrentFocusIndex != -1 {
-		fmt.Printf("Focused Thought Index: %d\n", entity.Mind.CurrentFocusIndex)
+		fmt.Fprintf(w, "Focused Thought Index: %d\n", entity.Mind.CurrentFocusIndex)
 	} else {
-		fmt.Println("Focused Thought Index: None")
+		fmt.Fprintln(w, "Focused Thought Index: None")
 	}
-	fmt.Println("------------------------")
+	fmt.Fprintln(w, "------------------------")
 }
 
-var autoPilotEnabled bool = false // Global flag for player's autopilot mode
-
-// selectAIAction encapsulates the decision-making logic for an automated entity.
-// It can be used for both the AI and the player in autopilot mode.
-func selectAIAction(entity *Entity) []string {
-	var commandParts []string
-
-	switch entity.CurrentFSMState.(type) {
-	case *IdleState:
-		if entity.Mind.Energy < 30 && entity.Mind.Energy < entity.Mind.MaxEnergy {
-			commandParts = []string{"recharge"}
-		} else if entity.Mind.Energy > 50 && rand.Intn(2) == 0 { // 50% chance to think
-			commandParts = []string{"think"}
-		} else if rand.Intn(3) == 0 { // Small chance to try reflecting or acting if energy is high
-			if rand.Intn(2) == 0 {
-				commandParts = []string{"reflect"}
-			} else {
-				commandParts = []string{"act"}
+// errQuit is returned by the REPL input handler when the player quits.
+var errQuit = errors.New("quit")
+
+// replInput returns the Simulation.Input handler for the terminal. It keeps
+// prompting until the player enters a command for their entity, handling the
+// global commands (view, graph, autopilot, save, load, quit) along the way.
+// If journal is non-nil, loads are recorded in it.
+func replInput(sim *Simulation, reader *bufio.Reader, journal *Journal) func(e *Entity) ([]string, error) {
+	return func(currentEntity *Entity) ([]string, error) {
+		for {
+			fmt.Printf("\n%s\n", currentEntity.CurrentFSMState.GetPrompt(currentEntity))
+			fmt.Print("> ")
+			input, err := reader.ReadString('\n')
+			if err != nil && input == "" {
+				return nil, errQuit // stdin closed
 			}
-		}
-	case *ThinkingState:
-		if entity.Mind.Energy > 15 && rand.Intn(2) == 0 { // 50% chance to generate
-			commandParts = []string{"generate"}
-		} else if len(entity.Mind.Thoughts) > 0 && entity.Mind.CurrentFocusIndex == -1 && rand.Intn(2) == 0 {
-			focusIndex := rand.Intn(len(entity.Mind.Thoughts))
-			commandParts = []string{"focus", fmt.Sprintf("%d", focusIndex)}
-		} else { // Default to idle or try reflecting if focused
-			if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.Energy > 30 && rand.Intn(2) == 0 {
-				commandParts = []string{"reflect"} // Chance to go reflect if focused and has energy
-			} else {
-				commandParts = []string{"idle"}
+			parts := strings.Fields(strings.TrimSpace(input))
+			if len(parts) == 0 {
+				return nil, nil
 			}
-		}
-	case *ReflectingState:
-		if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.Energy > 20 && entity.Mind.Clarity < 0.9 && rand.Intn(2) == 0 {
-			commandParts = []string{"introspect"}
-		} else if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.Clarity >= entity.Mind.ExpressionThreshold && entity.Mind.Energy > 30 && rand.Intn(2) == 0 {
-			commandParts = []string{"act"} // Chance to go act if clarity is good
-		} else {
-			commandParts = []string{"idle"}
-		}
-	case *ActingState:
-		// Constants for AI evolution decision (mirroring states.go for now)
-		const HighClarityForEvolve = 0.95
-		const EnergyCostEvolve = 50
-		const MinExpressionThresholdForAIDecrease = 0.20 // AI won't try to decrease if already very low
-		const MaxEnergySoftCapForAI = 150                // AI prioritizes evolving MaxEnergy if below this
-
-		// Attempt to Evolve first if conditions are met
-		if entity.Mind.CurrentFocusIndex != -1 &&
-			entity.Mind.Clarity >= HighClarityForEvolve &&
-			entity.Mind.Energy >= EnergyCostEvolve {
-
-			if entity.Mind.MaxEnergy < MaxEnergySoftCapForAI {
-				commandParts = []string{"evolve", "max_energy", "increase"}
-			} else if entity.Mind.ExpressionThreshold > MinExpressionThresholdForAIDecrease {
-				commandParts = []string{"evolve", "threshold", "decrease"}
-			}
-		}
 
-		// If AI didn't choose to evolve, consider expressing or idling
-		if len(commandParts) == 0 {
-			if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.Energy > 25 && entity.Mind.Clarity >= entity.Mind.ExpressionThreshold && rand.Intn(2) == 0 {
-				commandParts = []string{"express"}
-			} else {
-				commandParts = []string{"idle"}
+			switch parts[0] {
+			case "quit":
+				return nil, errQuit
+			case "view":
+				displayStatus(os.Stdout, currentEntity)
+				continue // viewing doesn't change state or end turn
+			case "graph":
+				if len(parts) < 2 {
+					printNeighborhood(os.Stdout, currentEntity)
+					continue
+				}
+				if err := writeDOTFile(parts[1], currentEntity); err != nil {
+					fmt.Printf("Error exporting graph: %v\n", err)
+				} else {
+					fmt.Printf("Thought graph written to %s\n", parts[1])
+				}
+				continue
+			case "autopilot":
+				sim.SetAutopilot(!sim.Autopilot())
+				if sim.Autopilot() {
+					fmt.Println("Player autopilot ENABLED.")
+				} else {
+					fmt.Println("Player autopilot DISABLED.")
+				}
+				return nil, nil
+			case "save":
+				if len(parts) < 2 {
+					fmt.Println("Usage: save <filename.json>")
+					continue
+				}
+				filename := parts[1]
+				if err := saveGame(filename, sim); err != nil {
+					fmt.Printf("Error saving game: %v\n", err)
+				} else {
+					fmt.Printf("Game saved to %s\n", filename)
+					sim.LogEvent(Event{Kind: EventNotice, Entity: currentEntity.ID, Reason: fmt.Sprintf("Game state saved to %s by %s", filename, currentEntity.ID)})
+				}
+				continue
+			case "load":
+				if len(parts) < 2 {
+					fmt.Println("Usage: load <filename.json>")
+					continue
+				}
+				filename := parts[1]
+				loaded, err := loadGame(filename)
+				if err != nil {
+					fmt.Printf("Error loading game: %v\n", err)
+					continue
+				}
+				sim.Restore(loaded)
+				fmt.Printf("Game loaded from %s\n", filename)
+				sim.LogEvent(Event{Kind: EventNotice, Entity: currentEntity.ID, Reason: fmt.Sprintf("Game state loaded from %s by %s", filename, currentEntity.ID)})
+				if journal != nil {
+					if err := journal.Snapshot(sim); err != nil {
+						return nil, fmt.Errorf("journal: %w", err)
+					}
+				}
+				return nil, nil
 			}
+			return parts, nil
 		}
 	}
-	return commandParts
 }
 
-// SerializableEntityState represents an entity's state for serialization.
-type SerializableEntityState struct {
-	ID                  string       `json:"id"`
-	IsPlayer            bool         `json:"is_player"`
-	Mind                *MindContext `json:"mind"` // MindContext is from states.go but used here
-	CurrentFSMStateName string       `json:"current_fsm_state_name"`
+// replOutput shows the turn-by-turn transcript while the player is playing
+// manually and the global dashboard while autopilot is on.
+type replOutput struct {
+	sim       *Simulation
+	text      Output
+	dashboard Output
 }
 
-// SimulationState represents the simulation state for serialization.
-type SimulationState struct {
-	Entities         []SerializableEntityState `json:"entities"`
-	EventLog         []string                  `json:"event_log"`
-	AutoPilotEnabled bool                      `json:"auto_pilot_enabled"`
-	// Potentially add RNG state if deep determinism is needed, for now skipping.
+func (o *replOutput) Turn(turn Turn) {
+	if !o.sim.Autopilot() {
+		o.text.Turn(turn)
+	}
 }
 
-// getStateByName converts a state name string to a State interface instance.
-// This is crucial for restoring FSM states during loading.
-func getStateByName(name string) State {
-	switch name {
-	case "Idle":
-		return &IdleState{}
-	case "Thinking":
-		return &ThinkingState{}
-	case "Reflecting":
-		return &ReflectingState{}
-	case "Acting":
-		return &ActingState{}
-	default:
-		fmt.Printf("Warning: Unknown state name '%s' during load. Defaulting to IdleState.\n", name)
-		return &IdleState{} // Fallback, should ideally not happen with valid save files
+func (o *replOutput) TickDone(sim *Simulation) {
+	if o.sim.Autopilot() {
+		o.dashboard.TickDone(sim)
 	}
 }
 
-// saveGame saves the current simulation state to a file.
-func saveGame(filename string, entities []*Entity, currentEventLog []string, autopilot bool) error {
-	simulationState := SimulationState{
-		Entities:         make([]SerializableEntityState, len(entities)),
-		EventLog:         currentEventLog,
-		AutoPilotEnabled: autopilot,
+// runReplay implements 'qualia replay [--until N] <journal>': it rebuilds a
+// session from its journal, verifying every turn, and prints where it ended.
+func runReplay(args []string) int {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	until := fs.Int("until", 0, "stop after this tick (0 replays the whole journal)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: qualia replay [--until N] <journal.jsonl>")
+		fs.PrintDefaults()
 	}
-
-	for i, entity := range entities {
-		simulationState.Entities[i] = SerializableEntityState{
-			ID:                  entity.ID,
-			IsPlayer:            entity.IsPlayer,
-			Mind:                entity.Mind, // Revert to direct assignment
-			CurrentFSMStateName: entity.CurrentFSMState.GetName(),
-		}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
 	}
 
-	data, err := json.MarshalIndent(simulationState, "", "  ")
+	f, err := os.Open(fs.Arg(0))
 	if err != nil {
-		return err
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
 	}
+	defer f.Close()
 
-	return ioutil.WriteFile(filename, data, 0644)
+	sim, err := replayJournal(f, *until)
+	if sim != nil {
+		fmt.Printf("Replayed %s to tick %d (seed %d).\n", fs.Arg(0), sim.Tick(), sim.Seed())
+		for _, entity := range sim.Entities() {
+			displayStatus(os.Stdout, entity)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Replay failed: %v\n", err)
+		return 1
+	}
+	fmt.Println("Every replayed turn matched the journal.")
+	return 0
 }
 
-// loadGame loads the simulation state from a file.
-// It returns the loaded entities, event log, autopilot status, and any error encountered.
-func loadGame(filename string) ([]*Entity, []string, bool, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, nil, false, err
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(runReplay(os.Args[2:]))
 	}
 
-	var simulationState SimulationState
-	err = json.Unmarshal(data, &simulationState)
-	if err != nil {
-		return nil, nil, false, err
+	seed := flag.Int64("seed", 0, "random seed for a reproducible run (0 picks one at random)")
+	aiPolicies := flag.String("ai-policies", DefaultPolicyName, "comma-separated policy per AI entity ("+strings.Join(policyNames(), ", ")+", or "+treePolicyPrefix+"FILE for a behavior tree)")
+	journalFile := flag.String("journal", "", "append every command to this JSONL journal, for 'qualia replay'")
+	eventsFile := flag.String("events", "", "append every event to this JSONL file")
+	transitionsFile := flag.String("transitions", "", "load the FSM transition table from this JSON file instead of the built-in one")
+	configFile := flag.String("config", "", "load tuning parameters from this JSON file")
+	perceiveFile := flag.String("perceive", "", "feed lines of this file or named pipe, or of stdin if -, to entities observing in the Perceiving state")
+	var overrides configOverrides
+	var corpora corpusFlags
+	var markovs markovFlags
+	var llms llmFlags
+	var decays, spans, goals mindFlags
+	flag.Var(&decays, "decay", "salience an unfocused thought loses per tick, e.g. --decay AI-Alpha=0.02; without ENTITY= for every entity (repeatable)")
+	flag.Var(&spans, "working-memory", "most thoughts a mind holds before forgetting the least salient, e.g. --working-memory Player-1=7; 0 for no limit (repeatable)")
+	flag.Var(&goals, "goal", "give an entity a goal, as express:N, max_energy:N, threshold:X or understand:INDEX[:CLARITY], e.g. --goal AI-Alpha=express:3; without ENTITY= for every entity (repeatable)")
+	flag.Var(&llms, "llm", "generate and rewrite thoughts with an OpenAI-compatible chat-completions endpoint, e.g. http://localhost:8080/v1; prefix with ENTITY= for one entity only (repeatable)")
+	llmModel := flag.String("llm-model", "local", "model name sent to --llm endpoints")
+	flag.Var(&markovs, "markov", "generate new sentences from a markov model trained on this corpus file (same formats and ENTITY= prefix as --corpus; repeatable)")
+	flag.Var(&corpora, "corpus", "generate thoughts from sentences in this text, .jsonl or ChatGPT export .json file; prefix with ENTITY= for one entity only (repeatable)")
+	flag.Var(&overrides, "set", "override one tuning parameter, e.g. --set generate_cost=12 (repeatable)")
+	flag.Parse()
+	if *seed == 0 {
+		*seed = randomSeed()
 	}
 
-	entities := make([]*Entity, len(simulationState.Entities))
-	for i, entityState := range simulationState.Entities {
-		entities[i] = &Entity{
-			ID:              entityState.ID,
-			IsPlayer:        entityState.IsPlayer,
-			Mind:            entityState.Mind, // Revert to direct assignment
-			CurrentFSMState: getStateByName(entityState.CurrentFSMStateName),
+	cfg := DefaultConfig()
+	if *configFile != "" {
+		var err error
+		if cfg, err = LoadConfig(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
 		}
 	}
-
-	return entities, simulationState.EventLog, simulationState.AutoPilotEnabled, nil
-}
-
-func main() {
-	rand.Seed(time.Now().UnixNano()) // Initialize random seed
-
-	player := &Entity{
-		ID:              "Player-1",
-		IsPlayer:        true,
-		Mind:            NewMindContext(),
-		CurrentFSMState: &IdleState{}, // Start in Idle state
+	if err := overrides.apply(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
 	}
 
-	aiEntity := &Entity{
-		ID:              "AI-Alpha",
-		IsPlayer:        false,
-		Mind:            NewMindContext(),
-		CurrentFSMState: &IdleState{}, // AI also starts in Idle state
+	rules := DefaultTransitions()
+	if *transitionsFile != "" {
+		var err error
+		if rules, err = LoadTransitions(*transitionsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
 	}
 
-	entities := []*Entity{player, aiEntity}
+	entities, err := NewEntities(cfg, rules, strings.Split(*aiPolicies, ","))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := corpora.apply(entities); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := markovs.apply(entities, cfg.MarkovOrder); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := llms.apply(entities, *llmModel); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := decays.apply("decay", entities, setSalienceDecay); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := spans.apply("working-memory", entities, setWorkingMemory); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := goals.apply("goal", entities, setGoal); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	sim := NewSimulation(*seed, entities)
+	sim.SetConfig(cfg)
+	sim.SetTransitions(rules)
+	if *perceiveFile != "" {
+		stream, err := OpenTextStream(*perceiveFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		defer stream.Close()
+		sim.SetEnvironment(NewEnvironment(stream))
+	}
+	sim.Output = &replOutput{sim: sim, text: &TextOutput{W: os.Stdout}, dashboard: &DashboardOutput{W: os.Stdout}}
+
+	var journal *Journal
+	if *journalFile != "" {
+		f, err := os.OpenFile(*journalFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if journal, err = NewJournal(f, sim); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing journal: %v\n", err)
+			os.Exit(1)
+		}
+		sim.Output = MultiOutput{sim.Output, journal}
+	}
+	if *eventsFile != "" {
+		f, err := os.OpenFile(*eventsFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		events := NewEventWriter(f)
+		subscription := sim.Bus.SubscribeAsync(0, events.Handle)
+		defer func() { // Runs before f.Close, flushing the queue
+			subscription.Close()
+			if events.Err() != nil {
+				fmt.Printf("Events error: %v\n", events.Err())
+			}
+		}()
+	}
+	commands := os.Stdin
+	if *perceiveFile == "-" { // Stdin carries the stream, so commands come from the terminal
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --perceive -: no terminal to read commands from: %v\n", err)
+			os.Exit(2)
+		}
+		defer tty.Close()
+		commands = tty
+	}
+	reader := bufio.NewReader(commands)
+	sim.Input = replInput(sim, reader, journal)
 
-	reader := bufio.NewReader(os.Stdin)
 	fmt.Println("Mind Simulation MVP - Endless Mode with Entities")
+	fmt.Printf("Seed: %d (rerun with --seed %d to reproduce)\n", *seed, *seed)
 	fmt.Println("Type 'quit' to exit.")
 	fmt.Println("Type 'autopilot' to toggle player's automatic mode.")
 	fmt.Println("Type 'save <filename.json>' to save the game.")
 	fmt.Println("Type 'load <filename.json>' to load the game.")
 
 	for {
-		for _, currentEntity := range entities {
-			// Passive energy regeneration for all entities
-			if currentEntity.Mind.Energy < currentEntity.Mind.MaxEnergy {
-				currentEntity.Mind.Energy++
-			}
-
-			if currentEntity.IsPlayer {
-				var parts []string
-				var command string
-
-				if autoPilotEnabled {
-					fmt.Printf("\n--- Player %s's turn (AUTOPILOT ACTIVE) (%s) ---\n", currentEntity.ID, currentEntity.CurrentFSMState.GetName())
-					parts = selectAIAction(currentEntity)
-					if len(parts) > 0 {
-						command = parts[0]
-						msg := fmt.Sprintf("Player %s (autopilot) attempts: %s", currentEntity.ID, strings.Join(parts, " "))
-						fmt.Println(msg) // Still print attempt for clarity even in autopilot before dashboard refresh
-						// addEventToLog(msg) // Event added by HandleInput wrapper later
-					} else {
-						fmt.Printf("Player %s (autopilot) decides to do nothing this turn.\n", currentEntity.ID)
-						// No state change or input to handle, so continue entity loop
-						// but need to decide if we skip the dashboard update for this entity's no-action.
-						// For now, we'll let the main loop handle dashboard update.
-						continue
-					}
-				} else { // Manual player input
-					fmt.Printf("\n%s\n", currentEntity.CurrentFSMState.GetPrompt(currentEntity))
-					fmt.Print("> ")
-					input, _ := reader.ReadString('\n')
-					input = strings.TrimSpace(input)
-					parts = strings.Fields(input)
-
-					if len(parts) == 0 {
-						continue
-					}
-					command = parts[0]
-				}
-
-				if command == "quit" {
-					fmt.Println("Exiting simulation.")
-					return
-				}
-				if command == "view" {
-					displayStatus(currentEntity)
-					continue // viewing doesn't change state or end turn
-				}
-				if command == "autopilot" {
-					autoPilotEnabled = !autoPilotEnabled
-					if autoPilotEnabled {
-						fmt.Println("Player autopilot ENABLED.")
-					} else {
-						fmt.Println("Player autopilot DISABLED.")
-					}
-					continue
-				}
-
-				if command == "save" {
-					if len(parts) < 2 {
-						fmt.Println("Usage: save <filename.json>")
-						continue
-					}
-					filename := parts[1]
-					if err := saveGame(filename, entities, eventLog, autoPilotEnabled); err != nil {
-						fmt.Printf("Error saving game: %v\n", err)
-					} else {
-						fmt.Printf("Game saved to %s\n", filename)
-						addEventToLog(fmt.Sprintf("Game state saved to %s by %s", filename, currentEntity.ID))
-					}
-					continue
-				}
-
-				if command == "load" {
-					if len(parts) < 2 {
-						fmt.Println("Usage: load <filename.json>")
-						continue
-					}
-					filename := parts[1]
-					loadedEntities, loadedEventLog, loadedAutopilot, err := loadGame(filename)
-					if err != nil {
-						fmt.Printf("Error loading game: %v\n", err)
-					} else {
-						entities = loadedEntities
-						// Need to re-assign player and aiEntity pointers if they are used directly elsewhere
-						// For now, assuming entities slice is the source of truth.
-						for _, e := range entities {
-							if e.IsPlayer {
-								player = e // Update main player reference
-								break
-							}
-						}
-						// Similarly update aiEntity if needed, or just iterate entities directly.
-
-						eventLog = loadedEventLog
-						autoPilotEnabled = loadedAutopilot
-						fmt.Printf("Game loaded from %s\n", filename)
-						addEventToLog(fmt.Sprintf("Game state loaded from %s by %s", filename, currentEntity.ID)) // activeEntity might be stale here if player changed
-						// Force a dashboard render or prompt after load
-						if autoPilotEnabled {
-							renderGlobalDashboard(entities)
-						} else {
-							fmt.Print(player.CurrentFSMState.GetPrompt(player) + " > ")
-						}
-					}
-					continue
-				}
-
-				newState, returnedEvents := currentEntity.CurrentFSMState.HandleInput(currentEntity.ID, currentEntity.Mind, parts)
-				currentEntity.CurrentFSMState = newState
-
-				for _, event := range returnedEvents {
-					addEventToLog(event)
-				}
-
-				if !autoPilotEnabled { // Only show individual status if player is manual
-					displayStatus(currentEntity)
-				}
-			} else { // AI Entity Logic
-				if !autoPilotEnabled { // If player is manual, show AI turn details for context
-					fmt.Printf("\n--- AI Entity %s's turn (%s) ---\n", currentEntity.ID, currentEntity.CurrentFSMState.GetName())
-				}
-				var aiCommandParts []string
-				aiCommandParts = selectAIAction(currentEntity)
-
-				if len(aiCommandParts) > 0 {
-					msg := fmt.Sprintf("AI %s attempts: %s", currentEntity.ID, strings.Join(aiCommandParts, " "))
-					if !autoPilotEnabled {
-						fmt.Println(msg)
-					}
-					// addEventToLog(msg) // Event added by HandleInput wrapper later
-					newState, returnedEvents := currentEntity.CurrentFSMState.HandleInput(currentEntity.ID, currentEntity.Mind, aiCommandParts)
-					currentEntity.CurrentFSMState = newState
-
-					for _, event := range returnedEvents {
-						addEventToLog(event)
-					}
-
-					if !autoPilotEnabled { // Only show AI status if player is manual
-						displayStatus(currentEntity)
-					}
-				} else {
-					msg := fmt.Sprintf("AI %s decides to do nothing this turn.", currentEntity.ID)
-					if !autoPilotEnabled {
-						fmt.Println(msg)
-					}
-					addEventToLog(msg) // Log this specific inaction
-				}
+		if err := sim.Step(); err != nil {
+			if errors.Is(err, errQuit) {
+				fmt.Println("Exiting simulation.")
+				return
 			}
-
-			// Pause logic remains, but dashboard update is outside this inner entity loop if autopilot is on.
-			// The sleep should happen *before* the dashboard re-render for that cycle.
-
-		} // End of for _, currentEntity := range entities
-
-		// After all entities have had their turn in a cycle:
-		if autoPilotEnabled {
-			renderGlobalDashboard(entities)
-			time.Sleep(1 * time.Second) // Pause for dashboard readability
-		} else {
-			// If player is manual, a smaller pause between player's full turn cycles might be good too, or rely on individual turn sleeps.
-			// For now, let's assume the existing per-entity sleep is sufficient when player is manual.
-			// The player's prompt provides a natural pause.
+			fmt.Printf("Simulation error: %v\n", err)
+			return
+		}
+		if journal != nil && journal.Err() != nil {
+			fmt.Printf("Journal error: %v\n", journal.Err())
+			return
 		}
 
+		// The dashboard has been redrawn by the output layer; pause for readability.
+		if sim.Autopilot() {
+			time.Sleep(time.Duration(sim.Config().AutopilotDelayMS) * time.Millisecond)
+		}
 	}
 }
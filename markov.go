@@ -0,0 +1,142 @@
+// markov.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// MarkovModel is a word-level n-gram model trained on a corpus. It walks the
+// chain from a sentence start, so 'generate' produces new sentences spliced
+// from the corpus rather than copies of it.
+type MarkovModel struct {
+	Label string                  `json:"name"`
+	Order int                     `json:"order"` // Words of context per step
+	Chain map[string][]markovNext `json:"chain"` // Last Order words, space-joined -> the words seen next
+}
+
+// markovNext is a word that followed a state in training, and how often.
+type markovNext struct {
+	Word  string `json:"w"` // Empty for the end of a sentence
+	Count int    `json:"n"`
+}
+
+// TrainMarkov builds an n-gram model of the given order from the corpus.
+func TrainMarkov(corpus *Corpus, order int) *MarkovModel {
+	counts := make(map[string]map[string]int)
+	for _, phrase := range corpus.Phrases {
+		state := make([]string, order) // Sentences start from empty context
+		for _, word := range append(strings.Fields(phrase), "") {
+			key := strings.Join(state, " ")
+			if counts[key] == nil {
+				counts[key] = make(map[string]int)
+			}
+			counts[key][word]++
+			state = append(state[1:], word)
+		}
+	}
+
+	model := &MarkovModel{Label: corpus.Label, Order: order, Chain: make(map[string][]markovNext, len(counts))}
+	for key, next := range counts {
+		words := make([]markovNext, 0, len(next))
+		for word, n := range next {
+			words = append(words, markovNext{word, n})
+		}
+		// Fixed order, so the same RNG draws always pick the same word.
+		sort.Slice(words, func(i, j int) bool { return words[i].Word < words[j].Word })
+		model.Chain[key] = words
+	}
+	return model
+}
+
+func (m *MarkovModel) Kind() string { return "markov" }
+func (m *MarkovModel) Name() string { return fmt.Sprintf("%s (order-%d markov)", m.Label, m.Order) }
+
+// Generate walks the chain until it reaches a sentence end or the mind's
+// markov_max_words, choosing each word under markov_temperature.
+func (m *MarkovModel) Generate(ctx *MindContext) string {
+	state := make([]string, m.Order)
+	var words []string
+	for len(words) < ctx.Config.MarkovMaxWords {
+		word := pickNext(m.Chain[strings.Join(state, " ")], ctx.Config.MarkovTemperature, ctx.Rand)
+		if word == "" {
+			break
+		}
+		words = append(words, word)
+		state = append(state[1:], word)
+	}
+	return strings.Join(words, " ")
+}
+
+// pickNext samples a next word with probability proportional to
+// count^(1/temperature): 1 follows the corpus, lower is more predictable and
+// higher more surprising. A temperature of 0 always takes the most common.
+func pickNext(candidates []markovNext, temperature float64, rng *RNG) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	if temperature <= 0 {
+		best := candidates[0]
+		for _, c := range candidates[1:] {
+			if c.Count > best.Count {
+				best = c
+			}
+		}
+		return best.Word
+	}
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, c := range candidates {
+		weights[i] = math.Pow(float64(c.Count), 1/temperature)
+		total += weights[i]
+	}
+	r := rng.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return candidates[i].Word
+		}
+		r -= w
+	}
+	return candidates[len(candidates)-1].Word
+}
+
+func (m *MarkovModel) validate() error {
+	if m.Order < 1 {
+		return fmt.Errorf("markov order %d must be at least 1", m.Order)
+	}
+	if len(m.Chain[strings.Repeat(" ", m.Order-1)]) == 0 {
+		return errors.New("markov model has no sentence starts")
+	}
+	return nil
+}
+
+// markovFlags collects repeated --markov [entity=]file flags.
+type markovFlags []string
+
+func (f *markovFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *markovFlags) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// apply trains a model of the given order on each corpus and gives it to the
+// named entity, or to every entity if no name is given.
+func (f markovFlags) apply(entities []*Entity, order int) error {
+	for _, spec := range f {
+		err := assignThoughtSource(entities, spec, func(filename string) (ThoughtSource, error) {
+			corpus, err := LoadCorpus(filename)
+			if err != nil {
+				return nil, err
+			}
+			return TrainMarkov(corpus, order), nil
+		})
+		if err != nil {
+			return fmt.Errorf("--markov %s: %w", spec, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// markov_test.go
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var markovCorpus = &Corpus{Label: "test", Phrases: []string{
+	"the mind shapes the body",
+	"the body shapes the world",
+	"the world shapes the mind",
+}}
+
+func TestTrainMarkov_CountsTransitions(t *testing.T) {
+	model := TrainMarkov(markovCorpus, 1)
+	starts := model.Chain[""]
+	if len(starts) != 1 || starts[0] != (markovNext{"the", 3}) {
+		t.Errorf("TrainMarkov: Expected every sentence to start with 'the', got %+v", starts)
+	}
+	if next := model.Chain["the"]; len(next) != 3 || next[0].Word != "body" || next[0].Count != 2 {
+		t.Errorf("TrainMarkov: Expected sorted successors of 'the', got %+v", next)
+	}
+	if next := model.Chain["mind"]; len(next) != 2 || next[0] != (markovNext{"", 1}) {
+		t.Errorf("TrainMarkov: Expected 'mind' to end a sentence once, got %+v", next)
+	}
+}
+
+func TestMarkovModel_Generate(t *testing.T) {
+	model := TrainMarkov(markovCorpus, 1)
+	ctx := NewMindContext(DefaultConfig())
+
+	ctx.Rand = NewRNG(4)
+	first := model.Generate(ctx)
+	ctx.Rand = NewRNG(4)
+	if again := model.Generate(ctx); again != first || !strings.HasPrefix(first, "the ") {
+		t.Errorf("Generate: Expected the same seed to give the same sentence, got %q and %q", first, again)
+	}
+
+	ctx.Config = DefaultConfig()
+	ctx.Config.MarkovMaxWords = 3
+	for i := 0; i < 20; i++ {
+		if words := len(strings.Fields(model.Generate(ctx))); words > 3 {
+			t.Fatalf("Generate: Expected at most 3 words, got %d", words)
+		}
+	}
+
+	ctx.Config.MarkovTemperature = 0
+	if got := model.Generate(ctx); got != "the body" {
+		t.Errorf("Generate: Expected the most common path at temperature 0, got %q", got)
+	}
+}
+
+func TestMarkovFlags_TrainsWithConfiguredOrder(t *testing.T) {
+	filename := writeCorpus(t, "notes.txt", "The body shapes the world we see. The world shapes the mind we have.")
+	entities := NewDefaultEntities()
+	if err := (markovFlags{filename}).apply(entities, 3); err != nil {
+		t.Fatalf("markovFlags: %v", err)
+	}
+	model, ok := entities[1].Mind.ThoughtSource.(*MarkovModel)
+	if !ok || model.Order != 3 || entities[0].Mind.ThoughtSource != model {
+		t.Fatalf("markovFlags: Expected an order-3 model for every entity, got %v", entities[1].Mind.ThoughtSource)
+	}
+	if err := (markovFlags{"missing.txt"}).apply(entities, 2); err == nil {
+		t.Errorf("markovFlags: Expected an error for a missing corpus")
+	}
+}
+
+func TestSaveGame_KeepsMarkovModel(t *testing.T) {
+	entities := NewDefaultEntities()
+	entities[1].Mind.ThoughtSource = TrainMarkov(markovCorpus, 2)
+	original := NewSimulation(5, entities)
+	original.SetAutopilot(true)
+	original.Run(context.Background(), 30)
+
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, original); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	if model, ok := loaded.Entities()[1].Mind.ThoughtSource.(*MarkovModel); !ok || model.Order != 2 {
+		t.Fatalf("loadGame: Expected the markov model restored, got %v", loaded.Entities()[1].Mind.ThoughtSource)
+	}
+	original.Run(context.Background(), 150)
+	loaded.Run(context.Background(), 150)
+	if mindsJSON(t, original) != mindsJSON(t, loaded) {
+		t.Errorf("Loaded game with a markov model diverged from the original")
+	}
+}
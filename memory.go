@@ -0,0 +1,115 @@
+// memory.go
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Thoughts fade from memory unless they are attended to. Every tick each
+// unfocused thought loses its mind's SalienceDecay; focusing on a thought or
+// introspecting on it restores full salience. A thought whose salience falls
+// below forget_salience is forgotten, and a mind with a WorkingMemory span
+// forgets its least salient thoughts whenever it holds more than that.
+
+// refresh restores the thought's salience after it has been attended to.
+func (t *Thought) refresh() { t.Salience = 1 }
+
+// decay runs one tick of forgetting over the mind's unfocused thoughts and
+// returns a ThoughtForgotten event for each one lost.
+func (ctx *MindContext) decay(entityID string) []Event {
+	var events []Event
+	for i := len(ctx.Thoughts) - 1; i >= 0; i-- {
+		if i == ctx.CurrentFocusIndex {
+			continue
+		}
+		t := &ctx.Thoughts[i]
+		t.Salience = max(t.Salience-ctx.SalienceDecay, 0)
+		if t.Salience < ctx.Config.ForgetSalience {
+			events = append(events, forgotten(entityID, *t, ""))
+			ctx.removeThought(i)
+		}
+	}
+	return events
+}
+
+// evict forgets the least salient unfocused thoughts, oldest first among
+// equals, until the mind is within its working memory span.
+func (ctx *MindContext) evict(entityID string) []Event {
+	var events []Event
+	for ctx.WorkingMemory > 0 && len(ctx.Thoughts) > ctx.WorkingMemory {
+		least := -1
+		for i, t := range ctx.Thoughts {
+			if i != ctx.CurrentFocusIndex && (least < 0 || t.Salience < ctx.Thoughts[least].Salience) {
+				least = i
+			}
+		}
+		if least < 0 {
+			break // Only the focused thought is left
+		}
+		events = append(events, forgotten(entityID, ctx.Thoughts[least], fmt.Sprintf("working memory holds %d", ctx.WorkingMemory)))
+		ctx.removeThought(least)
+	}
+	return events
+}
+
+// forgotten is the event for a thought leaving memory, with the reason if it
+// was pushed out rather than faded.
+func forgotten(entityID string, t Thought, reason string) Event {
+	return Event{Kind: EventThoughtForgotten, Entity: entityID, ThoughtID: t.ID, Thought: t.Text, Clarity: t.Clarity, NewValue: t.Salience, Reason: reason}
+}
+
+// mindFlags collects repeated [entity=]value flags that tune individual minds.
+type mindFlags []string
+
+func (f *mindFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *mindFlags) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// apply sets each value on the named entity's mind, or on every mind if no
+// name is given. Later flags win.
+func (f mindFlags) apply(flag string, entities []*Entity, set func(ctx *MindContext, value string) error) error {
+	for _, spec := range f {
+		id, value, ok := strings.Cut(spec, "=")
+		if !ok {
+			id, value = "", spec
+		}
+		found := false
+		for _, e := range entities {
+			if id == "" || e.ID == id {
+				if err := set(e.Mind, value); err != nil {
+					return fmt.Errorf("--%s %s: %w", flag, spec, err)
+				}
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("--%s %s: no entity %q", flag, spec, id)
+		}
+	}
+	return nil
+}
+
+// setSalienceDecay is the --decay setter.
+func setSalienceDecay(ctx *MindContext, value string) error {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return fmt.Errorf("%q is not a decay rate between 0 and 1", value)
+	}
+	ctx.SalienceDecay = rate
+	return nil
+}
+
+// setWorkingMemory is the --working-memory setter.
+func setWorkingMemory(ctx *MindContext, value string) error {
+	span, err := strconv.Atoi(value)
+	if err != nil || span < 0 {
+		return fmt.Errorf("%q is not a number of thoughts (0 for no limit)", value)
+	}
+	ctx.WorkingMemory = span
+	return nil
+}
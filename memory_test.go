@@ -0,0 +1,127 @@
+// memory_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMemory_UnfocusedThoughtsFadeAndAreForgotten(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.SalienceDecay = 0.3
+	ctx.AddThought("kept in focus", OriginGenerated)
+	ctx.AddThought("left alone", OriginGenerated)
+	ctx.AddThought("looked at again", OriginGenerated)
+	ctx.CurrentFocusIndex = 0
+
+	ctx.decay("e")
+	ctx.decay("e")
+	if s := ctx.Thoughts[1].Salience; math.Abs(s-0.4) > 1e-9 || ctx.Thoughts[0].Salience != 1 {
+		t.Errorf("decay: Expected unfocused salience 0.40 and focused 1, got %+v", ctx.Thoughts)
+	}
+	(&ThinkingState{}).HandleInput("e", ctx, strings.Fields("focus 2"))
+	if ctx.Thoughts[2].Salience != 1 {
+		t.Errorf("focus: Expected salience refreshed to 1, got %.2f", ctx.Thoughts[2].Salience)
+	}
+
+	var events []Event
+	for range 4 {
+		events = append(events, ctx.decay("e")...)
+	}
+	if len(events) != 2 || events[0].Kind != EventThoughtForgotten || events[0].Thought != "left alone" || events[1].Thought != "kept in focus" {
+		t.Fatalf("decay: Expected both faded thoughts forgotten, got %+v", events)
+	}
+	if len(ctx.Thoughts) != 1 || ctx.CurrentFocusIndex != 0 || ctx.Focused().Text != "looked at again" {
+		t.Errorf("decay: Expected only the focused thought left, got %+v (focus %d)", ctx.Thoughts, ctx.CurrentFocusIndex)
+	}
+}
+
+func TestMemory_IntrospectionRefreshesSalience(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("a thought", OriginGenerated).Salience = 0.3
+	ctx.CurrentFocusIndex = 0
+	(&ReflectingState{}).HandleInput("e", ctx, strings.Fields("introspect"))
+	if ctx.Thoughts[0].Salience != 1 {
+		t.Errorf("introspect: Expected salience refreshed to 1, got %.2f", ctx.Thoughts[0].Salience)
+	}
+}
+
+func TestMemory_WorkingMemoryEvictsLeastSalient(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.WorkingMemory = 2
+	ctx.AddThought("old", OriginGenerated)
+	ctx.AddThought("faint", OriginGenerated).Salience = 0.2
+	ctx.AddThought("fainter but focused", OriginGenerated).Salience = 0.1
+	ctx.AddThought("new", OriginGenerated)
+	ctx.CurrentFocusIndex = 2
+
+	events := ctx.evict("e")
+	if len(events) != 2 || events[0].Thought != "faint" || events[1].Thought != "old" || !strings.Contains(events[0].String(), "working memory holds 2") {
+		t.Errorf("evict: Expected the least salient unfocused thoughts forgotten, oldest first among equals, got %+v", events)
+	}
+	if len(ctx.Thoughts) != 2 || ctx.Focused().Text != "fainter but focused" {
+		t.Errorf("evict: Expected the focused thought and the new one kept, got %+v", ctx.Thoughts)
+	}
+}
+
+func TestMemory_StepForgetsAndApplyEnforcesSpan(t *testing.T) {
+	entities := NewDefaultEntities()
+	ai := entities[1]
+	ai.Mind.WorkingMemory = 1
+	ai.Mind.AddThought("about to fade", OriginGenerated).Salience = 0.042
+	ai.Policy = &scriptedPolicy{commands: []Command{NewCommand("think"), NewCommand("generate"), NewCommand("generate")}}
+	sim := NewSimulation(1, entities)
+	var out bytes.Buffer
+	sim.Output = &TextOutput{W: &out}
+
+	sim.Run(context.Background(), 3)
+	if !strings.Contains(out.String(), "AI-Alpha forgot 'about to fade' (salience 0.04)") {
+		t.Errorf("Step: Expected the faded thought forgotten in the transcript, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "to make room (working memory holds 1)") || len(ai.Mind.Thoughts) != 1 || ai.Mind.Thoughts[0].ID != 3 {
+		t.Errorf("Apply: Expected the older generated thought evicted, got %+v", ai.Mind.Thoughts)
+	}
+}
+
+func TestMemory_ReplayForgetsIdentically(t *testing.T) {
+	sim := NewSimulation(5, NewDefaultEntities())
+	for _, e := range sim.Entities() {
+		e.Mind.SalienceDecay = 0.04
+		e.Mind.WorkingMemory = 3
+	}
+	sim.SetAutopilot(true)
+	var buf bytes.Buffer
+	journal, err := NewJournal(&buf, sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Output = journal
+	sim.Run(context.Background(), 150)
+
+	replayed, err := replayJournal(&buf, 0)
+	if err != nil {
+		t.Fatalf("replayJournal: %v", err)
+	}
+	if mindsJSON(t, sim) != mindsJSON(t, replayed) {
+		t.Errorf("Replay with forgetting does not match original")
+	}
+}
+
+func TestMindFlags_SetPerEntity(t *testing.T) {
+	entities := NewDefaultEntities()
+	flags := mindFlags{"0.1", "AI-Alpha=0.02"}
+	if err := flags.apply("decay", entities, setSalienceDecay); err != nil {
+		t.Fatal(err)
+	}
+	if entities[0].Mind.SalienceDecay != 0.1 || entities[1].Mind.SalienceDecay != 0.02 {
+		t.Errorf("--decay: Expected 0.1 and 0.02, got %v and %v", entities[0].Mind.SalienceDecay, entities[1].Mind.SalienceDecay)
+	}
+	for _, bad := range []mindFlags{{"Nobody=3"}, {"Player-1=-1"}, {"x"}} {
+		if err := bad.apply("working-memory", entities, setWorkingMemory); err == nil {
+			t.Errorf("--working-memory %v: Expected an error", bad)
+		}
+	}
+}
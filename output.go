@@ -0,0 +1,86 @@
+// output.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Turn describes one entity's turn within a tick.
+type Turn struct {
+	Tick   int
+	Entity *Entity
+	From   State    // State the entity was in before the command
+	Parts  []string // Command attempted; empty if the entity did nothing
+	Events []Event
+}
+
+// Output is the presentation layer the simulation reports to. State handlers
+// never print; the REPL, the dashboard and tests each plug in their own Output.
+type Output interface {
+	// Turn is called after each entity's turn.
+	Turn(turn Turn)
+	// TickDone is called once every entity has had its turn in a tick.
+	TickDone(sim *Simulation)
+}
+
+// DiscardOutput ignores everything. It is the default for a Simulation.
+type DiscardOutput struct{}
+
+func (DiscardOutput) Turn(turn Turn)           {}
+func (DiscardOutput) TickDone(sim *Simulation) {}
+
+// TextOutput writes a turn-by-turn transcript, as shown when the player is
+// playing manually.
+type TextOutput struct {
+	W io.Writer
+}
+
+func (o *TextOutput) Turn(turn Turn) {
+	entity := turn.Entity
+	if !entity.IsPlayer {
+		fmt.Fprintf(o.W, "\n--- AI Entity %s's turn (%s) ---\n", entity.ID, turn.From.GetName())
+		if len(turn.Parts) == 0 {
+			fmt.Fprintln(o.W, Event{Kind: EventNoAction, Entity: entity.ID})
+			return
+		}
+		fmt.Fprintf(o.W, "AI %s attempts: %s\n", entity.ID, strings.Join(turn.Parts, " "))
+	}
+	if len(turn.Parts) == 0 {
+		return
+	}
+	for _, event := range turn.Events {
+		fmt.Fprintln(o.W, event)
+	}
+	displayStatus(o.W, entity)
+}
+
+func (o *TextOutput) TickDone(sim *Simulation) {}
+
+// DashboardOutput redraws the global dashboard once per tick.
+type DashboardOutput struct {
+	W io.Writer
+}
+
+func (o *DashboardOutput) Turn(turn Turn) {}
+
+func (o *DashboardOutput) TickDone(sim *Simulation) {
+	renderGlobalDashboard(o.W, sim)
+}
+
+// MultiOutput fans every call out to several outputs, e.g. the terminal and a
+// journal.
+type MultiOutput []Output
+
+func (m MultiOutput) Turn(turn Turn) {
+	for _, o := range m {
+		o.Turn(turn)
+	}
+}
+
+func (m MultiOutput) TickDone(sim *Simulation) {
+	for _, o := range m {
+		o.TickDone(sim)
+	}
+}
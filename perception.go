@@ -0,0 +1,75 @@
+// perception.go
+package main
+
+import "strings"
+
+// MaxInboxSize is how many unprocessed perceptions a mind holds on to; older
+// ones are forgotten when new ones arrive.
+const MaxInboxSize = 5
+
+// Expression is a thought an entity has put out into the shared world.
+// ActingState queues it in the mind's Outbox and the Simulation delivers it.
+type Expression struct {
+	Text    string
+	Clarity float64
+	Qualia  Qualia
+}
+
+// Perception is an expression as it reached another entity. Externalization is
+// a lossy process: the less clear the expression, the more of it is lost.
+type Perception struct {
+	From    string  `json:"from"`
+	Text    string  `json:"text"`
+	Clarity float64 `json:"clarity"`
+	Qualia  Qualia  `json:"qualia"` // As conveyed, with noise
+	Tick    int     `json:"tick"`
+}
+
+// perceive degrades an expression as heard by someone else. Every word
+// survives with probability equal to the expression's fidelity (see
+// Qualia.Fidelity), the listener is only as clear about it as the expresser
+// was about the part that survived, and what it felt like arrives blurred.
+func perceive(from string, expr Expression, tick int, cfg *Config, rng *RNG) Perception {
+	fidelity := expr.Qualia.Fidelity(expr.Clarity)
+	words := strings.Fields(expr.Text)
+	kept := 0
+	for i := range words {
+		if rng.Float64() < fidelity {
+			kept++
+		} else {
+			words[i] = "..."
+		}
+	}
+	clarity := 0.0
+	if len(words) > 0 {
+		clarity = expr.Clarity * float64(kept) / float64(len(words))
+	}
+	qualia := expr.Qualia.received(fidelity, cfg, rng)
+	return Perception{From: from, Text: strings.Join(words, " "), Clarity: clarity, Qualia: qualia, Tick: tick}
+}
+
+// receive adds a perception to the mind's inbox, dropping the oldest if full.
+func (ctx *MindContext) receive(p Perception) {
+	ctx.Inbox = append(ctx.Inbox, p)
+	if len(ctx.Inbox) > MaxInboxSize {
+		ctx.Inbox = ctx.Inbox[len(ctx.Inbox)-MaxInboxSize:]
+	}
+}
+
+// broadcast delivers everything the entity expressed this turn to every other
+// entity and returns the resulting events.
+func (s *Simulation) broadcast(from *Entity) []Event {
+	var events []Event
+	for _, expr := range from.Mind.Outbox {
+		for _, to := range s.entities {
+			if to == from {
+				continue
+			}
+			p := perceive(from.ID, expr, s.tick, s.config, s.rng)
+			to.Mind.receive(p)
+			events = append(events, Event{Kind: EventHeard, Entity: to.ID, From: from.ID, Thought: p.Text, Clarity: p.Clarity})
+		}
+	}
+	from.Mind.Outbox = nil
+	return events
+}
@@ -0,0 +1,237 @@
+// planner.go
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Planning is implemented by policies that work to a plan, so it can be shown
+// alongside what they do.
+type Planning interface {
+	Plan(view EntityView) []Command
+}
+
+// PlannerPolicy pursues the entity's first goal. It works out a plan afresh
+// every tick, so it adjusts when a command fails or the mind changes under
+// it, and carries out the first step. Without a goal, or without a way to
+// it, it falls back on the random heuristics.
+type PlannerPolicy struct {
+	fallback RandomHeuristicPolicy
+}
+
+func (p *PlannerPolicy) Name() string { return "planner" }
+
+func (p *PlannerPolicy) Decide(view EntityView, rng *RNG) Command {
+	if plan := p.Plan(view); len(plan) > 0 {
+		return plan[0]
+	}
+	return p.fallback.Decide(view, rng)
+}
+
+// Plan returns the commands the entity expects to take to its first goal, or
+// nil if it has none.
+func (p *PlannerPolicy) Plan(view EntityView) []Command {
+	if len(view.Goals) == 0 || view.Rules == nil {
+		return nil
+	}
+	return planGoal(view, view.Goals[0])
+}
+
+// maxPlanSteps bounds how far ahead a plan looks, e.g. when resting barely
+// restores energy.
+const maxPlanSteps = 40
+
+// plan builds a command sequence while tracking the state and energy the
+// entity should be in after each command. The rules say what moving between
+// states costs, so plans follow custom transition tables too; ok is cleared
+// if the table has no way to do something.
+type plan struct {
+	view   EntityView
+	state  string
+	energy int
+	steps  []Command
+	ok     bool
+}
+
+// planGoal returns a plan for the goal, or nil if there is none. Expressing
+// and evolving both need a focused thought that is clear enough, so the plan
+// readies the clearest thought (or a new one) before acting.
+func planGoal(view EntityView, goal Goal) []Command {
+	p := &plan{view: view, state: view.State, energy: view.Energy, ok: true}
+	cfg := &p.view.Config
+	switch goal.Kind {
+	case GoalExpress:
+		p.ready(p.clearest(), view.Threshold())
+		p.do("Acting", NewCommand("express"))
+	case GoalMaxEnergy:
+		p.ready(p.clearest(), cfg.EvolveMinClarity)
+		p.do("Acting", NewCommand("evolve", "max_energy", "increase"))
+	case GoalThreshold:
+		p.ready(p.clearest(), cfg.EvolveMinClarity)
+		p.do("Acting", NewCommand("evolve", "threshold", "decrease"))
+	case GoalUnderstand:
+		index := -1
+		for i, t := range view.Thoughts {
+			if t.ID == goal.ThoughtID {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil
+		}
+		p.ready(index, goal.Target)
+	}
+	if !p.ok {
+		return nil
+	}
+	return p.steps
+}
+
+// clearest returns the index of the clearest thought, preferring the focused
+// one, or -1 if the mind is empty.
+func (p *plan) clearest() int {
+	best := -1
+	if p.view.HasFocus() {
+		best = p.view.FocusIndex
+	}
+	for i, t := range p.view.Thoughts {
+		if best < 0 || t.Clarity > p.view.Thoughts[best].Clarity {
+			best = i
+		}
+	}
+	return best
+}
+
+// ready focuses the thought at index, or a newly generated one if index is
+// -1, and introspects on it until it should be at least clarity clear.
+// Introspection is expected to gain the middle of its range, as the mind's
+// affect moves it.
+func (p *plan) ready(index int, clarity float64) {
+	current := InitialClarity
+	if index < 0 {
+		p.do("Thinking", NewCommand("generate"))
+		index = len(p.view.Thoughts)
+	} else {
+		current = p.view.Thoughts[index].Clarity
+	}
+	if index != p.view.FocusIndex {
+		p.do("Thinking", NewCommand("focus", strconv.Itoa(index)))
+	}
+	cfg := &p.view.Config
+	gain := p.view.Affect.IntrospectGain((cfg.IntrospectGainMin+cfg.IntrospectGainMax)/2, cfg)
+	if current < clarity && gain <= 0 {
+		p.ok = false // Introspection gets nowhere in this mood
+		return
+	}
+	for ; current < clarity && p.ok && len(p.steps) < maxPlanSteps; current += gain {
+		p.do("Reflecting", NewCommand("introspect"))
+	}
+}
+
+// do adds a command to be run in state, preceded by the moves to get there
+// and any rest needed to afford it.
+func (p *plan) do(state string, cmd Command) {
+	if !p.ok || len(p.steps) >= maxPlanSteps {
+		return
+	}
+	rule := p.view.Rules.Lookup(state, cmd.Name)
+	if rule == nil {
+		p.ok = false
+		return
+	}
+	need := p.need(rule)
+	p.goTo(state, need)
+	if p.energy < need && state != "Idle" {
+		p.goTo("Idle", 0)
+		p.goTo(state, need)
+	}
+	p.step(cmd, rule)
+}
+
+// goTo adds the moves from the current state to state: directly if a rule
+// leads there, or else through Idle. Before leaving Idle the entity rests
+// until it can afford the move and then have energy left on arrival.
+func (p *plan) goTo(state string, energy int) {
+	for p.ok && p.state != state && len(p.steps) < maxPlanSteps {
+		rule := p.move(p.state, state)
+		if rule == nil && p.state != "Idle" {
+			rule = p.move(p.state, "Idle")
+		}
+		if rule == nil {
+			p.ok = false
+			return
+		}
+		if p.state == "Idle" {
+			p.rest(max(p.need(rule), rule.Cost.resolve(&p.view.Config)+energy))
+		}
+		p.step(NewCommand(rule.Command), rule)
+		p.state = rule.To
+	}
+}
+
+// move returns the rule leading from one state to another, if there is one.
+func (p *plan) move(from, to string) *TransitionRule {
+	for i, rule := range p.view.Rules.Rules {
+		if rule.From == from && rule.To == to {
+			return &p.view.Rules.Rules[i]
+		}
+	}
+	return nil
+}
+
+// rest recharges in Idle until the entity should have energy, or as much as
+// it can hold.
+func (p *plan) rest(energy int) {
+	rule := p.view.Rules.Lookup("Idle", "recharge")
+	for p.energy < min(energy, p.view.MaxEnergy) && len(p.steps) < maxPlanSteps {
+		if rule == nil {
+			p.ok = false
+			return
+		}
+		p.step(NewCommand("recharge"), rule)
+	}
+}
+
+// need is the energy a rule's guard asks for.
+func (p *plan) need(rule *TransitionRule) int {
+	return max(rule.Guard.MinEnergy.resolve(&p.view.Config), rule.Cost.resolve(&p.view.Config))
+}
+
+// step adds a command and the energy it is expected to leave, after the
+// next tick's regeneration.
+func (p *plan) step(cmd Command, rule *TransitionRule) {
+	cfg := &p.view.Config
+	p.steps = append(p.steps, cmd)
+	p.energy -= rule.Cost.resolve(cfg)
+	for _, effect := range rule.Effects {
+		if effect == "recharge" {
+			p.energy += cfg.RechargeAmount
+		}
+	}
+	p.energy = min(p.energy+cfg.EnergyRegen, p.view.MaxEnergy)
+}
+
+// formatPlan shows a plan on one line, with repeated commands counted, e.g.
+// "think -> focus 2 -> idle -> reflect -> introspect x3". Only the first
+// limit steps are shown.
+func formatPlan(steps []Command, limit int) string {
+	var parts []string
+	for i := 0; i < len(steps); {
+		if len(parts) == limit {
+			return strings.Join(parts, " -> ") + " -> ..."
+		}
+		n := 1
+		for i+n < len(steps) && steps[i+n].String() == steps[i].String() {
+			n++
+		}
+		part := steps[i].String()
+		if n > 1 {
+			part += fmt.Sprintf(" x%d", n)
+		}
+		parts = append(parts, part)
+		i += n
+	}
+	return strings.Join(parts, " -> ")
+}
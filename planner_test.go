@@ -0,0 +1,56 @@
+// planner_test.go
+package main
+
+import (
+	"testing"
+)
+
+func TestPlan_RestsBeforeUnaffordableSteps(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	entity.Mind.AddThought("a thought", OriginGenerated).Clarity = 0.65
+	entity.Mind.CurrentFocusIndex = 0
+	entity.Mind.Energy = 8
+	entity.CurrentFSMState = &ReflectingState{}
+	setGoal(entity.Mind, "express:1")
+
+	want := "idle -> recharge -> reflect -> introspect -> idle -> recharge -> act -> express"
+	if got := formatPlan((&PlannerPolicy{}).Plan(entity.View()), 20); got != want {
+		t.Errorf("Plan: Expected %q, got %q", want, got)
+	}
+}
+
+func TestPlan_GeneratesWhenTheMindIsEmpty(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	setGoal(entity.Mind, "express:1")
+
+	want := "think -> generate -> focus 0 -> idle -> reflect -> introspect x3 -> idle -> ..."
+	if got := formatPlan((&PlannerPolicy{}).Plan(entity.View()), 7); got != want {
+		t.Errorf("Plan: Expected %q, got %q", want, got)
+	}
+}
+
+func TestPlan_NeedsAWayInTheRules(t *testing.T) {
+	rules, err := ParseTransitions([]byte(`{"rules": [
+		{"from": "Idle", "command": "act", "to": "Acting"},
+		{"from": "Acting", "command": "express", "guard": {"requires_focus": true}, "effects": ["express"]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity := NewDefaultEntities()[1]
+	entity.Mind.Rules = rules
+	setGoal(entity.Mind, "express:1")
+	if plan := (&PlannerPolicy{}).Plan(entity.View()); plan != nil {
+		t.Errorf("Plan: Expected no plan without a way to think, got %v", plan)
+	}
+}
+
+func TestFormatPlan(t *testing.T) {
+	steps := []Command{NewCommand("introspect"), NewCommand("introspect"), NewCommand("focus", "1"), NewCommand("focus", "2")}
+	if got := formatPlan(steps, 5); got != "introspect x2 -> focus 1 -> focus 2" {
+		t.Errorf("formatPlan: got %q", got)
+	}
+	if got := formatPlan(steps, 1); got != "introspect x2 -> ..." {
+		t.Errorf("formatPlan: Expected the plan cut short, got %q", got)
+	}
+}
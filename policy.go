@@ -0,0 +1,231 @@
+// policy.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Command is a decision made by a Policy, in the same vocabulary a player types.
+// The zero Command means "do nothing this turn".
+type Command struct {
+	Name string
+	Args []string
+}
+
+// NewCommand builds a Command from whitespace-separated parts.
+func NewCommand(parts ...string) Command {
+	if len(parts) == 0 {
+		return Command{}
+	}
+	return Command{Name: parts[0], Args: parts[1:]}
+}
+
+// IsZero reports whether the command is "do nothing".
+func (c Command) IsZero() bool { return c.Name == "" }
+
+// Parts returns the command as HandleInput expects it.
+func (c Command) Parts() []string {
+	if c.IsZero() {
+		return nil
+	}
+	return append([]string{c.Name}, c.Args...)
+}
+
+func (c Command) String() string { return strings.Join(c.Parts(), " ") }
+
+// EntityView is a read-only snapshot of an entity handed to policies, so a
+// policy can look at a mind but not change it behind the FSM's back.
+type EntityView struct {
+	ID                  string
+	IsPlayer            bool
+	State               string
+	Energy              int
+	MaxEnergy           int
+	ExpressionThreshold float64
+	Affect              Affect
+	Thoughts            []Thought
+	FocusIndex          int // -1 if no focus
+	Inbox               []Perception
+	Goals               []Goal
+	Config              Config           // The parameters the entity's mind runs under
+	Rules               *TransitionTable // What each command does and costs; read-only
+}
+
+// View takes a snapshot of the entity for its policy.
+func (e *Entity) View() EntityView {
+	return EntityView{
+		ID:                  e.ID,
+		IsPlayer:            e.IsPlayer,
+		State:               e.CurrentFSMState.GetName(),
+		Energy:              e.Mind.Energy,
+		MaxEnergy:           e.Mind.MaxEnergy,
+		ExpressionThreshold: e.Mind.ExpressionThreshold,
+		Affect:              e.Mind.Affect,
+		Thoughts:            append([]Thought(nil), e.Mind.Thoughts...),
+		FocusIndex:          e.Mind.CurrentFocusIndex,
+		Inbox:               append([]Perception(nil), e.Mind.Inbox...),
+		Goals:               append([]Goal(nil), e.Mind.Goals...),
+		Config:              *e.Mind.Config,
+		Rules:               e.Mind.Rules,
+	}
+}
+
+// HasFocus reports whether the entity is focused on a thought.
+func (v EntityView) HasFocus() bool {
+	return v.FocusIndex >= 0 && v.FocusIndex < len(v.Thoughts)
+}
+
+// FocusedClarity returns the clarity of the focused thought, or 0 without focus.
+func (v EntityView) FocusedClarity() float64 {
+	if !v.HasFocus() {
+		return 0
+	}
+	return v.Thoughts[v.FocusIndex].Clarity
+}
+
+// Threshold returns the expression threshold as the entity's affect moves it,
+// which is what express is judged against.
+func (v EntityView) Threshold() float64 {
+	return v.Affect.Threshold(v.ExpressionThreshold, &v.Config)
+}
+
+// Policy decides what an automated entity does each tick. Each Entity holds
+// its own, so different brains can be compared in the same simulation.
+type Policy interface {
+	Name() string
+	Decide(view EntityView, rng *RNG) Command
+}
+
+// policies maps the names used in save files and on the command line to
+// policy constructors.
+var policies = map[string]func() Policy{
+	"random":  func() Policy { return &RandomHeuristicPolicy{} },
+	"planner": func() Policy { return &PlannerPolicy{} },
+	"goap":    func() Policy { return &GOAPPolicy{} },
+}
+
+// DefaultPolicyName is used for AI entities and for the player's autopilot.
+const DefaultPolicyName = "random"
+
+// getPolicyByName creates the named policy, or loads the behavior tree a
+// "tree:FILE" name points to for an entity following rules.
+func getPolicyByName(name string, rules *TransitionTable) (Policy, error) {
+	if file, ok := strings.CutPrefix(name, treePolicyPrefix); ok {
+		tree, err := LoadBehaviorTree(file, rules)
+		if err != nil {
+			return nil, err
+		}
+		return &BehaviorTreePolicy{File: file, Tree: tree}, nil
+	}
+	newPolicy, ok := policies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy %q (available: %s, or %sFILE)", name, strings.Join(policyNames(), ", "), treePolicyPrefix)
+	}
+	return newPolicy(), nil
+}
+
+// policyNames lists the registered policies in a stable order.
+func policyNames() []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RandomHeuristicPolicy is the original autopilot: a handful of hand-tuned
+// rules per state with coin flips between them. Moods tip some of the flips:
+// an irritable mind rests sooner, a gloomy one broods on its focus and an
+// excited one speaks up whenever it can.
+type RandomHeuristicPolicy struct{}
+
+func (p *RandomHeuristicPolicy) Name() string { return "random" }
+
+func (p *RandomHeuristicPolicy) Decide(view EntityView, rng *RNG) Command {
+	const MinExpressionThresholdForAIDecrease = 0.20 // AI won't try to decrease if already very low
+	const MaxEnergySoftCapForAI = 150                // AI prioritizes evolving MaxEnergy if below this
+
+	mood := view.Affect.Mood()
+	switch view.State {
+	case "Idle":
+		tired := view.Energy < 30 || (mood == MoodIrritable && view.Energy*3 < view.MaxEnergy*2)
+		if tired && view.Energy < view.MaxEnergy {
+			if len(view.Thoughts) > 0 && rng.IntN(2) == 0 { // A tired mind with something on it may sleep on it
+				return NewCommand("sleep")
+			}
+			return NewCommand("recharge")
+		} else if view.Energy > 50 && rng.IntN(2) == 0 { // 50% chance to think
+			return NewCommand("think")
+		} else if rng.IntN(3) == 0 { // Small chance to try reflecting, acting or perceiving if energy is high
+			switch rng.IntN(3) {
+			case 0:
+				return NewCommand("reflect")
+			case 1:
+				return NewCommand("act")
+			}
+			return NewCommand("perceive")
+		}
+	case "Thinking":
+		if len(view.Inbox) > 0 && view.Energy > 15 && rng.IntN(2) == 0 { // 50% chance to deal with what was heard
+			if view.HasFocus() && rng.IntN(2) == 0 {
+				return NewCommand("integrate", "0")
+			} else if view.Inbox[0].Clarity >= 0.3 {
+				return NewCommand("accept", "0")
+			}
+			return NewCommand("ignore", "0")
+		} else if n := len(view.Thoughts); n >= 2 && view.Energy > 30 && rng.IntN(4) == 0 { // Sometimes merge two thoughts
+			i, j := rng.IntN(n), rng.IntN(n-1)
+			if j >= i {
+				j++
+			}
+			if rng.IntN(2) == 0 {
+				return NewCommand("combine", fmt.Sprint(i), fmt.Sprint(j), "keep")
+			}
+			return NewCommand("combine", fmt.Sprint(i), fmt.Sprint(j))
+		} else if view.Energy > 15 && rng.IntN(2) == 0 { // 50% chance to generate
+			return NewCommand("generate")
+		} else if len(view.Thoughts) > 0 && !view.HasFocus() && rng.IntN(2) == 0 {
+			return NewCommand("focus", fmt.Sprintf("%d", rng.IntN(len(view.Thoughts))))
+		} else if view.HasFocus() && view.Energy > 30 && rng.IntN(2) == 0 {
+			return NewCommand("reflect") // Chance to go reflect if focused and has energy
+		}
+		return NewCommand("idle")
+	case "Reflecting":
+		if view.HasFocus() && view.Energy > 20 && view.FocusedClarity() < 0.9 && (mood == MoodGloomy || rng.IntN(2) == 0) {
+			return NewCommand("introspect")
+		} else if view.HasFocus() && view.FocusedClarity() >= view.Threshold() && view.Energy > 30 && rng.IntN(2) == 0 {
+			return NewCommand("act") // Chance to go act if clarity is good
+		}
+		return NewCommand("idle")
+	case "Acting":
+		// Attempt to Evolve first if conditions are met
+		if view.HasFocus() && view.FocusedClarity() >= view.Config.EvolveMinClarity && view.Energy >= view.Config.EvolveCost {
+			if view.MaxEnergy < MaxEnergySoftCapForAI {
+				return NewCommand("evolve", "max_energy", "increase")
+			} else if view.ExpressionThreshold > MinExpressionThresholdForAIDecrease {
+				return NewCommand("evolve", "threshold", "decrease")
+			}
+		}
+		// If AI didn't choose to evolve, consider expressing or idling
+		if view.HasFocus() && view.Energy > 25 && view.FocusedClarity() >= view.Threshold() && (mood == MoodExcited || rng.IntN(2) == 0) {
+			return NewCommand("express")
+		}
+		return NewCommand("idle")
+	case "Dreaming":
+		if view.Energy*5 >= view.MaxEnergy*4 { // Wake once 80% rested
+			return NewCommand("wake")
+		}
+		return NewCommand("dream")
+	case "Perceiving":
+		if len(view.Inbox) > 0 && view.Energy > 10 {
+			return NewCommand("listen")
+		} else if view.Energy > 20 && rng.IntN(2) == 0 { // 50% chance to look around
+			return NewCommand("observe")
+		}
+		return NewCommand("idle")
+	}
+	return Command{}
+}
@@ -0,0 +1,121 @@
+// qualia.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Modality is the sense a thought is experienced in.
+type Modality string
+
+const (
+	ModalityVerbal   Modality = "verbal"   // Inner speech, or text read from a stream
+	ModalityAuditory Modality = "auditory" // Something heard
+	ModalityVisual   Modality = "visual"
+	ModalityTactile  Modality = "tactile"  // Touch and temperature
+	ModalityAbstract Modality = "abstract" // Combined from thoughts in different senses
+)
+
+var modalities = []Modality{ModalityVerbal, ModalityAuditory, ModalityVisual, ModalityTactile, ModalityAbstract}
+
+// Qualia describes what having a thought is like, alongside how well it is
+// understood (its clarity).
+type Qualia struct {
+	Intensity   float64  `json:"intensity"`   // How strongly it is felt, 0.0 to 1.0
+	Vividness   float64  `json:"vividness"`   // How detailed and distinct, 0.0 to 1.0
+	Modality    Modality `json:"modality"`    // The sense it is experienced in
+	Familiarity float64  `json:"familiarity"` // How known it feels, 0.0 to 1.0
+}
+
+// newQualia is how a thought of the given origin first feels: middling in
+// intensity, faint unless it was perceived, and in the sense it arrived by.
+// Familiarity depends on the rest of the mind; see AddThought.
+func newQualia(origin ThoughtOrigin) Qualia {
+	q := Qualia{Intensity: 0.5, Vividness: 0.2, Modality: ModalityVerbal}
+	switch origin {
+	case OriginHeard:
+		q.Modality = ModalityAuditory
+	case OriginPerceived:
+		q.Vividness = 0.6
+	}
+	return q
+}
+
+// blend is the qualia of a thought combined from two others: the mean of
+// each quality, in their sense if they share one.
+func blend(a, b Qualia) Qualia {
+	q := Qualia{
+		Intensity:   (a.Intensity + b.Intensity) / 2,
+		Vividness:   (a.Vividness + b.Vividness) / 2,
+		Modality:    a.Modality,
+		Familiarity: (a.Familiarity + b.Familiarity) / 2,
+	}
+	if a.Modality != b.Modality {
+		q.Modality = ModalityAbstract
+	}
+	return q
+}
+
+// introspect changes the qualia of a thought being introspected on: it grows
+// as vivid as the clarity gained, more familiar, and a little less intense as
+// the mind gets used to it.
+func (q *Qualia) introspect(gain float64, cfg *Config) {
+	q.Vividness = min(q.Vividness+gain, 1)
+	q.Familiarity = min(q.Familiarity+cfg.IntrospectFamiliarity, 1)
+	q.Intensity = max(q.Intensity-cfg.IntrospectHabituation, 0)
+}
+
+// Fidelity is how much of a thought with the given clarity survives being
+// expressed: clarity^(1.5 - vividness/2 - familiarity/2). A vivid, familiar
+// thought comes across better than its clarity alone would, a faint, novel
+// one worse, and a perfectly clear thought always arrives intact.
+func (q Qualia) Fidelity(clarity float64) float64 {
+	return math.Pow(clarity, 1.5-q.Vividness/2-q.Familiarity/2)
+}
+
+// received is the noisy copy of the qualia a listener gets from an expression
+// with the given fidelity. Intensity and vividness are jittered by up to
+// (1 - fidelity) * qualia_noise, and the sense survives with probability
+// fidelity, or else it was just words heard. The same number of draws is
+// always made, so replays stay in step.
+func (q Qualia) received(fidelity float64, cfg *Config, rng *RNG) Qualia {
+	jitter := func(x float64) float64 {
+		return min(max(x+(rng.Float64()*2-1)*(1-fidelity)*cfg.QualiaNoise, 0), 1)
+	}
+	q.Intensity = jitter(q.Intensity)
+	q.Vividness = jitter(q.Vividness)
+	if rng.Float64() >= fidelity {
+		q.Modality = ModalityAuditory
+	}
+	return q
+}
+
+func (q Qualia) String() string {
+	return fmt.Sprintf("intensity %.2f, vividness %.2f, %s, familiarity %.2f", q.Intensity, q.Vividness, q.Modality, q.Familiarity)
+}
+
+func (q Qualia) validate() error {
+	for _, v := range []float64{q.Intensity, q.Vividness, q.Familiarity} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("qualia %s are outside 0..1", q)
+		}
+	}
+	for _, m := range modalities {
+		if q.Modality == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown modality %q", q.Modality)
+}
+
+// qualiaEffect shows the qualia of the thought at args[0]: qualia <index>.
+func qualiaEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 || index >= len(ctx.Thoughts) {
+		return []Event{invalid(entityID, "qualia", fmt.Sprintf("invalid index '%s'", args[0]))}, false
+	}
+	t := ctx.Thoughts[index]
+	return []Event{{Kind: EventQualia, Entity: entityID, Index: index, ThoughtID: t.ID, Thought: t.Text, Clarity: t.Clarity, Qualia: &t.Qualia}}, true
+}
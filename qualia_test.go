@@ -0,0 +1,122 @@
+// qualia_test.go
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestQualia_NewThoughtsFeelLikeTheirOrigin(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	generated := *ctx.AddThought("the rain outside is loud", OriginGenerated)
+	if generated.Qualia != (Qualia{Intensity: 0.5, Vividness: 0.2, Modality: ModalityVerbal}) {
+		t.Errorf("AddThought: Expected a faint, unfamiliar verbal thought, got %+v", generated.Qualia)
+	}
+	similar := *ctx.AddThought("the rain outside", OriginGenerated)
+	if math.Abs(similar.Qualia.Familiarity-2.0/3) > 1e-9 {
+		t.Errorf("AddThought: Expected familiarity from the overlap with the first thought, got %.2f", similar.Qualia.Familiarity)
+	}
+
+	heard := *ctx.AddHeard(Perception{From: "b", Text: "someone laughing", Clarity: 0.4, Qualia: Qualia{Intensity: 0.9, Vividness: 0.7, Modality: ModalityVisual, Familiarity: 0.8}})
+	if heard.Qualia != (Qualia{Intensity: 0.9, Vividness: 0.7, Modality: ModalityVisual, Familiarity: 0}) {
+		t.Errorf("AddHeard: Expected the conveyed qualia with the listener's own familiarity, got %+v", heard.Qualia)
+	}
+
+	ctx.Environment = &Environment{Sensors: []Sensor{defaultSensors[0]}}
+	ctx.Energy = ctx.MaxEnergy
+	observeEffect("e", ctx, nil)
+	perceived := ctx.Thoughts[len(ctx.Thoughts)-1].Qualia
+	if perceived.Modality != ModalityVisual || perceived.Vividness != 0.6 || perceived.Intensity < 0 || perceived.Intensity > 1 {
+		t.Errorf("observe: Expected a vivid visual thought with the reading's intensity, got %+v", perceived)
+	}
+}
+
+func TestQualia_IntrospectionChangesTheVector(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.IntrospectGainMin, cfg.IntrospectGainMax = 0.2, 0.2
+	ctx := NewMindContext(cfg)
+	ctx.AddThought("a thought", OriginGenerated)
+	ctx.CurrentFocusIndex = 0
+
+	(&ReflectingState{}).HandleInput("e", ctx, strings.Fields("introspect"))
+	q := ctx.Thoughts[0].Qualia
+	if math.Abs(q.Vividness-0.4) > 1e-9 || math.Abs(q.Familiarity-0.1) > 1e-9 || math.Abs(q.Intensity-0.45) > 1e-9 {
+		t.Errorf("introspect: Expected vividness 0.40, familiarity 0.10 and intensity 0.45, got %+v", q)
+	}
+}
+
+func TestQualia_CombinedThoughtsBlend(t *testing.T) {
+	a := Qualia{Intensity: 0.2, Vividness: 0.4, Modality: ModalityVisual, Familiarity: 1}
+	b := Qualia{Intensity: 0.6, Vividness: 0.8, Modality: ModalityVisual, Familiarity: 0}
+	if got := blend(a, b); math.Abs(got.Intensity-0.4) > 1e-9 || math.Abs(got.Vividness-0.6) > 1e-9 || got.Familiarity != 0.5 || got.Modality != ModalityVisual {
+		t.Errorf("blend: Expected the mean in the shared sense, got %+v", got)
+	}
+	b.Modality = ModalityTactile
+	if got := blend(a, b); got.Modality != ModalityAbstract {
+		t.Errorf("blend: Expected different senses to make an abstract thought, got %s", got.Modality)
+	}
+}
+
+func TestQualia_Fidelity(t *testing.T) {
+	faint := Qualia{Vividness: 0, Familiarity: 0}
+	vivid := Qualia{Vividness: 1, Familiarity: 1}
+	if faint.Fidelity(1) != 1 || vivid.Fidelity(1) != 1 {
+		t.Errorf("Fidelity: Expected a perfectly clear thought to come across intact")
+	}
+	if got := faint.Fidelity(0.64); math.Abs(got-0.512) > 1e-9 {
+		t.Errorf("Fidelity: Expected a faint, novel thought at 0.64 to come across at 0.512, got %.3f", got)
+	}
+	if got := vivid.Fidelity(0.64); math.Abs(got-0.8) > 1e-9 {
+		t.Errorf("Fidelity: Expected a vivid, familiar thought at 0.64 to come across at 0.80, got %.3f", got)
+	}
+}
+
+func TestPerceive_ReceiversGetNoisyQualia(t *testing.T) {
+	cfg := DefaultConfig()
+	rng := NewRNG(9)
+	sent := Qualia{Intensity: 0.5, Vividness: 0.5, Modality: ModalityVisual, Familiarity: 0.5}
+
+	clear := perceive("speaker", Expression{Text: "a bright light", Clarity: 1, Qualia: sent}, 1, cfg, rng)
+	if clear.Qualia != sent {
+		t.Errorf("perceive: Expected a fully clear expression to convey its qualia exactly, got %+v", clear.Qualia)
+	}
+
+	changed, lostSense := false, false
+	for range 50 {
+		p := perceive("speaker", Expression{Text: "a bright light", Clarity: 0.5, Qualia: sent}, 1, cfg, rng)
+		if math.Abs(p.Qualia.Intensity-0.5) > 0.25 || math.Abs(p.Qualia.Vividness-0.5) > 0.25 {
+			t.Fatalf("perceive: Expected noise within (1 - fidelity) * qualia_noise, got %+v", p.Qualia)
+		}
+		changed = changed || p.Qualia.Intensity != sent.Intensity
+		lostSense = lostSense || p.Qualia.Modality == ModalityAuditory
+	}
+	if !changed || !lostSense {
+		t.Errorf("perceive: Expected a half-clear expression to blur its qualia and sometimes its sense")
+	}
+}
+
+func TestQualiaCommand(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("a thought", OriginGenerated).Clarity = 0.64
+
+	_, events := (&ReflectingState{}).HandleInput("e", ctx, strings.Fields("qualia 0"))
+	want := "e's thought [0] 'a thought' feels like: intensity 0.50, vividness 0.20, verbal, familiarity 0.00. Clarity 0.64, expressed with fidelity 0.54."
+	if len(events) != 1 || events[0].Kind != EventQualia || events[0].String() != want {
+		t.Errorf("qualia: Expected %q, got %+v", want, events)
+	}
+	_, events = (&ReflectingState{}).HandleInput("e", ctx, strings.Fields("qualia 3"))
+	if len(events) != 1 || events[0].Kind != EventInvalidCommand {
+		t.Errorf("qualia: Expected an invalid index rejected, got %+v", events)
+	}
+}
+
+func TestLoadGame_RejectsInvalidQualia(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("a thought", OriginGenerated).Qualia.Modality = "smell"
+	ctx.receive(Perception{From: "b", Text: "words", Qualia: Qualia{Intensity: 2, Modality: ModalityAuditory}})
+	errs := ctx.validate("mind")
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), `unknown modality "smell"`) || !strings.Contains(errs[1].Error(), "outside 0..1") {
+		t.Errorf("validate: Expected the bad modality and intensity reported, got %v", errs)
+	}
+}
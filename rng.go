@@ -0,0 +1,50 @@
+// rng.go
+package main
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+)
+
+// RNG is the simulation's random source. Unlike the global math/rand functions
+// it is seedable and its exact position in the stream can be saved and
+// restored, so a loaded game continues exactly as the original would have.
+type RNG struct {
+	*rand.Rand
+	src *rand.PCG
+}
+
+// NewRNG creates a random source seeded with seed.
+func NewRNG(seed int64) *RNG {
+	src := rand.NewPCG(uint64(seed), uint64(seed)^0x9e3779b97f4a7c15)
+	return &RNG{Rand: rand.New(src), src: src}
+}
+
+// randomSeed picks a seed for runs that did not ask for one.
+func randomSeed() int64 {
+	return rand.Int64()
+}
+
+// MarshalJSON stores the generator's internal state.
+func (r *RNG) MarshalJSON() ([]byte, error) {
+	state, err := r.src.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(state)
+}
+
+// UnmarshalJSON restores a state written by MarshalJSON.
+func (r *RNG) UnmarshalJSON(data []byte) error {
+	var state []byte
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	src := &rand.PCG{}
+	if err := src.UnmarshalBinary(state); err != nil {
+		return err
+	}
+	r.src = src
+	r.Rand = rand.New(src)
+	return nil
+}
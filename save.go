@@ -0,0 +1,544 @@
+// save.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CurrentSaveVersion is the format_version written by saveGame. Bump it and
+// append to saveMigrations whenever the save format changes.
+const CurrentSaveVersion = 13
+
+// SerializableEntityState represents an entity's state for serialization.
+type SerializableEntityState struct {
+	ID                  string              `json:"id"`
+	IsPlayer            bool                `json:"is_player"`
+	Mind                *MindContext        `json:"mind"` // MindContext is from states.go but used here
+	CurrentFSMStateName string              `json:"current_fsm_state_name"`
+	PolicyName          string              `json:"policy,omitempty"`         // Empty for a manually driven player
+	ThoughtSource       *SavedThoughtSource `json:"thought_source,omitempty"` // Only if not the built-in phrases
+}
+
+// SimulationState represents the simulation state for serialization.
+type SimulationState struct {
+	FormatVersion    int                       `json:"format_version"`
+	Entities         []SerializableEntityState `json:"entities"`
+	EventLog         []Event                   `json:"event_log"`
+	AutoPilotEnabled bool                      `json:"auto_pilot_enabled"`
+	Seed             int64                     `json:"seed"`
+	Tick             int                       `json:"tick"`
+	RNG              *RNG                      `json:"rng,omitempty"`         // Exact RNG position, so a loaded game continues identically
+	PolicyRNG        *RNG                      `json:"policy_rng,omitempty"`  // Same for the policies' decision stream
+	Transitions      *TransitionTable          `json:"transitions,omitempty"` // Only if not the built-in table
+	Config           *Config                   `json:"config,omitempty"`      // Parameters left out keep their defaults
+}
+
+// saveMigrations[i] upgrades a decoded save from format_version i to i+1.
+// Migrations work on generic JSON so they never depend on today's structs.
+var saveMigrations = []func(save map[string]any) error{
+	migrateV0ToV1,
+	migrateV1ToV2,
+	migrateV2ToV3,
+	migrateV3ToV4,
+	migrateV4ToV5,
+	migrateV5ToV6,
+	migrateV6ToV7,
+	migrateV7ToV8,
+	migrateV8ToV9,
+	migrateV9ToV10,
+	migrateV10ToV11,
+	migrateV11ToV12,
+	migrateV12ToV13,
+}
+
+// migrateV0ToV1 upgrades unversioned saves: thoughts were plain strings with a
+// single Clarity on the mind for the focused one, and policies were implied.
+func migrateV0ToV1(save map[string]any) error {
+	entities, _ := save["entities"].([]any)
+	autopilot, _ := save["auto_pilot_enabled"].(bool)
+	for i, raw := range entities {
+		entity, ok := raw.(map[string]any)
+		if !ok {
+			return fmt.Errorf("entities[%d] is not an object", i)
+		}
+		if _, ok := entity["policy"]; !ok {
+			if isPlayer, _ := entity["is_player"].(bool); !isPlayer || autopilot {
+				entity["policy"] = "random" // The only policy when v1 was defined
+			}
+		}
+
+		mind, ok := entity["mind"].(map[string]any)
+		if !ok {
+			continue // Left for validation to report
+		}
+		focusedClarity, _ := mind["Clarity"].(float64)
+		focus, _ := mind["CurrentFocusIndex"].(float64)
+		delete(mind, "Clarity")
+		thoughts, _ := mind["Thoughts"].([]any)
+		for j, t := range thoughts {
+			text, ok := t.(string)
+			if !ok {
+				continue // Already a record
+			}
+			clarity := InitialClarity
+			if j == int(focus) {
+				clarity = focusedClarity
+			}
+			thoughts[j] = map[string]any{
+				"id":      j + 1,
+				"text":    text,
+				"clarity": clarity,
+				"source":  string(OriginGenerated),
+			}
+		}
+		if _, ok := mind["NextThoughtID"]; !ok {
+			mind["NextThoughtID"] = len(thoughts)
+		}
+	}
+	return nil
+}
+
+// migrateV1ToV2 covers the split of policy decisions onto their own RNG
+// stream. Version 1 saves have no policy_rng; they continue with a fresh
+// decision stream derived from the seed, so nothing needs rewriting.
+func migrateV1ToV2(save map[string]any) error {
+	return nil
+}
+
+// migrateV2ToV3 converts the event log from preformatted strings to Event
+// records. The old text is kept verbatim as a notice.
+func migrateV2ToV3(save map[string]any) error {
+	log, _ := save["event_log"].([]any)
+	for i, raw := range log {
+		text, ok := raw.(string)
+		if !ok {
+			continue // Already a record
+		}
+		log[i] = map[string]any{"kind": string(EventNotice), "entity": "", "tick": 0, "reason": text}
+	}
+	return nil
+}
+
+// migrateV3ToV4 covers the move to data-driven transition tables. Saves
+// without a transitions table use the built-in one, as every older save did.
+func migrateV3ToV4(save map[string]any) error {
+	return nil
+}
+
+// migrateV4ToV5 covers the move of tuning parameters into Config. Older
+// saves ran with what are now the defaults, which is what a save without a
+// config gets.
+func migrateV4ToV5(save map[string]any) error {
+	return nil
+}
+
+// migrateV5ToV6 covers per-entity thought sources. Entities saved without
+// one generate from the built-in phrases, as they always did.
+func migrateV5ToV6(save map[string]any) error {
+	return nil
+}
+
+// migrateV6ToV7 covers thought lineage. Older thoughts have no recorded
+// parents, which is how a thought without parents is saved.
+func migrateV6ToV7(save map[string]any) error {
+	return nil
+}
+
+// migrateV7ToV8 covers associations between thoughts. Older minds start with
+// none, and nothing focused or heard before, as a new mind does.
+func migrateV7ToV8(save map[string]any) error {
+	return nil
+}
+
+// migrateV8ToV9 adds forgetting. Every thought starts fully salient, and minds
+// decay at the rate new minds had when forgetting was introduced, with no
+// working memory limit.
+func migrateV8ToV9(save map[string]any) error {
+	entities, _ := save["entities"].([]any)
+	for _, raw := range entities {
+		entity, _ := raw.(map[string]any)
+		mind, ok := entity["mind"].(map[string]any)
+		if !ok {
+			continue // Left for validation to report
+		}
+		if _, ok := mind["SalienceDecay"]; !ok {
+			mind["SalienceDecay"] = 0.005
+		}
+		thoughts, _ := mind["Thoughts"].([]any)
+		for _, t := range thoughts {
+			if thought, ok := t.(map[string]any); ok {
+				if _, ok := thought["salience"]; !ok {
+					thought["salience"] = 1.0
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrateV9ToV10 covers affect. Minds saved without one start out neutral,
+// which is how a zero Affect decodes.
+func migrateV9ToV10(save map[string]any) error {
+	return nil
+}
+
+// migrateV10ToV11 adds qualia. Thoughts get what a new thought of their
+// origin feels like, none of them familiar, and perceptions waiting in an
+// inbox feel like something heard.
+func migrateV10ToV11(save map[string]any) error {
+	entities, _ := save["entities"].([]any)
+	for _, raw := range entities {
+		entity, _ := raw.(map[string]any)
+		mind, ok := entity["mind"].(map[string]any)
+		if !ok {
+			continue // Left for validation to report
+		}
+		thoughts, _ := mind["Thoughts"].([]any)
+		for _, t := range thoughts {
+			if thought, ok := t.(map[string]any); ok {
+				if _, ok := thought["qualia"]; !ok {
+					source, _ := thought["source"].(string)
+					thought["qualia"] = qualiaJSON(newQualia(ThoughtOrigin(source)))
+				}
+			}
+		}
+		inbox, _ := mind["Inbox"].([]any)
+		for _, p := range inbox {
+			if perception, ok := p.(map[string]any); ok {
+				if _, ok := perception["qualia"]; !ok {
+					perception["qualia"] = qualiaJSON(newQualia(OriginHeard))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// migrateV11ToV12 covers goals. Minds saved without any have none, which is
+// how a mind without goals is saved.
+func migrateV11ToV12(save map[string]any) error {
+	return nil
+}
+
+// qualiaJSON is q as it decodes into a generic save.
+func qualiaJSON(q Qualia) map[string]any {
+	return map[string]any{"intensity": q.Intensity, "vividness": q.Vividness, "modality": string(q.Modality), "familiarity": q.Familiarity}
+}
+
+// migrateV12ToV13 moves the payload of logged ActivationSpread events out of
+// index and new_value into their own count and amount fields.
+func migrateV12ToV13(save map[string]any) error {
+	log, _ := save["event_log"].([]any)
+	for _, raw := range log {
+		event, ok := raw.(map[string]any)
+		if !ok || event["kind"] != string(EventActivationSpread) {
+			continue
+		}
+		for from, to := range map[string]string{"index": "count", "new_value": "amount"} {
+			if v, ok := event[from]; ok {
+				event[to] = v
+				delete(event, from)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateSave brings a decoded save up to CurrentSaveVersion.
+func migrateSave(save map[string]any) error {
+	version := 0
+	if v, ok := save["format_version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			return fmt.Errorf("format_version %v is not a valid version number", v)
+		}
+		version = int(f)
+	}
+	if version > CurrentSaveVersion {
+		return fmt.Errorf("save format_version %d is newer than this build supports (%d)", version, CurrentSaveVersion)
+	}
+	for ; version < CurrentSaveVersion; version++ {
+		if err := saveMigrations[version](save); err != nil {
+			return fmt.Errorf("migrating save from format_version %d: %w", version, err)
+		}
+	}
+	save["format_version"] = CurrentSaveVersion
+	return nil
+}
+
+// getStateByName converts a state name string to a State interface instance.
+// This is crucial for restoring FSM states during loading.
+func getStateByName(name string) (State, error) {
+	switch name {
+	case "Idle":
+		return &IdleState{}, nil
+	case "Thinking":
+		return &ThinkingState{}, nil
+	case "Reflecting":
+		return &ReflectingState{}, nil
+	case "Acting":
+		return &ActingState{}, nil
+	case "Dreaming":
+		return &DreamingState{}, nil
+	case "Perceiving":
+		return &PerceivingState{}, nil
+	default:
+		return nil, fmt.Errorf("unknown state name %q", name)
+	}
+}
+
+// rules returns the transition table the save runs with.
+func (st *SimulationState) rules() *TransitionTable {
+	if st.Transitions != nil {
+		return st.Transitions
+	}
+	return DefaultTransitions()
+}
+
+// validate checks a migrated save for anything that would crash or confuse the
+// simulation later, reporting every problem it finds.
+func (st *SimulationState) validate() error {
+	var errs []error
+	if len(st.Entities) == 0 {
+		errs = append(errs, errors.New("save has no entities"))
+	}
+	if st.Tick < 0 {
+		errs = append(errs, fmt.Errorf("tick %d is negative", st.Tick))
+	}
+	seen := make(map[string]bool)
+	for i, e := range st.Entities {
+		where := fmt.Sprintf("entities[%d] (%s)", i, e.ID)
+		if e.ID == "" {
+			errs = append(errs, fmt.Errorf("entities[%d] has no id", i))
+		} else if seen[e.ID] {
+			errs = append(errs, fmt.Errorf("%s: duplicate id", where))
+		}
+		seen[e.ID] = true
+		if _, err := getStateByName(e.CurrentFSMStateName); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+		if e.PolicyName != "" {
+			if _, err := getPolicyByName(e.PolicyName, st.rules()); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			}
+		}
+		if e.ThoughtSource != nil {
+			if _, err := e.ThoughtSource.restore(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			}
+		}
+		if e.Mind == nil {
+			errs = append(errs, fmt.Errorf("%s: mind is missing", where))
+			continue
+		}
+		errs = append(errs, e.Mind.validate(where)...)
+	}
+	return errors.Join(errs...)
+}
+
+// validate reports every way the mind is out of range.
+func (ctx *MindContext) validate(where string) []error {
+	var errs []error
+	if ctx.MaxEnergy <= 0 {
+		errs = append(errs, fmt.Errorf("%s: MaxEnergy %d must be positive", where, ctx.MaxEnergy))
+	}
+	if ctx.Energy < 0 || ctx.Energy > ctx.MaxEnergy {
+		errs = append(errs, fmt.Errorf("%s: Energy %d is outside 0..%d", where, ctx.Energy, ctx.MaxEnergy))
+	}
+	if ctx.ExpressionThreshold < 0 || ctx.ExpressionThreshold > 1 {
+		errs = append(errs, fmt.Errorf("%s: ExpressionThreshold %.2f is outside 0..1", where, ctx.ExpressionThreshold))
+	}
+	if ctx.Affect.Valence < -1 || ctx.Affect.Valence > 1 || ctx.Affect.Arousal < 0 || ctx.Affect.Arousal > 1 {
+		errs = append(errs, fmt.Errorf("%s: Affect %+v is outside valence -1..1, arousal 0..1", where, ctx.Affect))
+	}
+	if ctx.SalienceDecay < 0 || ctx.SalienceDecay > 1 {
+		errs = append(errs, fmt.Errorf("%s: SalienceDecay %.3f is outside 0..1", where, ctx.SalienceDecay))
+	}
+	if ctx.WorkingMemory < 0 {
+		errs = append(errs, fmt.Errorf("%s: WorkingMemory %d is negative", where, ctx.WorkingMemory))
+	}
+	if ctx.CurrentFocusIndex < -1 || ctx.CurrentFocusIndex >= len(ctx.Thoughts) {
+		errs = append(errs, fmt.Errorf("%s: CurrentFocusIndex %d is out of range for %d thoughts", where, ctx.CurrentFocusIndex, len(ctx.Thoughts)))
+	}
+	ids := make(map[int]bool)
+	for j, t := range ctx.Thoughts {
+		if t.Clarity < 0 || t.Clarity > 1 {
+			errs = append(errs, fmt.Errorf("%s: thought[%d] clarity %.2f is outside 0..1", where, j, t.Clarity))
+		}
+		if t.Salience < 0 || t.Salience > 1 {
+			errs = append(errs, fmt.Errorf("%s: thought[%d] salience %.2f is outside 0..1", where, j, t.Salience))
+		}
+		if err := t.Qualia.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: thought[%d] %w", where, j, err))
+		}
+		if t.ID <= 0 || t.ID > ctx.NextThoughtID || ids[t.ID] {
+			errs = append(errs, fmt.Errorf("%s: thought[%d] id %d is invalid or duplicated (next id %d)", where, j, t.ID, ctx.NextThoughtID))
+		}
+		ids[t.ID] = true
+		for _, parent := range t.Parents {
+			if parent <= 0 || parent >= t.ID {
+				errs = append(errs, fmt.Errorf("%s: thought[%d] parent id %d is not an earlier thought", where, j, parent))
+			}
+		}
+	}
+	for j, p := range ctx.Inbox {
+		if p.Clarity < 0 || p.Clarity > 1 {
+			errs = append(errs, fmt.Errorf("%s: inbox[%d] clarity %.2f is outside 0..1", where, j, p.Clarity))
+		}
+		if err := p.Qualia.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: inbox[%d] %w", where, j, err))
+		}
+	}
+	for j, g := range ctx.Goals {
+		if err := g.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: goals[%d]: %w", where, j, err))
+		}
+	}
+	for j, l := range ctx.Links {
+		if l.A >= l.B || !ids[l.A] || !ids[l.B] {
+			errs = append(errs, fmt.Errorf("%s: links[%d] %d-%d is not a pair of thoughts in the mind, lower id first", where, j, l.A, l.B))
+		}
+		if l.Weight <= 0 || l.Weight > 1 {
+			errs = append(errs, fmt.Errorf("%s: links[%d] weight %.2f is outside 0..1", where, j, l.Weight))
+		}
+	}
+	return errs
+}
+
+// saveGame saves the current simulation state to a file.
+func saveGame(filename string, sim *Simulation) error {
+	data, err := encodeSave(sim)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// encodeSave serializes the simulation in the current save format.
+func encodeSave(sim *Simulation) ([]byte, error) {
+	entities := sim.Entities()
+	simulationState := SimulationState{
+		FormatVersion:    CurrentSaveVersion,
+		Entities:         make([]SerializableEntityState, len(entities)),
+		EventLog:         sim.EventLog(),
+		AutoPilotEnabled: sim.Autopilot(),
+		Seed:             sim.seed,
+		Tick:             sim.tick,
+		RNG:              sim.rng,
+		PolicyRNG:        sim.decide,
+		Config:           sim.config,
+	}
+	if sim.rules != DefaultTransitions() {
+		simulationState.Transitions = sim.rules
+	}
+
+	for i, entity := range entities {
+		simulationState.Entities[i] = SerializableEntityState{
+			ID:                  entity.ID,
+			IsPlayer:            entity.IsPlayer,
+			Mind:                entity.Mind, // Revert to direct assignment
+			CurrentFSMStateName: entity.CurrentFSMState.GetName(),
+		}
+		if entity.Policy != nil {
+			simulationState.Entities[i].PolicyName = entity.Policy.Name()
+		}
+		source, err := saveThoughtSource(entity.Mind.ThoughtSource)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entity.ID, err)
+		}
+		simulationState.Entities[i].ThoughtSource = source
+	}
+
+	return json.MarshalIndent(simulationState, "", "  ")
+}
+
+// loadGame loads the simulation state from a file, migrating older formats
+// and rejecting saves that fail validation with a descriptive error.
+// The returned Simulation has no Input or Output; use Restore to load it into
+// a running one.
+func loadGame(filename string) (*Simulation, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sim, err := decodeSave(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return sim, nil
+}
+
+// decodeSave migrates, validates and rebuilds a simulation from save data.
+func decodeSave(data []byte) (*Simulation, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a valid save file: %w", err)
+	}
+	if err := migrateSave(raw); err != nil {
+		return nil, err
+	}
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	simulationState := SimulationState{Config: DefaultConfig()}
+	decoder := json.NewDecoder(bytes.NewReader(migrated))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&simulationState); err != nil {
+		return nil, fmt.Errorf("invalid save: %w", err)
+	}
+	if simulationState.Transitions != nil { // Before validate, which loads behavior trees against it
+		if err := simulationState.Transitions.init(); err != nil {
+			return nil, fmt.Errorf("invalid save: transitions: %w", err)
+		}
+	}
+	if err := simulationState.validate(); err != nil {
+		return nil, fmt.Errorf("invalid save: %w", err)
+	}
+	if simulationState.Config == nil {
+		simulationState.Config = DefaultConfig() // "config": null
+	}
+	if err := simulationState.Config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid save: config: %w", err)
+	}
+
+	entities := make([]*Entity, len(simulationState.Entities))
+	for i, entityState := range simulationState.Entities {
+		state, _ := getStateByName(entityState.CurrentFSMStateName) // Checked by validate
+		entities[i] = &Entity{
+			ID:              entityState.ID,
+			IsPlayer:        entityState.IsPlayer,
+			Mind:            entityState.Mind, // Revert to direct assignment
+			CurrentFSMState: state,
+		}
+		if entityState.PolicyName != "" {
+			entities[i].Policy, _ = getPolicyByName(entityState.PolicyName, simulationState.rules())
+		}
+		entities[i].Mind.ThoughtSource = DefaultThoughtSource()
+		if entityState.ThoughtSource != nil {
+			entities[i].Mind.ThoughtSource, _ = entityState.ThoughtSource.restore() // Checked by validate
+		}
+	}
+
+	sim := NewSimulation(simulationState.Seed, entities)
+	if simulationState.RNG != nil {
+		// Older saves have no RNG state; they continue from a fresh stream.
+		sim.rng = simulationState.RNG
+		sim.attach()
+	}
+	if simulationState.PolicyRNG != nil {
+		sim.decide = simulationState.PolicyRNG
+	}
+	if simulationState.Transitions != nil {
+		sim.SetTransitions(simulationState.Transitions)
+	}
+	sim.SetConfig(simulationState.Config)
+	sim.eventLog = simulationState.EventLog
+	sim.tick = simulationState.Tick
+	return sim, nil
+}
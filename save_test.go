@@ -0,0 +1,204 @@
+// save_test.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// legacySave is a save written before format_version existed.
+const legacySave = `{
+  "entities": [
+    {"id": "Player-1", "is_player": true, "current_fsm_state_name": "Reflecting",
+     "mind": {"Thoughts": ["embodiment shapes perception", "the internal world is vast"],
+              "CurrentFocusIndex": 1, "Clarity": 0.45, "Energy": 60, "MaxEnergy": 100, "ExpressionThreshold": 0.7}},
+    {"id": "AI-Alpha", "is_player": false, "current_fsm_state_name": "Idle",
+     "mind": {"Thoughts": [], "CurrentFocusIndex": -1, "Clarity": 0, "Energy": 70, "MaxEnergy": 100, "ExpressionThreshold": 0.7}}
+  ],
+  "event_log": ["[10:00:00] Player-1 started reflecting."],
+  "auto_pilot_enabled": false
+}`
+
+func writeSave(t *testing.T, contents string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadGame_MigratesLegacySave(t *testing.T) {
+	sim, err := loadGame(writeSave(t, legacySave))
+	if err != nil {
+		t.Fatalf("loadGame legacy: %v", err)
+	}
+	player, ai := sim.Entities()[0], sim.Entities()[1]
+	assertStateType(t, &ReflectingState{}, player.CurrentFSMState)
+	if len(player.Mind.Thoughts) != 2 || player.Mind.Thoughts[1].Text != "the internal world is vast" {
+		t.Fatalf("loadGame legacy: Unexpected thoughts %+v", player.Mind.Thoughts)
+	}
+	if player.Mind.FocusedClarity() != 0.45 {
+		t.Errorf("loadGame legacy: Expected focused thought to inherit clarity 0.45, got %.2f", player.Mind.FocusedClarity())
+	}
+	if player.Mind.Thoughts[0].Clarity != InitialClarity {
+		t.Errorf("loadGame legacy: Expected unfocused thought clarity %.2f, got %.2f", InitialClarity, player.Mind.Thoughts[0].Clarity)
+	}
+	if player.Mind.Thoughts[0].Salience != 1 || player.Mind.SalienceDecay != 0.005 {
+		t.Errorf("loadGame legacy: Expected fully salient thoughts decaying at 0.005, got %.2f and %.3f", player.Mind.Thoughts[0].Salience, player.Mind.SalienceDecay)
+	}
+	if player.Mind.Thoughts[0].Qualia != newQualia(OriginGenerated) {
+		t.Errorf("loadGame legacy: Expected the qualia of a new generated thought, got %+v", player.Mind.Thoughts[0].Qualia)
+	}
+	if player.Policy != nil || ai.Policy == nil {
+		t.Errorf("loadGame legacy: Expected manual player and policy-driven AI")
+	}
+	if log := sim.EventLog(); len(log) != 1 || log[0].Kind != EventNotice || log[0].String() != "[10:00:00] Player-1 started reflecting." {
+		t.Errorf("loadGame legacy: Expected the old event log kept as a notice, got %+v", log)
+	}
+	// A new thought must not reuse a migrated ID.
+	if added := player.Mind.AddThought("new", OriginGenerated); added.ID <= 2 {
+		t.Errorf("loadGame legacy: New thought reused id %d", added.ID)
+	}
+}
+
+func TestLoadGame_RoundTripWritesCurrentVersion(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, NewSimulation(1, NewDefaultEntities())); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	data, _ := os.ReadFile(filename)
+	if !strings.Contains(string(data), fmt.Sprintf(`"format_version": %d`, CurrentSaveVersion)) {
+		t.Errorf("saveGame: Expected format_version in save, got %s", data)
+	}
+	if _, err := loadGame(filename); err != nil {
+		t.Errorf("loadGame round trip: %v", err)
+	}
+}
+
+func TestLoadGame_RejectsInvalidSaves(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(string) string
+		wantErr string
+	}{
+		{"missing mind", func(s string) string {
+			return strings.Replace(s, `"mind": {"Thoughts": [], "CurrentFocusIndex": -1, "Clarity": 0, "Energy": 70, "MaxEnergy": 100, "ExpressionThreshold": 0.7}`, `"mind": null`, 1)
+		}, "AI-Alpha): mind is missing"},
+		{"focus out of range", func(s string) string {
+			return strings.Replace(s, `"CurrentFocusIndex": 1`, `"CurrentFocusIndex": 5`, 1)
+		}, "CurrentFocusIndex 5 is out of range for 2 thoughts"},
+		{"negative energy", func(s string) string {
+			return strings.Replace(s, `"Energy": 60`, `"Energy": -4`, 1)
+		}, "Energy -4 is outside 0..100"},
+		{"unknown state", func(s string) string {
+			return strings.Replace(s, `"Reflecting"`, `"Daydreaming"`, 1)
+		}, `unknown state name "Daydreaming"`},
+		{"newer version", func(s string) string {
+			return strings.Replace(s, `"auto_pilot_enabled": false`, `"auto_pilot_enabled": false, "format_version": 99`, 1)
+		}, "newer than this build supports"},
+		{"unknown field", func(s string) string {
+			return strings.Replace(s, `"auto_pilot_enabled": false`, `"auto_pilot_enabled": false, "mystery": 1`, 1)
+		}, `unknown field "mystery"`},
+		{"no entities", func(s string) string { return `{"entities": []}` }, "save has no entities"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadGame(writeSave(t, tt.edit(legacySave)))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadGame: Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGetStateByName_RoundTripsEveryState(t *testing.T) {
+	for _, state := range []State{&IdleState{}, &ThinkingState{}, &ReflectingState{}, &ActingState{}, &DreamingState{}} {
+		got, err := getStateByName(state.GetName())
+		if err != nil {
+			t.Errorf("getStateByName(%q): %v", state.GetName(), err)
+			continue
+		}
+		assertStateType(t, state, got)
+	}
+}
+
+func TestLoadGame_KeepsLineage(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	mind := sim.Player().Mind
+	a := *mind.AddThought("embodiment shapes perception", OriginGenerated)
+	b := *mind.AddThought("meaning is constructed", OriginGenerated)
+	mind.AddChild("embodiment shapes constructed", a, b)
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	loadedMind := loaded.Player().Mind
+	if got := lineage(loadedMind, loadedMind.Thoughts[2]); got != "#1 + #2" {
+		t.Errorf("loadGame: Expected the child's parents kept, got lineage %q", got)
+	}
+
+	mind.Thoughts[2].Parents[0] = 7
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	if _, err := loadGame(filename); err == nil || !strings.Contains(err.Error(), "parent id 7 is not an earlier thought") {
+		t.Errorf("loadGame: Expected an invalid parent to be rejected, got %v", err)
+	}
+}
+
+func TestLoadGame_KeepsLinks(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	mind := sim.Player().Mind
+	mind.AddThought("embodiment shapes perception", OriginGenerated)
+	mind.AddThought("meaning is constructed", OriginGenerated)
+	mind.link(1, 2, 0.4)
+	mind.LastFocusID = 2
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	if got := loaded.Player().Mind; !slices.Equal(got.Links, mind.Links) || got.LastFocusID != 2 {
+		t.Errorf("loadGame: Expected links %+v and last focus 2, got %+v and %d", mind.Links, got.Links, got.LastFocusID)
+	}
+
+	mind.Links[0].B = 9
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	if _, err := loadGame(filename); err == nil || !strings.Contains(err.Error(), "links[0] 1-9 is not a pair of thoughts") {
+		t.Errorf("loadGame: Expected a link to a missing thought to be rejected, got %v", err)
+	}
+}
+
+func TestMigrateV12ToV13_MovesActivationSpreadPayload(t *testing.T) {
+	save := map[string]any{"event_log": []any{
+		map[string]any{"kind": string(EventActivationSpread), "index": 2.0, "new_value": 0.15},
+		map[string]any{"kind": string(EventFocused), "index": 1.0},
+	}}
+	if err := migrateV12ToV13(save); err != nil {
+		t.Fatalf("migrateV12ToV13: %v", err)
+	}
+	log := save["event_log"].([]any)
+	spread, focus := log[0].(map[string]any), log[1].(map[string]any)
+	if spread["count"] != 2.0 || spread["amount"] != 0.15 || spread["index"] != nil || spread["new_value"] != nil {
+		t.Errorf("migrateV12ToV13: Expected count 2 and amount 0.15, got %+v", spread)
+	}
+	if focus["index"] != 1.0 {
+		t.Errorf("migrateV12ToV13: Expected other events left alone, got %+v", focus)
+	}
+}
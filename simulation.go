@@ -0,0 +1,288 @@
+// simulation.go
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+const MAX_EVENT_LOG_SIZE = 7 // Number of recent events to keep for display
+
+// Simulation owns the entities, the event log, the RNG and the tick counter.
+// It knows nothing about terminals; the REPL and the dashboard drive it through
+// Step and Run, and other tools can embed it directly.
+type Simulation struct {
+	entities []*Entity
+	eventLog []Event
+	seed     int64
+	rng      *RNG // Cognition: shared by every entity's mind and by perception
+	decide   *RNG // Decisions: handed to policies, kept apart so replays can skip them
+	rules    *TransitionTable
+	config   *Config
+	env      *Environment // Kept across Restore, like Input and Output
+	tick     int
+	ctx      context.Context // Of the Run in progress, handed to each command
+
+	// Input supplies commands for entities without a Policy (the player when
+	// autopilot is off). Returning no parts skips the entity's turn.
+	// If Input is nil, such entities simply skip their turns.
+	Input func(e *Entity) ([]string, error)
+
+	// Output receives every turn and tick for presentation. Defaults to
+	// DiscardOutput.
+	Output Output
+
+	// Bus carries every logged event to its subscribers. The recent-events
+	// log is itself one of them.
+	Bus *EventBus
+
+	generation int // Bumped by Restore so an in-flight Step can stop early
+}
+
+// policySeedSalt derives the decision stream's seed from the simulation seed.
+const policySeedSalt = 0x5bd1e995
+
+// NewSimulation creates a simulation over the given entities. Two simulations
+// created with the same seed and fed the same player input run identically.
+func NewSimulation(seed int64, entities []*Entity) *Simulation {
+	s := &Simulation{
+		entities: entities,
+		eventLog: make([]Event, 0, MAX_EVENT_LOG_SIZE),
+		seed:     seed,
+		rng:      NewRNG(seed),
+		decide:   NewRNG(seed ^ policySeedSalt),
+		rules:    DefaultTransitions(),
+		config:   DefaultConfig(),
+		env:      NewEnvironment(),
+		Output:   DiscardOutput{},
+		Bus:      NewEventBus(),
+	}
+	s.Bus.Subscribe(s.record)
+	s.attach()
+	return s
+}
+
+// attach points every entity's mind at the simulation's random source,
+// transition table, config and environment.
+func (s *Simulation) attach() {
+	for _, entity := range s.entities {
+		entity.Mind.Rand = s.rng
+		entity.Mind.Rules = s.rules
+		entity.Mind.Config = s.config
+		entity.Mind.Environment = s.env
+	}
+}
+
+// NewDefaultEntities returns the standard Player-1 / AI-Alpha pairing with
+// the default config.
+func NewDefaultEntities() []*Entity {
+	entities, _ := NewEntities(DefaultConfig(), DefaultTransitions(), []string{DefaultPolicyName})
+	return entities
+}
+
+// aiNames names the AI entities in the order they are created.
+var aiNames = []string{"Alpha", "Beta", "Gamma", "Delta", "Epsilon", "Zeta", "Eta", "Theta"}
+
+// NewEntities returns Player-1 followed by one AI entity per named policy,
+// so different brains can be compared side by side. Minds start out as cfg
+// says, and behavior trees are checked against rules.
+func NewEntities(cfg *Config, rules *TransitionTable, aiPolicies []string) ([]*Entity, error) {
+	entities := []*Entity{
+		{ID: "Player-1", IsPlayer: true, Mind: NewMindContext(cfg), CurrentFSMState: &IdleState{}},
+	}
+	for i, name := range aiPolicies {
+		policy, err := getPolicyByName(name, rules)
+		if err != nil {
+			return nil, err
+		}
+		id := fmt.Sprintf("AI-%d", i+1)
+		if i < len(aiNames) {
+			id = "AI-" + aiNames[i]
+		}
+		entities = append(entities, &Entity{ID: id, Mind: NewMindContext(cfg), CurrentFSMState: &IdleState{}, Policy: policy})
+	}
+	return entities, nil
+}
+
+// Entities returns the entities taking part in the simulation.
+func (s *Simulation) Entities() []*Entity { return s.entities }
+
+// EventLog returns the most recent events, oldest first.
+func (s *Simulation) EventLog() []Event { return s.eventLog }
+
+// Tick returns the number of completed or in-progress ticks.
+func (s *Simulation) Tick() int { return s.tick }
+
+// Seed returns the seed the simulation was originally created with.
+func (s *Simulation) Seed() int64 { return s.seed }
+
+// Transitions returns the transition table every entity's states consult.
+func (s *Simulation) Transitions() *TransitionTable { return s.rules }
+
+// SetTransitions replaces the transition table for every entity.
+func (s *Simulation) SetTransitions(t *TransitionTable) {
+	s.rules = t
+	s.attach()
+}
+
+// Config returns the parameters the simulation runs under.
+func (s *Simulation) Config() *Config { return s.config }
+
+// SetConfig replaces the parameters for every entity. Starting energy and
+// threshold only apply to minds created with the config; see NewEntities.
+func (s *Simulation) SetConfig(cfg *Config) {
+	s.config = cfg
+	s.attach()
+}
+
+// Environment returns what entities in the Perceiving state observe.
+func (s *Simulation) Environment() *Environment { return s.env }
+
+// SetEnvironment replaces the environment for every entity. Unlike the config
+// it is not saved: streams are outside the simulation.
+func (s *Simulation) SetEnvironment(env *Environment) {
+	s.env = env
+	s.attach()
+}
+
+// Player returns the first player entity, or nil if there is none.
+func (s *Simulation) Player() *Entity {
+	for _, e := range s.entities {
+		if e.IsPlayer {
+			return e
+		}
+	}
+	return nil
+}
+
+// Restore replaces the simulation contents with those of loaded, e.g. a game
+// read by loadGame, keeping the current Input, Output, environment and Bus
+// subscribers. A Step in progress stops after the current turn.
+func (s *Simulation) Restore(loaded *Simulation) {
+	s.entities = loaded.entities
+	s.eventLog = loaded.eventLog
+	s.seed = loaded.seed
+	s.rng = loaded.rng
+	s.decide = loaded.decide
+	s.rules = loaded.rules
+	s.config = loaded.config
+	s.tick = loaded.tick
+	s.attach()
+	s.generation++
+}
+
+// LogEvent stamps an event with the current tick and publishes it on the bus.
+func (s *Simulation) LogEvent(event Event) {
+	event.Tick = s.tick
+	s.Bus.Publish(event)
+}
+
+// record keeps the most recent events for the dashboard and save files.
+func (s *Simulation) record(event Event) {
+	s.eventLog = append(s.eventLog, event)
+	if len(s.eventLog) > MAX_EVENT_LOG_SIZE {
+		s.eventLog = s.eventLog[len(s.eventLog)-MAX_EVENT_LOG_SIZE:]
+	}
+}
+
+// Autopilot reports whether the player is currently driven by a policy.
+func (s *Simulation) Autopilot() bool {
+	player := s.Player()
+	return player != nil && player.Policy != nil
+}
+
+// SetAutopilot hands the player entities over to the default policy, or back
+// to Input when turned off. Autopilot is just another policy assignment.
+func (s *Simulation) SetAutopilot(on bool) {
+	for _, e := range s.entities {
+		if !e.IsPlayer {
+			continue
+		}
+		e.Policy = nil
+		if on {
+			e.Policy, _ = getPolicyByName(DefaultPolicyName, s.rules)
+		}
+	}
+}
+
+// Step runs a single tick: every entity regenerates, forgets what has faded,
+// settles its feelings, picks a command and applies it. An error from Input
+// aborts the tick and is returned.
+func (s *Simulation) Step() error {
+	s.tick++
+	generation := s.generation
+	for _, entity := range s.entities {
+		// Passive energy regeneration for all entities
+		entity.Mind.Energy = min(entity.Mind.Energy+s.config.EnergyRegen, entity.Mind.MaxEnergy)
+		passive := append(entity.Mind.decay(entity.ID), entity.Mind.settle(entity.ID)...)
+		for i := range passive {
+			passive[i].Tick = s.tick
+			s.LogEvent(passive[i])
+		}
+
+		from := entity.CurrentFSMState
+		var parts []string
+		if entity.Policy != nil {
+			parts = entity.Policy.Decide(entity.View(), s.decide).Parts()
+		} else if s.Input != nil {
+			var err error
+			parts, err = s.Input(entity)
+			if err != nil {
+				return err
+			}
+			if s.generation != generation {
+				return nil // Input replaced the simulation (e.g. load); start afresh next tick
+			}
+		}
+
+		var events []Event
+		if len(parts) > 0 {
+			events = s.Apply(entity, parts)
+		} else if !entity.IsPlayer {
+			s.LogEvent(Event{Kind: EventNoAction, Entity: entity.ID})
+		}
+		s.Output.Turn(Turn{Tick: s.tick, Entity: entity, From: from, Parts: parts, Events: append(passive, events...)})
+	}
+	s.Output.TickDone(s)
+	return nil
+}
+
+// Apply feeds a command to an entity's current state, evicts thoughts beyond
+// its working memory, reviews its goals, lets the entity feel what happened,
+// records the resulting events and returns them, stamped with the current
+// tick.
+func (s *Simulation) Apply(entity *Entity, parts []string) []Event {
+	entity.Mind.Tick = s.tick
+	entity.Mind.Context = s.ctx
+	if s.ctx == nil {
+		entity.Mind.Context = context.Background()
+	}
+	newState, events := entity.CurrentFSMState.HandleInput(entity.ID, entity.Mind, parts)
+	entity.CurrentFSMState = newState
+	events = append(events, entity.Mind.evict(entity.ID)...)
+	events = append(events, entity.Mind.review(entity.ID, events)...)
+	events = append(events, entity.Mind.feel(entity.ID, events)...)
+	events = append(events, s.broadcast(entity)...)
+	for i := range events {
+		events[i].Tick = s.tick
+		s.LogEvent(events[i])
+	}
+	return events
+}
+
+// Run steps the simulation n times, or until ctx is cancelled when n <= 0.
+// Cancelling ctx also cuts short a command waiting on slow work, such as an
+// LLM call.
+func (s *Simulation) Run(ctx context.Context, n int) error {
+	s.ctx = ctx
+	defer func() { s.ctx = nil }()
+	for i := 0; n <= 0 || i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
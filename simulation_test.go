@@ -0,0 +1,238 @@
+// simulation_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimulation_StepWithoutTerminal(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	sim.SetAutopilot(true)
+
+	for i := 0; i < 20; i++ {
+		if err := sim.Step(); err != nil {
+			t.Fatalf("Step %d returned error: %v", i, err)
+		}
+	}
+	if sim.Tick() != 20 {
+		t.Errorf("Simulation Step: Expected tick 20, got %d", sim.Tick())
+	}
+	if len(sim.EventLog()) == 0 {
+		t.Errorf("Simulation Step: Expected events to be logged after 20 ticks")
+	}
+}
+
+func TestSimulation_ManualEntityUsesInput(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	player := sim.Player()
+	sim.Input = func(e *Entity) ([]string, error) {
+		if e != player {
+			t.Errorf("Simulation Input: called for non-manual entity %s", e.ID)
+		}
+		return []string{"think"}, nil
+	}
+
+	if err := sim.Step(); err != nil {
+		t.Fatalf("Step returned error: %v", err)
+	}
+	assertStateType(t, &ThinkingState{}, player.CurrentFSMState)
+}
+
+func TestSimulation_InputErrorStopsRun(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	stop := errors.New("stop")
+	sim.Input = func(e *Entity) ([]string, error) { return nil, stop }
+
+	if err := sim.Run(context.Background(), 5); !errors.Is(err, stop) {
+		t.Errorf("Simulation Run: Expected input error, got %v", err)
+	}
+	if sim.Tick() != 1 {
+		t.Errorf("Simulation Run: Expected to stop after tick 1, got %d", sim.Tick())
+	}
+}
+
+func TestSimulation_RunHonoursContext(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sim.Run(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Simulation Run: Expected context.Canceled, got %v", err)
+	}
+	if sim.Tick() != 0 {
+		t.Errorf("Simulation Run: Expected no ticks after cancellation, got %d", sim.Tick())
+	}
+}
+
+func TestSimulation_TextOutputCapturesTurns(t *testing.T) {
+	sim := NewSimulation(1, NewDefaultEntities())
+	var buf bytes.Buffer
+	sim.Output = &TextOutput{W: &buf}
+	sim.Input = func(e *Entity) ([]string, error) { return []string{"recharge"}, nil }
+
+	if err := sim.Step(); err != nil {
+		t.Fatalf("Step returned error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Player-1 recharged.") {
+		t.Errorf("TextOutput: Expected player recharge event in output, got %q", out)
+	}
+	if !strings.Contains(out, "--- AI Entity AI-Alpha's turn (Idle) ---") {
+		t.Errorf("TextOutput: Expected AI turn header in output, got %q", out)
+	}
+}
+
+// mindsJSON snapshots every entity's state for comparison between runs.
+func mindsJSON(t *testing.T, sim *Simulation) string {
+	t.Helper()
+	var b strings.Builder
+	for _, e := range sim.Entities() {
+		data, err := json.Marshal(e.Mind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", e.ID, e.CurrentFSMState.GetName(), data)
+	}
+	return b.String()
+}
+
+func TestSimulation_SameSeedSameRun(t *testing.T) {
+	a := NewSimulation(42, NewDefaultEntities())
+	b := NewSimulation(42, NewDefaultEntities())
+	a.SetAutopilot(true)
+	b.SetAutopilot(true)
+
+	for i := 0; i < 200; i++ {
+		a.Step()
+		b.Step()
+	}
+	if mindsJSON(t, a) != mindsJSON(t, b) {
+		t.Errorf("Simulations with the same seed diverged:\n%s\nvs\n%s", mindsJSON(t, a), mindsJSON(t, b))
+	}
+}
+
+func TestSimulation_LoadedGameContinuesIdentically(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "save.json")
+	original := NewSimulation(7, NewDefaultEntities())
+	original.SetAutopilot(true)
+	original.Run(context.Background(), 50)
+
+	if err := saveGame(filename, original); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	if loaded.Tick() != 50 || loaded.Seed() != 7 {
+		t.Errorf("loadGame: Expected tick 50 and seed 7, got tick %d and seed %d", loaded.Tick(), loaded.Seed())
+	}
+
+	original.Run(context.Background(), 100)
+	loaded.Run(context.Background(), 100)
+	if mindsJSON(t, original) != mindsJSON(t, loaded) {
+		t.Errorf("Loaded game diverged from the original:\n%s\nvs\n%s", mindsJSON(t, original), mindsJSON(t, loaded))
+	}
+}
+
+func TestSimulation_ExpressionReachesOtherEntities(t *testing.T) {
+	sim := NewSimulation(3, NewDefaultEntities())
+	player, ai := sim.Entities()[0], sim.Entities()[1]
+	player.CurrentFSMState = &ActingState{}
+	player.Mind.AddThought("embodiment shapes perception", OriginGenerated).Clarity = 1.0
+	player.Mind.CurrentFocusIndex = 0
+
+	sim.Apply(player, []string{"express"})
+
+	if len(ai.Mind.Inbox) != 1 {
+		t.Fatalf("Expected AI to hear one expression, got %d", len(ai.Mind.Inbox))
+	}
+	heard := ai.Mind.Inbox[0]
+	if heard.From != player.ID || heard.Text != "embodiment shapes perception" || heard.Clarity != 1.0 {
+		t.Errorf("Fully clear expression should arrive intact, got %+v", heard)
+	}
+	if len(player.Mind.Inbox) != 0 || len(player.Mind.Outbox) != 0 {
+		t.Errorf("Expresser should not hear itself and its outbox should be drained")
+	}
+}
+
+func TestPerceive_LossyWithLowClarity(t *testing.T) {
+	rng := NewRNG(5)
+	expr := Expression{Text: "meaning is constructed not inherent at all", Clarity: 0.5}
+	for i := 0; i < 20; i++ {
+		p := perceive("speaker", expr, 1, DefaultConfig(), rng)
+		if p.Clarity > expr.Clarity {
+			t.Fatalf("perceive: Perceived clarity %.2f exceeds expressed clarity %.2f", p.Clarity, expr.Clarity)
+		}
+		if len(strings.Fields(p.Text)) != len(strings.Fields(expr.Text)) {
+			t.Fatalf("perceive: Expected lost words to be marked, got %q", p.Text)
+		}
+	}
+}
+
+// scriptedPolicy replays a fixed list of commands, then does nothing.
+type scriptedPolicy struct{ commands []Command }
+
+func (p *scriptedPolicy) Name() string { return "scripted" }
+func (p *scriptedPolicy) Decide(view EntityView, rng *RNG) Command {
+	if len(p.commands) == 0 {
+		return Command{}
+	}
+	c := p.commands[0]
+	p.commands = p.commands[1:]
+	return c
+}
+
+func TestSimulation_EachEntityUsesItsOwnPolicy(t *testing.T) {
+	entities := NewDefaultEntities()
+	entities[1].Policy = &scriptedPolicy{commands: []Command{NewCommand("think"), NewCommand("generate")}}
+	sim := NewSimulation(1, entities)
+
+	sim.Run(context.Background(), 2)
+	ai := entities[1]
+	assertStateType(t, &ThinkingState{}, ai.CurrentFSMState)
+	if len(ai.Mind.Thoughts) != 1 {
+		t.Errorf("Scripted policy: Expected 1 thought, got %d", len(ai.Mind.Thoughts))
+	}
+	if entities[0].Policy != nil || sim.Autopilot() {
+		t.Errorf("Player should stay manual unless autopilot is set")
+	}
+}
+
+func TestRandomHeuristicPolicy_RechargesWhenLow(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	entity.Mind.Energy = 10
+	policy := &RandomHeuristicPolicy{}
+
+	if got := policy.Decide(entity.View(), NewRNG(1)); got.String() != "recharge" {
+		t.Errorf("RandomHeuristicPolicy: Expected recharge at low energy in Idle, got %q", got)
+	}
+}
+
+func TestRandomHeuristicPolicy_WakesWhenRested(t *testing.T) {
+	entity := NewDefaultEntities()[1]
+	entity.CurrentFSMState = &DreamingState{}
+	policy := &RandomHeuristicPolicy{}
+
+	entity.Mind.Energy = 50
+	if got := policy.Decide(entity.View(), NewRNG(1)); got.String() != "dream" {
+		t.Errorf("RandomHeuristicPolicy: Expected to keep dreaming at half energy, got %q", got)
+	}
+	entity.Mind.Energy = 90
+	if got := policy.Decide(entity.View(), NewRNG(1)); got.String() != "wake" {
+		t.Errorf("RandomHeuristicPolicy: Expected to wake when rested, got %q", got)
+	}
+}
+
+func TestNewEntities_UnknownPolicy(t *testing.T) {
+	if _, err := NewEntities(DefaultConfig(), DefaultTransitions(), []string{"random", "nonexistent"}); err == nil {
+		t.Errorf("NewEntities: Expected an error for an unknown policy")
+	}
+}
@@ -2,8 +2,8 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"math/rand"
 	"strconv"
 	"strings"
 )
@@ -11,23 +11,46 @@ import (
 // MindContext holds the internal state of an entity's mind.
 // CurrentStateName has been removed as the Entity will hold its current State object.
 type MindContext struct {
-	Thoughts            []string
-	CurrentFocusIndex   int     // -1 if no focus
-	Clarity             float64 // 0.0 to 1.0 for the focused thought
+	Thoughts            []Thought
+	NextThoughtID       int
+	CurrentFocusIndex   int // -1 if no focus
 	Energy              int
 	MaxEnergy           int
 	ExpressionThreshold float64
+	Affect              Affect           // How the mind feels; see affect.go
+	SalienceDecay       float64          // Salience each unfocused thought loses per tick
+	WorkingMemory       int              // Most thoughts held at once; 0 for no limit
+	Inbox               []Perception     // Expressions heard from other entities, awaiting accept/ignore/integrate
+	Links               []Link           // Associations between thoughts; see graph.go
+	LastFocusID         int              // Thought focused most recently, linked to the next one focused
+	LastHeardID         int              // Thought heard most recently, linked to the next one heard
+	Goals               []Goal           // What the mind is working towards, first things first; see goal.go
+	Outbox              []Expression     `json:"-"` // Expressed this turn; drained by the Simulation
+	Rand                *RNG             `json:"-"` // Shared with the Simulation; see Simulation.attach
+	Rules               *TransitionTable `json:"-"` // Shared with the Simulation, like Rand
+	Config              *Config          `json:"-"` // Tuning parameters, shared with the Simulation
+	Environment         *Environment     `json:"-"` // What the Perceiving state observes, shared with the Simulation
+	ThoughtSource       ThoughtSource    `json:"-"` // This mind's own; saved separately, see SerializableEntityState
+	Tick                int              `json:"-"` // Current simulation tick, set before each command
+	Context             context.Context  `json:"-"` // Of the run, set like Tick; cancelling it cuts slow work such as LLM calls short
 }
 
-// NewMindContext creates and initializes a new MindContext.
-func NewMindContext() *MindContext {
+// NewMindContext creates and initializes a new MindContext with cfg's
+// starting energy and threshold.
+func NewMindContext(cfg *Config) *MindContext {
 	return &MindContext{
-		Thoughts:            make([]string, 0),
+		Thoughts:            make([]Thought, 0),
 		CurrentFocusIndex:   -1,
-		Clarity:             0,
-		Energy:              70,
-		MaxEnergy:           100,
-		ExpressionThreshold: 0.7,
+		Energy:              cfg.InitialEnergy,
+		MaxEnergy:           cfg.MaxEnergy,
+		ExpressionThreshold: cfg.ExpressionThreshold,
+		SalienceDecay:       cfg.SalienceDecay,
+		WorkingMemory:       cfg.WorkingMemory,
+		Rand:                NewRNG(randomSeed()),
+		Rules:               DefaultTransitions(),
+		Config:              cfg,
+		Environment:         NewEnvironment(),
+		ThoughtSource:       DefaultThoughtSource(),
 	}
 }
 
@@ -37,68 +60,52 @@ type Entity struct {
 	IsPlayer        bool
 	Mind            *MindContext
 	CurrentFSMState State
+	Policy          Policy // Decides for the entity each tick; nil means it is driven by Simulation.Input
 }
 
 // State defines the interface for all cognitive states.
 type State interface {
-	HandleInput(entityID string, context *MindContext, parts []string) (State, []string)
+	HandleInput(entityID string, context *MindContext, parts []string) (State, []Event)
 	GetName() string
 	GetPrompt(entity *Entity) string
 }
 
+// prompt formats the parts of a prompt every state shares: who and where the
+// entity is, its energy, any state-specific details, and the commands the
+// transition table offers in its state.
+func prompt(entity *Entity, state State, details string) string {
+	commands := append(entity.Mind.Rules.Commands(state.GetName()), "view", "quit")
+	return fmt.Sprintf("Entity %s (%s) | Energy: %d/%d%s | Commands: [%s]", entity.ID, state.GetName(), entity.Mind.Energy, entity.Mind.MaxEnergy, details, strings.Join(commands, " | "))
+}
+
+// focusDetails describes the focused thought for a prompt.
+func focusDetails(ctx *MindContext) string {
+	if focused := ctx.Focused(); focused != nil {
+		return fmt.Sprintf(" | Focus: '%s' (Clarity: %.2f)", focused.Text, focused.Clarity)
+	}
+	return " | Focus: None"
+}
+
 // --- IdleState ---
 type IdleState struct{}
 
 func (s *IdleState) GetName() string { return "Idle" }
 func (s *IdleState) GetPrompt(entity *Entity) string {
-	return fmt.Sprintf("Entity %s (Idle) | Energy: %d/%d | Commands: [think | reflect | act | recharge | view | quit]", entity.ID, entity.Mind.Energy, entity.Mind.MaxEnergy)
+	return prompt(entity, s, "")
 }
 
-func (s *IdleState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []string) {
-	command := parts[0]
-	var events []string
+func (s *IdleState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	return ctx.Rules.Apply(s, entityID, ctx, parts)
+}
 
-	switch command {
-	case "think":
-		if ctx.Energy >= 5 { // Assuming a small cost to transition
-			ctx.Energy -= 5
-			events = append(events, fmt.Sprintf("%s started thinking.", entityID))
-			return &ThinkingState{}, events
-		} else {
-			events = append(events, fmt.Sprintf("%s has not enough energy to start thinking.", entityID))
-			fmt.Println("Not enough energy to transition to Thinking. Energy: ", ctx.Energy)
-		}
-	case "reflect":
-		if ctx.Energy >= 5 {
-			ctx.Energy -= 5
-			events = append(events, fmt.Sprintf("%s started reflecting.", entityID))
-			return &ReflectingState{}, events
-		} else {
-			events = append(events, fmt.Sprintf("%s has not enough energy to start reflecting.", entityID))
-			fmt.Println("Not enough energy to transition to Reflecting. Energy: ", ctx.Energy)
-		}
-	case "act":
-		if ctx.Energy >= 5 {
-			ctx.Energy -= 5
-			events = append(events, fmt.Sprintf("%s prepared to act.", entityID))
-			return &ActingState{}, events
-		} else {
-			events = append(events, fmt.Sprintf("%s has not enough energy to prepare to act.", entityID))
-			fmt.Println("Not enough energy to transition to Acting. Energy: ", ctx.Energy)
-		}
-	case "recharge":
-		oldEnergy := ctx.Energy
-		ctx.Energy += 25
-		if ctx.Energy > ctx.MaxEnergy {
-			ctx.Energy = ctx.MaxEnergy
-		}
-		events = append(events, fmt.Sprintf("%s recharged. Energy %d -> %d.", entityID, oldEnergy, ctx.Energy))
-		fmt.Printf("Energy recharged. Current energy: %d\n", ctx.Energy)
-	default:
-		fmt.Println("Unknown command in Idle state.")
-		events = append(events, fmt.Sprintf("%s tried unknown command '%s' in Idle.", entityID, command))
+// rechargeEffect restores energy, up to MaxEnergy.
+func rechargeEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	oldEnergy := ctx.Energy
+	ctx.Energy += ctx.Config.RechargeAmount
+	if ctx.Energy > ctx.MaxEnergy {
+		ctx.Energy = ctx.MaxEnergy
 	}
-	return s, events
+	return []Event{{Kind: EventRecharged, Entity: entityID, OldValue: float64(oldEnergy), NewValue: float64(ctx.Energy)}}, true
 }
 
 // --- ThinkingState ---
@@ -106,12 +113,14 @@ type ThinkingState struct{}
 
 func (s *ThinkingState) GetName() string { return "Thinking" }
 func (s *ThinkingState) GetPrompt(entity *Entity) string {
-	prompt := fmt.Sprintf("Entity %s (Thinking) | Energy: %d/%d | Thoughts: %d", entity.ID, entity.Mind.Energy, entity.Mind.MaxEnergy, len(entity.Mind.Thoughts))
-	if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.CurrentFocusIndex < len(entity.Mind.Thoughts) {
-		prompt += fmt.Sprintf(" | Focus: '%s' (Clarity: %.2f)", entity.Mind.Thoughts[entity.Mind.CurrentFocusIndex], entity.Mind.Clarity)
+	details := fmt.Sprintf(" | Thoughts: %d", len(entity.Mind.Thoughts))
+	if focused := entity.Mind.Focused(); focused != nil {
+		details += fmt.Sprintf(" | Focus: '%s' (Clarity: %.2f)", focused.Text, focused.Clarity)
+	}
+	if len(entity.Mind.Inbox) > 0 {
+		details += fmt.Sprintf(" | Heard: %d", len(entity.Mind.Inbox))
 	}
-	prompt += " | Commands: [generate | focus <index> | idle | view | quit]"
-	return prompt
+	return prompt(entity, s, details)
 }
 
 var potentialThoughts = []string{
@@ -123,58 +132,105 @@ var potentialThoughts = []string{
 	"externalization is a lossy process",
 }
 
-func (s *ThinkingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []string) {
-	command := parts[0]
-	var events []string
+func (s *ThinkingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	return ctx.Rules.Apply(s, entityID, ctx, parts)
+}
+
+// generateEffect adds a new thought from the mind's thought source.
+func generateEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	newThought := ctx.AddThought(ctx.ThoughtSource.Generate(ctx), OriginGenerated)
+	return []Event{{Kind: EventThoughtGenerated, Entity: entityID, ThoughtID: newThought.ID, Thought: newThought.Text}}, true
+}
 
-	if ctx.Energy < 10 && command != "idle" {
-		fmt.Println("Not enough energy to think. Try 'idle' then 'recharge'.")
-		events = append(events, fmt.Sprintf("%s has low energy for thinking actions.", entityID))
-		return s, events
+// focusEffect focuses on the thought at args[0].
+func focusEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 || index >= len(ctx.Thoughts) {
+		return []Event{invalid(entityID, "focus", fmt.Sprintf("invalid index '%s'", args[0]))}, false
 	}
+	ctx.CurrentFocusIndex = index // Clarity stays with the thought, so refocusing resumes earlier work
+	ctx.Thoughts[index].refresh()
+	t := ctx.Thoughts[index]
+	if ctx.ThoughtByID(ctx.LastFocusID) != nil {
+		ctx.link(t.ID, ctx.LastFocusID, ctx.Config.LinkFocusWeight)
+	}
+	ctx.LastFocusID = t.ID
+	return []Event{{Kind: EventFocused, Entity: entityID, Index: index, ThoughtID: t.ID, Thought: t.Text, Clarity: t.Clarity}}, true
+}
 
-	switch command {
-	case "generate":
-		if ctx.Energy >= 10 {
-			ctx.Energy -= 10
-			newThought := potentialThoughts[rand.Intn(len(potentialThoughts))]
-			ctx.Thoughts = append(ctx.Thoughts, newThought)
-			events = append(events, fmt.Sprintf("%s generated thought: '%s'.", entityID, newThought))
-			fmt.Printf("New thought generated: '%s'. Energy: %d\n", newThought, ctx.Energy)
-		} else {
-			events = append(events, fmt.Sprintf("%s failed to generate thought (low energy).", entityID))
-			fmt.Println("Not enough energy to generate a new thought.")
-		}
-	case "focus":
-		if len(parts) < 2 {
-			fmt.Println("Please specify the index of the thought to focus on.")
-			events = append(events, fmt.Sprintf("%s tried to focus without specifying index.", entityID))
-			break
-		}
-		index, err := strconv.Atoi(parts[1])
-		if err != nil || index < 0 || index >= len(ctx.Thoughts) {
-			fmt.Println("Invalid thought index.")
-			events = append(events, fmt.Sprintf("%s tried to focus on invalid index '%s'.", entityID, parts[1]))
-			break
-		}
-		if ctx.Energy >= 5 {
-			ctx.Energy -= 5
-			ctx.CurrentFocusIndex = index
-			ctx.Clarity = 0.1 // Initial low clarity for a newly focused thought
-			events = append(events, fmt.Sprintf("%s focused on thought [%d]: '%s'. Clarity reset to %.1f.", entityID, index, ctx.Thoughts[index], ctx.Clarity))
-			fmt.Printf("Focused on thought: '%s'. Clarity: %.2f. Energy: %d\n", ctx.Thoughts[index], ctx.Clarity, ctx.Energy)
-		} else {
-			events = append(events, fmt.Sprintf("%s failed to focus (low energy).", entityID))
-			fmt.Println("Not enough energy to focus.")
-		}
-	case "idle":
-		events = append(events, fmt.Sprintf("%s transitioned to Idle from Thinking.", entityID))
-		return &IdleState{}, events
-	default:
-		fmt.Println("Unknown command in Thinking state.")
-		events = append(events, fmt.Sprintf("%s tried unknown command '%s' in Thinking.", entityID, command))
+// takePerception removes and returns the perception at args[0] from the
+// inbox, or reports why it can't.
+func takePerception(entityID, command string, ctx *MindContext, args []string) (Perception, []Event, bool) {
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 || index >= len(ctx.Inbox) {
+		return Perception{}, []Event{invalid(entityID, command, fmt.Sprintf("invalid perception '%s'", args[0]))}, false
+	}
+	p := ctx.Inbox[index]
+	ctx.Inbox = append(ctx.Inbox[:index], ctx.Inbox[index+1:]...)
+	return p, nil, true
+}
+
+// acceptEffect adopts a perception as a heard thought.
+func acceptEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	p, events, ok := takePerception(entityID, "accept", ctx, args)
+	if !ok {
+		return events, false
+	}
+	thought := ctx.AddHeard(p)
+	return []Event{{Kind: EventPerceptionAccepted, Entity: entityID, From: p.From, ThoughtID: thought.ID, Thought: p.Text, Clarity: thought.Clarity}}, true
+}
+
+// ignoreEffect drops a perception.
+func ignoreEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	p, events, ok := takePerception(entityID, "ignore", ctx, args)
+	if !ok {
+		return events, false
+	}
+	return []Event{{Kind: EventPerceptionIgnored, Entity: entityID, From: p.From, Thought: p.Text}}, true
+}
+
+// integrateEffect folds a perception into the focused thought, corroborating it.
+func integrateEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	p, events, ok := takePerception(entityID, "integrate", ctx, args)
+	if !ok {
+		return events, false
+	}
+	focused := ctx.Focused()
+	focused.Clarity += p.Clarity * ctx.Config.IntegrateClarityGain
+	if focused.Clarity > 1.0 {
+		focused.Clarity = 1.0
 	}
-	return s, events
+	return []Event{{Kind: EventPerceptionIntegrated, Entity: entityID, From: p.From, Thought: p.Text, To: focused.Text, ThoughtID: focused.ID, Clarity: focused.Clarity}}, true
+}
+
+// combineEffect merges two thoughts into a child that records both as its
+// parents and starts at their mean clarity: combine <i> <j> [keep]. The
+// parents are consumed unless "keep" is given; if one was focused, the focus
+// passes to the child.
+func combineEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	i, errI := strconv.Atoi(args[0])
+	j, errJ := strconv.Atoi(args[1])
+	if errI != nil || errJ != nil || i < 0 || j < 0 || i >= len(ctx.Thoughts) || j >= len(ctx.Thoughts) || i == j {
+		return []Event{invalid(entityID, "combine", fmt.Sprintf("invalid indices '%s' and '%s'", args[0], args[1]))}, false
+	}
+	keep := len(args) > 2 && args[2] == "keep"
+	if len(args) > 2 && !keep {
+		return []Event{invalid(entityID, "combine", "usage is combine <i> <j> [keep]")}, false
+	}
+
+	a, b := ctx.Thoughts[i], ctx.Thoughts[j]
+	focusPasses := !keep && (ctx.CurrentFocusIndex == i || ctx.CurrentFocusIndex == j)
+	child := ctx.AddChild(recombine(a.Text, b.Text), a, b) // While the parents are there to pass on their links
+	child.Clarity = (a.Clarity + b.Clarity) / 2
+	event := Event{Kind: EventThoughtCombined, Entity: entityID, ThoughtID: child.ID, Thought: child.Text, Clarity: child.Clarity, From: a.Text, To: b.Text}
+	if !keep {
+		ctx.removeThought(max(i, j))
+		ctx.removeThought(min(i, j))
+	}
+	if focusPasses {
+		ctx.CurrentFocusIndex = len(ctx.Thoughts) - 1
+	}
+	return []Event{event}, true
 }
 
 // --- ReflectingState ---
@@ -182,65 +238,43 @@ type ReflectingState struct{}
 
 func (s *ReflectingState) GetName() string { return "Reflecting" }
 func (s *ReflectingState) GetPrompt(entity *Entity) string {
-	prompt := fmt.Sprintf("Entity %s (Reflecting) | Energy: %d/%d", entity.ID, entity.Mind.Energy, entity.Mind.MaxEnergy)
-	if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.CurrentFocusIndex < len(entity.Mind.Thoughts) {
-		prompt += fmt.Sprintf(" | Focus: '%s' (Clarity: %.2f)", entity.Mind.Thoughts[entity.Mind.CurrentFocusIndex], entity.Mind.Clarity)
-	} else {
-		prompt += " | Focus: None"
-	}
-	prompt += " | Commands: [introspect | unfocus | idle | view | quit]"
-	return prompt
+	return prompt(entity, s, focusDetails(entity.Mind))
 }
 
-func (s *ReflectingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []string) {
-	command := parts[0]
-	var events []string
+func (s *ReflectingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	return ctx.Rules.Apply(s, entityID, ctx, parts)
+}
 
-	if ctx.Energy < 15 && command == "introspect" {
-		fmt.Println("Not enough energy to introspect. Try 'idle' then 'recharge'.")
-		events = append(events, fmt.Sprintf("%s has low energy for introspection.", entityID))
-		return s, events
+// introspectEffect raises the focused thought's clarity and spreads some of the
+// gain to its associations. If the mind's thought source can rewrite thoughts,
+// the thought is also rephrased more clearly.
+func introspectEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	focused := ctx.Focused()
+	oldClarity := focused.Clarity
+	gainMin, gainMax := ctx.Config.IntrospectGainMin, ctx.Config.IntrospectGainMax
+	focused.Clarity += ctx.Affect.IntrospectGain(gainMin+(ctx.Rand.Float64()*(gainMax-gainMin)), ctx.Config) // Increase clarity, with some randomness
+	if focused.Clarity > 1.0 {
+		focused.Clarity = 1.0
 	}
-
-	switch command {
-	case "introspect":
-		if ctx.CurrentFocusIndex == -1 {
-			fmt.Println("No thought is currently focused. Focus on a thought first.")
-			events = append(events, fmt.Sprintf("%s tried to introspect without focus.", entityID))
-			break
-		}
-		if ctx.Energy >= 15 {
-			ctx.Energy -= 15
-			ctx.Clarity += 0.15 + (rand.Float64() * 0.1) // Increase clarity, with some randomness
-			if ctx.Clarity > 1.0 {
-				ctx.Clarity = 1.0
-			}
-			focusedThought := ctx.Thoughts[ctx.CurrentFocusIndex]
-			events = append(events, fmt.Sprintf("%s introspected on '%s'. Clarity now %.2f.", entityID, focusedThought, ctx.Clarity))
-			fmt.Printf("Introspecting... Clarity of '%s' increased to %.2f. Energy: %d\n", focusedThought, ctx.Clarity, ctx.Energy)
-		} else {
-			events = append(events, fmt.Sprintf("%s failed to introspect (low energy).", entityID))
-			fmt.Println("Not enough energy to introspect.")
-		}
-	case "unfocus":
-		if ctx.CurrentFocusIndex != -1 {
-			focusedThought := ctx.Thoughts[ctx.CurrentFocusIndex]
-			ctx.CurrentFocusIndex = -1
-			ctx.Clarity = 0
-			events = append(events, fmt.Sprintf("%s unfocused from '%s'.", entityID, focusedThought))
-			fmt.Printf("Unfocused from thought. Clarity reset. Energy: %d\n", ctx.Energy) // No energy cost to unfocus
-		} else {
-			fmt.Println("No thought is currently focused.")
-			events = append(events, fmt.Sprintf("%s tried to unfocus but no thought was focused.", entityID))
+	focused.TimesIntrospected++
+	focused.refresh()
+	focused.Qualia.introspect(focused.Clarity-oldClarity, ctx.Config)
+	event := Event{Kind: EventIntrospected, Entity: entityID, ThoughtID: focused.ID, Clarity: focused.Clarity}
+	if rewriter, ok := ctx.ThoughtSource.(Rewriter); ok {
+		if text, ok := rewriter.Rewrite(ctx, *focused); ok {
+			event.From = focused.Text
+			focused.Text = text
 		}
-	case "idle":
-		events = append(events, fmt.Sprintf("%s transitioned to Idle from Reflecting.", entityID))
-		return &IdleState{}, events
-	default:
-		fmt.Println("Unknown command in Reflecting state.")
-		events = append(events, fmt.Sprintf("%s tried unknown command '%s' in Reflecting.", entityID, command))
 	}
-	return s, events
+	event.Thought = focused.Text
+	return append([]Event{event}, ctx.spreadActivation(entityID, *focused, focused.Clarity-oldClarity)...), true
+}
+
+// unfocusEffect lets go of the focused thought, which keeps its clarity.
+func unfocusEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	focused := ctx.Focused()
+	ctx.CurrentFocusIndex = -1
+	return []Event{{Kind: EventUnfocused, Entity: entityID, ThoughtID: focused.ID, Thought: focused.Text}}, true
 }
 
 // --- ActingState ---
@@ -248,148 +282,176 @@ type ActingState struct{}
 
 func (s *ActingState) GetName() string { return "Acting" }
 func (s *ActingState) GetPrompt(entity *Entity) string {
-	prompt := fmt.Sprintf("Entity %s (Acting) | Energy: %d/%d", entity.ID, entity.Mind.Energy, entity.Mind.MaxEnergy)
-	if entity.Mind.CurrentFocusIndex != -1 && entity.Mind.CurrentFocusIndex < len(entity.Mind.Thoughts) {
-		prompt += fmt.Sprintf(" | Focus: '%s' (Clarity: %.2f)", entity.Mind.Thoughts[entity.Mind.CurrentFocusIndex], entity.Mind.Clarity)
-	} else {
-		prompt += " | Focus: None"
+	return prompt(entity, s, focusDetails(entity.Mind))
+}
+
+func (s *ActingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	return ctx.Rules.Apply(s, entityID, ctx, parts)
+}
+
+// expressEffect puts the focused thought out into the world if it is clear
+// enough, consuming it.
+func expressEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	focused := ctx.Focused()
+	if threshold := ctx.EffectiveThreshold(); focused.Clarity < threshold {
+		return []Event{{Kind: EventExpressFailed, Entity: entityID, ThoughtID: focused.ID, Thought: focused.Text, Clarity: focused.Clarity, Threshold: threshold}}, false
+	}
+	event := Event{Kind: EventExpressed, Entity: entityID, ThoughtID: focused.ID, Thought: focused.Text, Clarity: focused.Clarity}
+	ctx.Outbox = append(ctx.Outbox, Expression{Text: focused.Text, Clarity: focused.Clarity, Qualia: focused.Qualia})
+	ctx.removeFocused()
+	return []Event{event}, true
+}
+
+// evolveEffect consumes a deeply understood thought to change one of the
+// mind's parameters: evolve <parameter> <direction>.
+func evolveEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	parameter := strings.ToLower(args[0])
+	direction := strings.ToLower(args[1])
+	failed := Event{Kind: EventEvolveFailed, Entity: entityID, Parameter: parameter, Direction: direction}
+	cfg := ctx.Config
+
+	focused := ctx.Focused()
+	if focused == nil {
+		failed.Reason = "Cannot evolve without a deeply focused thought."
+		return []Event{failed}, false
 	}
-	prompt += " | Commands: [express | evolve <param> <dir> | idle | view | quit]"
-	return prompt
-}
-
-func (s *ActingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []string) {
-	command := parts[0]
-	var events []string
-
-	// Define constants for evolution parameters
-	const HighClarityForEvolve = 0.95
-	const EnergyCostEvolve = 50
-	const MaxEnergyEvolveAmount = 10
-	const ThresholdEvolveAmount = 0.05
-	const MinExpressionThreshold = 0.1
-	const MaxExpressionThreshold = 0.95 // Can't evolve to be trivially easy or impossible
-
-	if ctx.Energy < 20 && command == "express" { // Specific energy check for express
-		fmt.Println("Not enough energy to express. Try 'idle' then 'recharge'.")
-		events = append(events, fmt.Sprintf("%s has low energy for expressing thoughts.", entityID))
-		return s, events
+	if focused.Clarity < cfg.EvolveMinClarity {
+		failed.Reason = fmt.Sprintf("Clarity of focused thought '%.2f' is not high enough (%.2f required) to evolve.", focused.Clarity, cfg.EvolveMinClarity)
+		failed.Clarity = focused.Clarity
+		return []Event{failed}, false
 	}
 
-	switch command {
-	case "express":
-		if ctx.CurrentFocusIndex == -1 {
-			fmt.Println("No thought is currently focused. Focus and reflect first.")
-			events = append(events, fmt.Sprintf("%s tried to express without focus.", entityID))
-			break
+	evolved := Event{Kind: EventEvolved, Entity: entityID, Parameter: parameter, Direction: direction, ThoughtID: focused.ID, Thought: focused.Text}
+	switch {
+	case parameter == "max_energy" && direction == "increase":
+		evolved.OldValue = float64(ctx.MaxEnergy)
+		ctx.MaxEnergy += cfg.EvolveMaxEnergyStep
+		evolved.NewValue = float64(ctx.MaxEnergy)
+	case parameter == "threshold" && direction == "decrease":
+		evolved.OldValue = ctx.ExpressionThreshold
+		ctx.ExpressionThreshold -= cfg.EvolveThresholdStep
+		if ctx.ExpressionThreshold < cfg.MinExpressionThreshold {
+			ctx.ExpressionThreshold = cfg.MinExpressionThreshold
 		}
-		focusedThought := ctx.Thoughts[ctx.CurrentFocusIndex]
-		if ctx.Clarity < ctx.ExpressionThreshold {
-			msg := fmt.Sprintf("FAILED TO EXPRESS: '%s'. Clarity %.2f is below threshold %.2f.", focusedThought, ctx.Clarity, ctx.ExpressionThreshold)
-			events = append(events, fmt.Sprintf("%s %s", entityID, msg))
-			fmt.Printf("%s Energy: %d\n", msg, ctx.Energy)
-			break
+		evolved.NewValue = ctx.ExpressionThreshold
+	case parameter == "threshold" && direction == "increase":
+		evolved.OldValue = ctx.ExpressionThreshold
+		ctx.ExpressionThreshold += cfg.EvolveThresholdStep
+		if ctx.ExpressionThreshold > cfg.MaxExpressionThreshold {
+			ctx.ExpressionThreshold = cfg.MaxExpressionThreshold
 		}
+		evolved.NewValue = ctx.ExpressionThreshold
+	case parameter == "max_energy" || parameter == "threshold":
+		failed.Reason = fmt.Sprintf("Invalid direction '%s' for parameter '%s'.", direction, parameter)
+		return []Event{failed}, false
+	default:
+		failed.Reason = fmt.Sprintf("Unknown parameter '%s'.", parameter)
+		return []Event{failed}, false
+	}
 
-		if ctx.Energy >= 20 { // Standard express cost
-			ctx.Energy -= 20
-			msg := fmt.Sprintf("SUCCESSFULLY EXPRESSED: '%s'!", focusedThought)
-			events = append(events, fmt.Sprintf("%s %s", entityID, msg))
-			fmt.Printf("%s Clarity was %.2f. Energy: %d\n", msg, ctx.Clarity, ctx.Energy)
+	// Consume thought and reset focus
+	ctx.removeFocused()
+	return []Event{evolved}, true
+}
 
-			ctx.Thoughts = append(ctx.Thoughts[:ctx.CurrentFocusIndex], ctx.Thoughts[ctx.CurrentFocusIndex+1:]...)
-			ctx.CurrentFocusIndex = -1
-			ctx.Clarity = 0
-		} else {
-			events = append(events, fmt.Sprintf("%s failed to express '%s' (low energy).", entityID, focusedThought))
-			fmt.Println("Not enough energy to express the thought.")
-		}
+// --- DreamingState ---
+type DreamingState struct{}
 
-	case "evolve":
-		if len(parts) < 3 {
-			msg := "Usage: evolve <parameter> <direction> (e.g., evolve max_energy increase)"
-			fmt.Println(msg)
-			events = append(events, fmt.Sprintf("%s evolution command failed: %s", entityID, msg))
-			break
-		}
-		parameter := strings.ToLower(parts[1])
-		direction := strings.ToLower(parts[2])
-
-		if ctx.CurrentFocusIndex == -1 {
-			msg := "Cannot evolve without a deeply focused thought."
-			fmt.Println(msg)
-			events = append(events, fmt.Sprintf("%s evolution failed: %s", entityID, msg))
-			break
-		}
-		if ctx.Clarity < HighClarityForEvolve {
-			msg := fmt.Sprintf("Clarity of focused thought '%.2f' is not high enough (%.2f required) to evolve.", ctx.Clarity, HighClarityForEvolve)
-			fmt.Println(msg)
-			events = append(events, fmt.Sprintf("%s evolution failed: %s", entityID, msg))
-			break
+func (s *DreamingState) GetName() string { return "Dreaming" }
+func (s *DreamingState) GetPrompt(entity *Entity) string {
+	return prompt(entity, s, fmt.Sprintf(" | Thoughts: %d", len(entity.Mind.Thoughts)))
+}
+
+func (s *DreamingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	return ctx.Rules.Apply(s, entityID, ctx, parts)
+}
+
+// dreamEffect restores energy faster than a recharge and works over the
+// unfocused thoughts: introspected ones consolidate, the rest fade, the
+// faintest are dropped, and sometimes two are fused into something new.
+func dreamEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	cfg := ctx.Config
+	oldEnergy := ctx.Energy
+	ctx.Energy = min(ctx.Energy+cfg.DreamEnergy, ctx.MaxEnergy)
+	events := []Event{{Kind: EventDreamed, Entity: entityID, OldValue: float64(oldEnergy), NewValue: float64(ctx.Energy)}}
+
+	for i := range ctx.Thoughts {
+		if i == ctx.CurrentFocusIndex {
+			continue
 		}
-		if ctx.Energy < EnergyCostEvolve {
-			msg := fmt.Sprintf("Not enough energy (%d required) to evolve.", EnergyCostEvolve)
-			fmt.Println(msg)
-			events = append(events, fmt.Sprintf("%s evolution failed: %s Energy %d/%d", entityID, msg, ctx.Energy, EnergyCostEvolve))
-			break
+		t := &ctx.Thoughts[i]
+		drift := ctx.Rand.Float64() * cfg.DreamClarityDrift
+		if t.TimesIntrospected > 0 {
+			t.Clarity = min(t.Clarity+drift, 1)
+		} else {
+			t.Clarity = max(t.Clarity-drift, 0)
 		}
+	}
 
-		// All conditions met, proceed with evolution
-		ctx.Energy -= EnergyCostEvolve
-		originalThought := ctx.Thoughts[ctx.CurrentFocusIndex]
-		evolved := false
-
-		switch parameter {
-		case "max_energy":
-			if direction == "increase" {
-				oldVal := ctx.MaxEnergy
-				ctx.MaxEnergy += MaxEnergyEvolveAmount
-				events = append(events, fmt.Sprintf("%s EVOLVED: MaxEnergy increased from %d to %d. Consumed thought: '%s'.", entityID, oldVal, ctx.MaxEnergy, originalThought))
-				evolved = true
-			} else {
-				events = append(events, fmt.Sprintf("%s evolution failed: Invalid direction '%s' for parameter '%s'.", entityID, direction, parameter))
-			}
-		case "threshold":
-			oldVal := ctx.ExpressionThreshold
-			if direction == "decrease" {
-				ctx.ExpressionThreshold -= ThresholdEvolveAmount
-				if ctx.ExpressionThreshold < MinExpressionThreshold {
-					ctx.ExpressionThreshold = MinExpressionThreshold
-				}
-				events = append(events, fmt.Sprintf("%s EVOLVED: ExpressionThreshold decreased from %.2f to %.2f. Consumed thought: '%s'.", entityID, oldVal, ctx.ExpressionThreshold, originalThought))
-				evolved = true
-			} else if direction == "increase" {
-				ctx.ExpressionThreshold += ThresholdEvolveAmount
-				if ctx.ExpressionThreshold > MaxExpressionThreshold {
-					ctx.ExpressionThreshold = MaxExpressionThreshold
-				}
-				events = append(events, fmt.Sprintf("%s EVOLVED: ExpressionThreshold increased from %.2f to %.2f. Consumed thought: '%s'.", entityID, oldVal, ctx.ExpressionThreshold, originalThought))
-				evolved = true
-			} else {
-				events = append(events, fmt.Sprintf("%s evolution failed: Invalid direction '%s' for parameter '%s'.", entityID, direction, parameter))
-			}
-		default:
-			events = append(events, fmt.Sprintf("%s evolution failed: Unknown parameter '%s'.", entityID, parameter))
+	for i := len(ctx.Thoughts) - 1; i >= 0; i-- {
+		if t := ctx.Thoughts[i]; i != ctx.CurrentFocusIndex && t.Clarity < cfg.DreamPruneClarity {
+			events = append(events, Event{Kind: EventThoughtPruned, Entity: entityID, ThoughtID: t.ID, Thought: t.Text, Clarity: t.Clarity})
+			ctx.removeThought(i)
 		}
+	}
 
-		if evolved {
-			// Consume thought and reset focus
-			ctx.Thoughts = append(ctx.Thoughts[:ctx.CurrentFocusIndex], ctx.Thoughts[ctx.CurrentFocusIndex+1:]...)
-			ctx.CurrentFocusIndex = -1
-			ctx.Clarity = 0
-			fmt.Printf("Entity %s successfully evolved. Energy: %d\n", entityID, ctx.Energy)
-		} else {
-			// Refund energy if evolution attempt failed due to bad params but passed initial checks
-			ctx.Energy += EnergyCostEvolve
-			fmt.Printf("Entity %s evolution attempt failed due to invalid parameters. Energy refunded.\n", entityID)
+	if n := len(ctx.Thoughts); n >= 2 && ctx.Rand.Float64() < cfg.DreamRecombineChance {
+		i, j := ctx.Rand.IntN(n), ctx.Rand.IntN(n-1)
+		if j >= i {
+			j++
 		}
+		a, b := ctx.Thoughts[i], ctx.Thoughts[j]
+		child := ctx.AddChild(recombine(a.Text, b.Text), a, b)
+		child.Clarity = min(a.Clarity, b.Clarity)
+		events = append(events, Event{Kind: EventThoughtRecombined, Entity: entityID, ThoughtID: child.ID, Thought: child.Text, Clarity: child.Clarity})
+	}
+	return events, true
+}
 
-	case "idle":
-		events = append(events, fmt.Sprintf("%s transitioned to Idle from Acting.", entityID))
-		return &IdleState{}, events
-	default:
-		fmt.Println("Unknown command in Acting state.")
-		events = append(events, fmt.Sprintf("%s tried unknown command '%s' in Acting.", entityID, command))
+// recombine splices the first half of one thought onto the second half of
+// another, the way dreams do.
+func recombine(a, b string) string {
+	aWords, bWords := strings.Fields(a), strings.Fields(b)
+	return strings.Join(append(aWords[:(len(aWords)+1)/2:(len(aWords)+1)/2], bWords[len(bWords)/2:]...), " ")
+}
+
+// --- PerceivingState ---
+type PerceivingState struct{}
+
+func (s *PerceivingState) GetName() string { return "Perceiving" }
+func (s *PerceivingState) GetPrompt(entity *Entity) string {
+	return prompt(entity, s, fmt.Sprintf(" | Thoughts: %d | Heard: %d", len(entity.Mind.Thoughts), len(entity.Mind.Inbox)))
+}
+
+func (s *PerceivingState) HandleInput(entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	return ctx.Rules.Apply(s, entityID, ctx, parts)
+}
+
+// listenEffect takes the oldest thing heard from another entity to heart,
+// attributed to its speaker.
+func listenEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	if len(ctx.Inbox) == 0 {
+		return []Event{invalid(entityID, "listen", "nothing has been heard")}, false
+	}
+	p := ctx.Inbox[0]
+	ctx.Inbox = ctx.Inbox[1:]
+	thought := ctx.AddHeard(p)
+	return []Event{{Kind: EventPerceived, Entity: entityID, From: p.From, ThoughtID: thought.ID, Thought: thought.Text, Clarity: thought.Clarity}}, true
+}
+
+// observeEffect turns the next stimulus in the environment into a thought,
+// attributed to its source.
+func observeEffect(entityID string, ctx *MindContext, args []string) ([]Event, bool) {
+	stimulus, ok := ctx.Environment.Observe(ctx.Rand)
+	if !ok {
+		return []Event{invalid(entityID, "observe", "there is nothing to perceive")}, false
+	}
+	thought := ctx.AddThought(stimulus.Text, OriginPerceived)
+	thought.Clarity = ctx.Config.ObserveClarity
+	thought.From = stimulus.Source
+	if stimulus.Modality != "" {
+		thought.Qualia.Modality = stimulus.Modality
+		thought.Qualia.Intensity = stimulus.Intensity
 	}
-	return s, events
+	return []Event{{Kind: EventPerceived, Entity: entityID, From: stimulus.Source, ThoughtID: thought.ID, Thought: thought.Text, Clarity: thought.Clarity}}, true
 }
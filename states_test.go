@@ -2,6 +2,10 @@
 package main
 
 import (
+	"io"
+	"math"
+	"os"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -15,7 +19,7 @@ func assertStateType(t *testing.T, expected State, actual State) {
 }
 
 func TestNewMindContext(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 
 	if ctx.Energy != 70 {
 		t.Errorf("NewMindContext: Expected initial Energy 70, got %d", ctx.Energy)
@@ -35,7 +39,7 @@ func TestNewMindContext(t *testing.T) {
 }
 
 func TestIdleState_Transitions(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	idle := &IdleState{}
 	entityID := "testEntity"
 
@@ -50,7 +54,7 @@ func TestIdleState_Transitions(t *testing.T) {
 }
 
 func TestIdleState_Recharge(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	idle := &IdleState{}
 	entityID := "testEntity"
 
@@ -77,7 +81,7 @@ func TestIdleState_Recharge(t *testing.T) {
 }
 
 func TestIdleState_ViewAndUnknown(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	idle := &IdleState{}
 	entityID := "testEntity"
 
@@ -94,22 +98,141 @@ func TestIdleState_ViewAndUnknown(t *testing.T) {
 	assertStateType(t, idle, newState)
 }
 
+func TestThinkingState_GenerateCreatesThoughtRecord(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Tick = 12
+	thinking := &ThinkingState{}
+
+	thinking.HandleInput("testEntity", ctx, strings.Fields("generate"))
+	thinking.HandleInput("testEntity", ctx, strings.Fields("generate"))
+	if len(ctx.Thoughts) != 2 {
+		t.Fatalf("ThinkingState Generate: Expected 2 thoughts, got %d", len(ctx.Thoughts))
+	}
+	first := ctx.Thoughts[0]
+	if first.Text == "" || first.Source != OriginGenerated || first.CreatedTick != 12 || first.Clarity != InitialClarity {
+		t.Errorf("ThinkingState Generate: Unexpected thought record %+v", first)
+	}
+	if first.ID == ctx.Thoughts[1].ID {
+		t.Errorf("ThinkingState Generate: Expected distinct thought IDs, both were %d", first.ID)
+	}
+}
+
+func TestThinkingState_RefocusKeepsClarity(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	thinking := &ThinkingState{}
+	ctx.AddThought("first", OriginGenerated).Clarity = 0.6
+	ctx.AddThought("second", OriginGenerated)
+
+	thinking.HandleInput("testEntity", ctx, strings.Fields("focus 0"))
+	thinking.HandleInput("testEntity", ctx, strings.Fields("focus 1"))
+	thinking.HandleInput("testEntity", ctx, strings.Fields("focus 0"))
+
+	if ctx.CurrentFocusIndex != 0 {
+		t.Fatalf("ThinkingState Focus: Expected focus on 0, got %d", ctx.CurrentFocusIndex)
+	}
+	if ctx.FocusedClarity() != 0.6 {
+		t.Errorf("ThinkingState Focus: Expected clarity 0.6 to survive refocusing, got %.2f", ctx.FocusedClarity())
+	}
+}
+
+func TestThinkingState_HandlePerceptions(t *testing.T) {
+	thinking := &ThinkingState{}
+	heard := Perception{From: "other", Text: "the internal world ... vast", Clarity: 0.4}
+
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Inbox = []Perception{heard}
+	thinking.HandleInput("testEntity", ctx, strings.Fields("accept 0"))
+	if len(ctx.Inbox) != 0 || len(ctx.Thoughts) != 1 {
+		t.Fatalf("ThinkingState Accept: Expected perception to become a thought, inbox %d thoughts %d", len(ctx.Inbox), len(ctx.Thoughts))
+	}
+	if ctx.Thoughts[0].Source != OriginHeard || ctx.Thoughts[0].Clarity != 0.4 || ctx.Thoughts[0].Text != heard.Text {
+		t.Errorf("ThinkingState Accept: Unexpected thought %+v", ctx.Thoughts[0])
+	}
+
+	ctx = NewMindContext(DefaultConfig())
+	ctx.Inbox = []Perception{heard}
+	initialEnergy := ctx.Energy
+	thinking.HandleInput("testEntity", ctx, strings.Fields("ignore 0"))
+	if len(ctx.Inbox) != 0 || len(ctx.Thoughts) != 0 || ctx.Energy != initialEnergy {
+		t.Errorf("ThinkingState Ignore: Expected perception dropped at no cost, inbox %d thoughts %d energy %d", len(ctx.Inbox), len(ctx.Thoughts), ctx.Energy)
+	}
+
+	ctx = NewMindContext(DefaultConfig())
+	ctx.Inbox = []Perception{heard}
+	ctx.AddThought("the internal world is vast", OriginGenerated).Clarity = 0.5
+	ctx.CurrentFocusIndex = 0
+	thinking.HandleInput("testEntity", ctx, strings.Fields("integrate 0"))
+	if len(ctx.Inbox) != 0 || len(ctx.Thoughts) != 1 {
+		t.Fatalf("ThinkingState Integrate: Expected perception folded into focus, inbox %d thoughts %d", len(ctx.Inbox), len(ctx.Thoughts))
+	}
+	if ctx.FocusedClarity() <= 0.5 {
+		t.Errorf("ThinkingState Integrate: Expected focused clarity to rise above 0.5, got %.2f", ctx.FocusedClarity())
+	}
+
+	ctx = NewMindContext(DefaultConfig())
+	newState, _ := thinking.HandleInput("testEntity", ctx, strings.Fields("accept 3"))
+	assertStateType(t, thinking, newState)
+	if len(ctx.Thoughts) != 0 {
+		t.Errorf("ThinkingState Accept: Invalid index should not add a thought")
+	}
+}
+
+func TestThinkingState_Combine(t *testing.T) {
+	thinking := &ThinkingState{}
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("embodiment shapes perception", OriginGenerated).Clarity = 0.2
+	ctx.AddThought("meaning is constructed", OriginGenerated)
+	ctx.AddThought("the internal world is vast", OriginGenerated).Clarity = 0.6
+	ctx.CurrentFocusIndex = 2
+	energy := ctx.Energy
+
+	_, events := thinking.HandleInput("e", ctx, strings.Fields("combine 0 2"))
+	if len(ctx.Thoughts) != 2 || ctx.Thoughts[0].Text != "meaning is constructed" {
+		t.Fatalf("combine: Expected both parents consumed, got %+v", ctx.Thoughts)
+	}
+	child := ctx.Thoughts[1]
+	if child.Source != OriginCombined || !slices.Equal(child.Parents, []int{1, 3}) || math.Abs(child.Clarity-0.4) > 1e-9 || child.Text != "embodiment shapes world is vast" {
+		t.Errorf("combine: Unexpected child %+v", child)
+	}
+	if ctx.CurrentFocusIndex != 1 || ctx.Energy != energy-ctx.Config.CombineCost {
+		t.Errorf("combine: Expected focus to pass to the child and the cost charged, got focus %d energy %d", ctx.CurrentFocusIndex, ctx.Energy)
+	}
+	if events[0].Kind != EventThoughtCombined || events[0].From != "embodiment shapes perception" || events[0].ThoughtID != child.ID {
+		t.Errorf("combine: Unexpected event %+v", events[0])
+	}
+
+	thinking.HandleInput("e", ctx, strings.Fields("combine 1 0 keep"))
+	if len(ctx.Thoughts) != 3 || !slices.Equal(ctx.Thoughts[2].Parents, []int{4, 2}) || ctx.CurrentFocusIndex != 1 {
+		t.Errorf("combine keep: Expected the parents and focus kept, got %+v (focus %d)", ctx.Thoughts, ctx.CurrentFocusIndex)
+	}
+
+	energy = ctx.Energy
+	for _, bad := range []string{"combine 1 1", "combine 0 9", "combine 0 x", "combine 0 1 twice", "combine 0"} {
+		if _, events := thinking.HandleInput("e", ctx, strings.Fields(bad)); events[0].Kind != EventInvalidCommand || len(ctx.Thoughts) != 3 || ctx.Energy != energy {
+			t.Errorf("%s: Expected an invalid command at no cost, got %+v", bad, events)
+		}
+	}
+}
+
 // Next: ReflectingState tests
 
 func TestReflectingState_Introspect(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	reflecting := &ReflectingState{}
 	entityID := "testEntity"
-	ctx.Thoughts = append(ctx.Thoughts, "thought to reflect on")
+	ctx.AddThought("thought to reflect on", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = 0.3
+	ctx.Thoughts[0].Clarity = 0.3
 	initialEnergy := ctx.Energy
 
 	newState, _ := reflecting.HandleInput(entityID, ctx, strings.Fields("introspect"))
 	assertStateType(t, reflecting, newState) // Should remain in ReflectingState
 
-	if ctx.Clarity <= 0.3 {
-		t.Errorf("ReflectingState Introspect: Expected clarity to increase from 0.3, got %.2f", ctx.Clarity)
+	if ctx.Thoughts[0].Clarity <= 0.3 {
+		t.Errorf("ReflectingState Introspect: Expected clarity to increase from 0.3, got %.2f", ctx.Thoughts[0].Clarity)
+	}
+	if ctx.Thoughts[0].TimesIntrospected != 1 {
+		t.Errorf("ReflectingState Introspect: Expected TimesIntrospected 1, got %d", ctx.Thoughts[0].TimesIntrospected)
 	}
 	expectedEnergy := initialEnergy - 15
 	if ctx.Energy != expectedEnergy {
@@ -117,45 +240,46 @@ func TestReflectingState_Introspect(t *testing.T) {
 	}
 
 	// Test clarity capping at 1.0
-	ctx.Clarity = 0.95
+	ctx.Thoughts[0].Clarity = 0.95
 	ctx.Energy = 50 // Ensure enough energy
 	_, _ = reflecting.HandleInput(entityID, ctx, strings.Fields("introspect"))
-	if ctx.Clarity > 1.0 {
-		t.Errorf("ReflectingState Introspect: Clarity %f exceeded 1.0", ctx.Clarity)
+	if ctx.Thoughts[0].Clarity > 1.0 {
+		t.Errorf("ReflectingState Introspect: Clarity %f exceeded 1.0", ctx.Thoughts[0].Clarity)
 	}
 	// Check if it's exactly 1.0 if it was close enough (e.g. 0.95 + (0.15 to 0.25) -> likely >= 1.0)
 	// The actual increment is 0.15 + (rand*0.1). So it can be up to 0.25.
 	// Let's test a few introspects to ensure it reaches 1.0 and stops.
-	ctx.Clarity = 0.8
+	ctx.Thoughts[0].Clarity = 0.8
 	ctx.Energy = 100                                                           // Reset energy for multiple introspects
 	_, _ = reflecting.HandleInput(entityID, ctx, strings.Fields("introspect")) // clarity becomes ~0.95-1.05
-	if ctx.Clarity > 1.0 {
-		ctx.Clarity = 1.0
+	if ctx.Thoughts[0].Clarity > 1.0 {
+		ctx.Thoughts[0].Clarity = 1.0
 	} // Simulate cap if first one overshot due to rand
 
 	// If it's not 1.0 yet, one more should do it or cap it.
-	if ctx.Clarity < 1.0 {
+	if ctx.Thoughts[0].Clarity < 1.0 {
 		_, _ = reflecting.HandleInput(entityID, ctx, strings.Fields("introspect"))
 	}
 	_, _ = reflecting.HandleInput(entityID, ctx, strings.Fields("introspect")) // Should stay 1.0 (or very close, then capped by logic)
 
 	// The introspect logic caps clarity at 1.0 internally. So we just check that.
-	if ctx.Clarity != 1.0 {
-		t.Errorf("ReflectingState Introspect: Expected clarity to cap at 1.0, got %.2f", ctx.Clarity)
+	if ctx.Thoughts[0].Clarity != 1.0 {
+		t.Errorf("ReflectingState Introspect: Expected clarity to cap at 1.0, got %.2f", ctx.Thoughts[0].Clarity)
 	}
 }
 
 func TestReflectingState_Introspect_NoFocus(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	reflecting := &ReflectingState{}
 	entityID := "testEntity"
+	ctx.AddThought("unfocused thought", OriginGenerated)
 	ctx.CurrentFocusIndex = -1 // No thought focused
-	initialClarity := ctx.Clarity
+	initialClarity := ctx.Thoughts[0].Clarity
 	initialEnergy := ctx.Energy
 
 	_, _ = reflecting.HandleInput(entityID, ctx, strings.Fields("introspect"))
-	if ctx.Clarity != initialClarity {
-		t.Errorf("ReflectingState Introspect NoFocus: Clarity changed, expected %.2f, got %.2f", initialClarity, ctx.Clarity)
+	if ctx.Thoughts[0].Clarity != initialClarity {
+		t.Errorf("ReflectingState Introspect NoFocus: Clarity changed, expected %.2f, got %.2f", initialClarity, ctx.Thoughts[0].Clarity)
 	}
 	if ctx.Energy != initialEnergy {
 		t.Errorf("ReflectingState Introspect NoFocus: Energy changed, expected %d, got %d", initialEnergy, ctx.Energy)
@@ -163,18 +287,18 @@ func TestReflectingState_Introspect_NoFocus(t *testing.T) {
 }
 
 func TestReflectingState_Introspect_NoEnergy(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	reflecting := &ReflectingState{}
 	entityID := "testEntity"
-	ctx.Thoughts = append(ctx.Thoughts, "thought to reflect on")
+	ctx.AddThought("thought to reflect on", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = 0.3
+	ctx.Thoughts[0].Clarity = 0.3
 	ctx.Energy = 5 // Not enough for introspect (costs 15)
 
-	initialClarity := ctx.Clarity
+	initialClarity := ctx.Thoughts[0].Clarity
 	_, _ = reflecting.HandleInput(entityID, ctx, strings.Fields("introspect"))
-	if ctx.Clarity != initialClarity {
-		t.Errorf("ReflectingState Introspect NoEnergy: Clarity changed, expected %.2f, got %.2f", initialClarity, ctx.Clarity)
+	if ctx.Thoughts[0].Clarity != initialClarity {
+		t.Errorf("ReflectingState Introspect NoEnergy: Clarity changed, expected %.2f, got %.2f", initialClarity, ctx.Thoughts[0].Clarity)
 	}
 	if ctx.Energy != 5 { // Energy should not change
 		t.Errorf("ReflectingState Introspect NoEnergy: Expected energy 5, got %d", ctx.Energy)
@@ -182,12 +306,12 @@ func TestReflectingState_Introspect_NoEnergy(t *testing.T) {
 }
 
 func TestReflectingState_Unfocus(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	reflecting := &ReflectingState{}
 	entityID := "testEntity"
-	ctx.Thoughts = append(ctx.Thoughts, "thought to unfocus")
+	ctx.AddThought("thought to unfocus", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = 0.5
+	ctx.Thoughts[0].Clarity = 0.5
 	initialEnergy := ctx.Energy
 
 	newState, _ := reflecting.HandleInput(entityID, ctx, strings.Fields("unfocus"))
@@ -196,10 +320,9 @@ func TestReflectingState_Unfocus(t *testing.T) {
 	if ctx.CurrentFocusIndex != -1 {
 		t.Errorf("ReflectingState Unfocus: Expected CurrentFocusIndex -1, got %d", ctx.CurrentFocusIndex)
 	}
-	// Clarity being reset to 0 on unfocus is a design choice reflected here.
-	// If it were to persist with the thought, this test would change.
-	if ctx.Clarity != 0.0 {
-		t.Errorf("ReflectingState Unfocus: Expected Clarity to reset to 0.0, got %.2f", ctx.Clarity)
+	// Clarity persists with the thought, so unfocusing loses no work.
+	if ctx.Thoughts[0].Clarity != 0.5 {
+		t.Errorf("ReflectingState Unfocus: Expected thought to keep Clarity 0.5, got %.2f", ctx.Thoughts[0].Clarity)
 	}
 	if ctx.Energy != initialEnergy { // Unfocus costs no energy
 		t.Errorf("ReflectingState Unfocus: Energy changed from %d to %d", initialEnergy, ctx.Energy)
@@ -207,7 +330,7 @@ func TestReflectingState_Unfocus(t *testing.T) {
 }
 
 func TestReflectingState_TransitionToIdle(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	reflecting := &ReflectingState{}
 	entityID := "testEntity"
 
@@ -216,17 +339,18 @@ func TestReflectingState_TransitionToIdle(t *testing.T) {
 }
 
 func TestReflectingState_UnknownCommand(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	reflecting := &ReflectingState{}
 	entityID := "testEntity"
 	initialClarity := 0.4
-	ctx.Clarity = initialClarity
+	ctx.AddThought("a thought", OriginGenerated)
+	ctx.Thoughts[0].Clarity = initialClarity
 	initialEnergy := ctx.Energy
 	ctx.CurrentFocusIndex = 0
 
 	newState, _ := reflecting.HandleInput(entityID, ctx, strings.Fields("unknownreflectingcommand"))
 	assertStateType(t, reflecting, newState)
-	if ctx.Clarity != initialClarity {
+	if ctx.Thoughts[0].Clarity != initialClarity {
 		t.Errorf("ReflectingState Unknown: Clarity changed")
 	}
 	if ctx.Energy != initialEnergy {
@@ -249,11 +373,11 @@ const (
 )
 
 func setupContextForEvolve(t *testing.T) (*MindContext, *ActingState) {
-	ctx := NewMindContext()
-	ctx.Thoughts = append(ctx.Thoughts, "A profound thought for evolution")
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("A profound thought for evolution", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = HighClarityForEvolveTest // Meets minimum clarity
-	ctx.Energy = EnergyCostEvolveTest + 20 // Sufficient energy
+	ctx.Thoughts[0].Clarity = HighClarityForEvolveTest // Meets minimum clarity
+	ctx.Energy = EnergyCostEvolveTest + 20             // Sufficient energy
 	acting := &ActingState{}
 	return ctx, acting
 }
@@ -280,10 +404,10 @@ func TestActingState_Evolve_MaxEnergy_Success(t *testing.T) {
 	if ctx.CurrentFocusIndex != -1 {
 		t.Errorf("Evolve MaxEnergy: Expected CurrentFocusIndex to be reset, got %d", ctx.CurrentFocusIndex)
 	}
-	if ctx.Clarity != 0 {
-		t.Errorf("Evolve MaxEnergy: Expected Clarity to be reset, got %.2f", ctx.Clarity)
+	if ctx.Focused() != nil {
+		t.Errorf("Evolve MaxEnergy: Expected no focused thought after evolving, got %+v", ctx.Focused())
 	}
-	if len(events) == 0 || !strings.Contains(events[0], "EVOLVED: MaxEnergy increased") {
+	if len(events) == 0 || events[0].Kind != EventEvolved || !strings.Contains(events[0].String(), "EVOLVED: MaxEnergy increased") {
 		t.Errorf("Evolve MaxEnergy: Expected evolution event, got %v", events)
 	}
 }
@@ -304,7 +428,7 @@ func TestActingState_Evolve_Threshold_Decrease_Success(t *testing.T) {
 	if ctx.Energy != initialEnergy-EnergyCostEvolveTest {
 		t.Errorf("Evolve Threshold Decrease: Expected Energy %d, got %d", initialEnergy-EnergyCostEvolveTest, ctx.Energy)
 	}
-	if len(events) == 0 || !strings.Contains(events[0], "EVOLVED: ExpressionThreshold decreased") {
+	if len(events) == 0 || events[0].Kind != EventEvolved || !strings.Contains(events[0].String(), "EVOLVED: ExpressionThreshold decreased") {
 		t.Errorf("Evolve Threshold Decrease: Expected evolution event, got %v", events)
 	}
 }
@@ -325,7 +449,7 @@ func TestActingState_Evolve_Threshold_Increase_Success(t *testing.T) {
 	if ctx.Energy != initialEnergy-EnergyCostEvolveTest {
 		t.Errorf("Evolve Threshold Increase: Expected Energy %d, got %d", initialEnergy-EnergyCostEvolveTest, ctx.Energy)
 	}
-	if len(events) == 0 || !strings.Contains(events[0], "EVOLVED: ExpressionThreshold increased") {
+	if len(events) == 0 || events[0].Kind != EventEvolved || !strings.Contains(events[0].String(), "EVOLVED: ExpressionThreshold increased") {
 		t.Errorf("Evolve Threshold Increase: Expected evolution event, got %v", events)
 	}
 }
@@ -342,8 +466,7 @@ func TestActingState_Evolve_Threshold_Capping(t *testing.T) {
 	}
 	// Try decreasing again, should stay at min
 	ctxMin.Energy = EnergyCostEvolveTest + 20 // Replenish energy for test
-	ctxMin.Clarity = HighClarityForEvolveTest // Reset clarity
-	ctxMin.Thoughts = append(ctxMin.Thoughts, "another one")
+	ctxMin.AddThought("another one", OriginGenerated).Clarity = HighClarityForEvolveTest
 	ctxMin.CurrentFocusIndex = len(ctxMin.Thoughts) - 1
 	actingMin.HandleInput(entityID, ctxMin, strings.Fields("evolve threshold decrease"))
 	if ctxMin.ExpressionThreshold != MinExpressionThresholdTest {
@@ -359,8 +482,7 @@ func TestActingState_Evolve_Threshold_Capping(t *testing.T) {
 	}
 	// Try increasing again, should stay at max
 	ctxMax.Energy = EnergyCostEvolveTest + 20 // Replenish energy
-	ctxMax.Clarity = HighClarityForEvolveTest // Reset clarity
-	ctxMax.Thoughts = append(ctxMax.Thoughts, "yet another")
+	ctxMax.AddThought("yet another", OriginGenerated).Clarity = HighClarityForEvolveTest
 	ctxMax.CurrentFocusIndex = len(ctxMax.Thoughts) - 1
 	actingMax.HandleInput(entityID, ctxMax, strings.Fields("evolve threshold increase"))
 	if ctxMax.ExpressionThreshold != MaxExpressionThresholdTest {
@@ -374,7 +496,7 @@ func TestActingState_Evolve_FailureConditions(t *testing.T) {
 	// Not enough arguments
 	ctxArgs, actingArgs := setupContextForEvolve(t)
 	_, eventsArgs := actingArgs.HandleInput(entityID, ctxArgs, strings.Fields("evolve max_energy"))
-	if len(eventsArgs) == 0 || !strings.Contains(eventsArgs[0], "Usage: evolve") {
+	if len(eventsArgs) == 0 || eventsArgs[0].Kind != EventInvalidCommand || !strings.Contains(eventsArgs[0].String(), "usage is evolve") {
 		t.Errorf("Evolve Fail Args: Expected usage message event, got %v", eventsArgs)
 	}
 
@@ -386,19 +508,19 @@ func TestActingState_Evolve_FailureConditions(t *testing.T) {
 	if ctxNoFocus.MaxEnergy != initialMaxEnergyNF {
 		t.Errorf("Evolve Fail NoFocus: MaxEnergy should not change, got %d", ctxNoFocus.MaxEnergy)
 	}
-	if len(eventsNoFocus) == 0 || !strings.Contains(eventsNoFocus[0], "without a deeply focused thought") {
+	if len(eventsNoFocus) == 0 || eventsNoFocus[0].Kind != EventEvolveFailed || !strings.Contains(eventsNoFocus[0].Reason, "without a deeply focused thought") {
 		t.Errorf("Evolve Fail NoFocus: Expected no focus message event, got %v", eventsNoFocus)
 	}
 
 	// Clarity too low
 	ctxLowClarity, actingLowClarity := setupContextForEvolve(t)
 	initialMaxEnergyLC := ctxLowClarity.MaxEnergy
-	ctxLowClarity.Clarity = HighClarityForEvolveTest - 0.1
+	ctxLowClarity.Thoughts[0].Clarity = HighClarityForEvolveTest - 0.1
 	_, eventsLowClarity := actingLowClarity.HandleInput(entityID, ctxLowClarity, strings.Fields("evolve max_energy increase"))
 	if ctxLowClarity.MaxEnergy != initialMaxEnergyLC {
 		t.Errorf("Evolve Fail LowClarity: MaxEnergy should not change, got %d", ctxLowClarity.MaxEnergy)
 	}
-	if len(eventsLowClarity) == 0 || !strings.Contains(eventsLowClarity[0], "not high enough") {
+	if len(eventsLowClarity) == 0 || eventsLowClarity[0].Kind != EventEvolveFailed || !strings.Contains(eventsLowClarity[0].Reason, "not high enough") {
 		t.Errorf("Evolve Fail LowClarity: Expected low clarity message event, got %v", eventsLowClarity)
 	}
 
@@ -410,7 +532,7 @@ func TestActingState_Evolve_FailureConditions(t *testing.T) {
 	if ctxLowEnergy.MaxEnergy != initialMaxEnergyLE {
 		t.Errorf("Evolve Fail LowEnergy: MaxEnergy should not change, got %d", ctxLowEnergy.MaxEnergy)
 	}
-	if len(eventsLowEnergy) == 0 || !strings.Contains(eventsLowEnergy[0], "Not enough energy") {
+	if len(eventsLowEnergy) == 0 || eventsLowEnergy[0].Kind != EventEnergyInsufficient || eventsLowEnergy[0].Required != EnergyCostEvolveTest {
 		t.Errorf("Evolve Fail LowEnergy: Expected low energy message event, got %v", eventsLowEnergy)
 	}
 
@@ -421,7 +543,7 @@ func TestActingState_Evolve_FailureConditions(t *testing.T) {
 	if ctxInvalidParam.MaxEnergy != initialMaxEnergyIP {
 		t.Errorf("Evolve Fail InvalidParam: MaxEnergy should not change, got %d", ctxInvalidParam.MaxEnergy)
 	}
-	if len(eventsInvalidParam) == 0 || !strings.Contains(eventsInvalidParam[0], "Unknown parameter") {
+	if len(eventsInvalidParam) == 0 || eventsInvalidParam[0].Kind != EventEvolveFailed || !strings.Contains(eventsInvalidParam[0].Reason, "Unknown parameter") {
 		t.Errorf("Evolve Fail InvalidParam: Expected unknown parameter message event, got %v", eventsInvalidParam)
 	}
 
@@ -432,7 +554,7 @@ func TestActingState_Evolve_FailureConditions(t *testing.T) {
 	if ctxInvalidDirME.MaxEnergy != initialMaxEnergyIDME {
 		t.Errorf("Evolve Fail InvalidDir MaxEnergy: MaxEnergy should not change, got %d", ctxInvalidDirME.MaxEnergy)
 	}
-	if len(eventsInvalidDirME) == 0 || !strings.Contains(eventsInvalidDirME[0], "Invalid direction") {
+	if len(eventsInvalidDirME) == 0 || eventsInvalidDirME[0].Kind != EventEvolveFailed || !strings.Contains(eventsInvalidDirME[0].Reason, "Invalid direction") {
 		t.Errorf("Evolve Fail InvalidDir MaxEnergy: Expected invalid direction event, got %v", eventsInvalidDirME)
 	}
 
@@ -443,18 +565,18 @@ func TestActingState_Evolve_FailureConditions(t *testing.T) {
 	if ctxInvalidDirTH.ExpressionThreshold != initialThresholdIDTH {
 		t.Errorf("Evolve Fail InvalidDir Threshold: Threshold should not change, got %.2f", ctxInvalidDirTH.ExpressionThreshold)
 	}
-	if len(eventsInvalidDirTH) == 0 || !strings.Contains(eventsInvalidDirTH[0], "Invalid direction") {
+	if len(eventsInvalidDirTH) == 0 || eventsInvalidDirTH[0].Kind != EventEvolveFailed || !strings.Contains(eventsInvalidDirTH[0].Reason, "Invalid direction") {
 		t.Errorf("Evolve Fail InvalidDir Threshold: Expected invalid direction event, got %v", eventsInvalidDirTH)
 	}
 }
 
 func TestActingState_Express_Success(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	acting := &ActingState{}
 	entityID := "testEntity"
-	ctx.Thoughts = append(ctx.Thoughts, "A brilliant idea")
+	ctx.AddThought("A brilliant idea", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = 0.8 // Above threshold 0.7
+	ctx.Thoughts[0].Clarity = 0.8 // Above threshold 0.7
 	ctx.ExpressionThreshold = 0.7
 	initialEnergy := ctx.Energy
 
@@ -470,12 +592,12 @@ func TestActingState_Express_Success(t *testing.T) {
 }
 
 func TestActingState_Express_LowClarity(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	acting := &ActingState{}
 	entityID := "testEntity"
-	ctx.Thoughts = append(ctx.Thoughts, "A muddled idea")
+	ctx.AddThought("A muddled idea", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = 0.5 // Below threshold 0.7
+	ctx.Thoughts[0].Clarity = 0.5 // Below threshold 0.7
 	ctx.ExpressionThreshold = 0.7
 	initialEnergy := ctx.Energy
 
@@ -487,7 +609,7 @@ func TestActingState_Express_LowClarity(t *testing.T) {
 }
 
 func TestActingState_Express_NoFocus(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	acting := &ActingState{}
 	entityID := "testEntity"
 	ctx.CurrentFocusIndex = -1 // No thought focused
@@ -501,12 +623,12 @@ func TestActingState_Express_NoFocus(t *testing.T) {
 }
 
 func TestActingState_Express_NoEnergy(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	acting := &ActingState{}
 	entityID := "testEntity"
-	ctx.Thoughts = append(ctx.Thoughts, "An energetic idea")
+	ctx.AddThought("An energetic idea", OriginGenerated)
 	ctx.CurrentFocusIndex = 0
-	ctx.Clarity = 0.9 // Sufficient clarity
+	ctx.Thoughts[0].Clarity = 0.9 // Sufficient clarity
 	ctx.ExpressionThreshold = 0.7
 	ctx.Energy = 10 // Not enough for express (costs 20)
 
@@ -518,7 +640,7 @@ func TestActingState_Express_NoEnergy(t *testing.T) {
 }
 
 func TestActingState_TransitionToIdle(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	acting := &ActingState{}
 	entityID := "testEntity"
 
@@ -527,12 +649,13 @@ func TestActingState_TransitionToIdle(t *testing.T) {
 }
 
 func TestActingState_UnknownCommand(t *testing.T) {
-	ctx := NewMindContext()
+	ctx := NewMindContext(DefaultConfig())
 	acting := &ActingState{}
 	entityID := "testEntity"
 	initialEnergy := ctx.Energy
+	ctx.AddThought("a thought", OriginGenerated)
 	ctx.CurrentFocusIndex = 0 // Assume some focus for consistent unknown behavior test
-	ctx.Clarity = 0.8
+	ctx.Thoughts[0].Clarity = 0.8
 
 	newState, _ := acting.HandleInput(entityID, ctx, strings.Fields("unknownactingcommand"))
 	assertStateType(t, acting, newState)
@@ -541,4 +664,130 @@ func TestActingState_UnknownCommand(t *testing.T) {
 	}
 }
 
+func TestStateHandlers_DoNotPrint(t *testing.T) {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+
+	states := []State{&IdleState{}, &ThinkingState{}, &ReflectingState{}, &ActingState{}}
+	commands := []string{"think", "reflect", "act", "recharge", "generate", "focus 0", "focus", "introspect", "unfocus", "express", "evolve max_energy increase", "idle", "bogus"}
+	for _, state := range states {
+		for _, command := range commands {
+			ctx := NewMindContext(DefaultConfig())
+			ctx.AddThought("a thought", OriginGenerated)
+			state.HandleInput("quietEntity", ctx, strings.Fields(command))
+		}
+	}
+
+	w.Close()
+	os.Stdout = stdout
+	printed, _ := io.ReadAll(r)
+	if len(printed) != 0 {
+		t.Errorf("State handlers should not print, but wrote %q", printed)
+	}
+}
+
+func TestDreamingState_SleepDreamWake(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Energy = 10
+
+	dreaming, events := (&IdleState{}).HandleInput("e", ctx, []string{"sleep"})
+	assertStateType(t, &DreamingState{}, dreaming)
+	if len(events) != 1 || events[0].To != "Dreaming" {
+		t.Errorf("sleep: Expected a transition to Dreaming, got %+v", events)
+	}
+	_, events = dreaming.HandleInput("e", ctx, []string{"dream"})
+	if ctx.Energy != 10+ctx.Config.DreamEnergy || events[0].Kind != EventDreamed {
+		t.Errorf("dream: Expected energy %d, got %d (%+v)", 10+ctx.Config.DreamEnergy, ctx.Energy, events)
+	}
+	if ctx.Config.DreamEnergy <= ctx.Config.RechargeAmount {
+		t.Errorf("Dreaming should restore more than a recharge")
+	}
+	ctx.Energy = ctx.MaxEnergy - 1
+	dreaming.HandleInput("e", ctx, []string{"dream"})
+	if ctx.Energy != ctx.MaxEnergy {
+		t.Errorf("dream: Expected energy capped at %d, got %d", ctx.MaxEnergy, ctx.Energy)
+	}
+	idle, _ := dreaming.HandleInput("e", ctx, []string{"wake"})
+	assertStateType(t, &IdleState{}, idle)
+}
+
+func TestDreamingState_ConsolidatesFadesAndPrunes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DreamRecombineChance = 0
+	ctx := NewMindContext(cfg)
+	ctx.AddThought("studied", OriginGenerated).TimesIntrospected = 2
+	ctx.AddThought("faint", OriginGenerated).Clarity = 0.01
+	ctx.AddThought("focused", OriginGenerated).Clarity = 0.01
+	ctx.AddThought("passing", OriginGenerated)
+	ctx.CurrentFocusIndex = 2
+
+	_, events := (&DreamingState{}).HandleInput("e", ctx, []string{"dream"})
+
+	if len(ctx.Thoughts) != 3 || ctx.Thoughts[1].Text != "focused" {
+		t.Fatalf("dream: Expected only the faint unfocused thought pruned, got %+v", ctx.Thoughts)
+	}
+	if ctx.CurrentFocusIndex != 1 || ctx.Focused().Clarity != 0.01 {
+		t.Errorf("dream: Expected focus to follow the focused thought untouched, got index %d", ctx.CurrentFocusIndex)
+	}
+	if ctx.Thoughts[0].Clarity < InitialClarity || ctx.Thoughts[2].Clarity > InitialClarity {
+		t.Errorf("dream: Expected the introspected thought to consolidate and the other to fade, got %.3f and %.3f", ctx.Thoughts[0].Clarity, ctx.Thoughts[2].Clarity)
+	}
+	if len(events) != 2 || events[1].Kind != EventThoughtPruned || events[1].Thought != "faint" {
+		t.Errorf("dream: Expected a ThoughtPruned event, got %+v", events)
+	}
+}
+
+func TestDreamingState_Recombines(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DreamRecombineChance = 1
+	ctx := NewMindContext(cfg)
+	ctx.AddThought("embodiment shapes perception", OriginGenerated)
+	ctx.AddThought("the internal world is vast", OriginGenerated).Clarity = 0.3
+
+	_, events := (&DreamingState{}).HandleInput("e", ctx, []string{"dream"})
+	if len(ctx.Thoughts) != 3 {
+		t.Fatalf("dream: Expected a composite thought, got %+v", ctx.Thoughts)
+	}
+	child := ctx.Thoughts[2]
+	if child.Source != OriginCombined || child.Clarity > InitialClarity || events[len(events)-1].Kind != EventThoughtRecombined {
+		t.Errorf("dream: Unexpected composite %+v (%+v)", child, events)
+	}
+	if got := recombine("embodiment shapes perception", "the internal world is vast"); got != "embodiment shapes world is vast" {
+		t.Errorf("recombine: got %q", got)
+	}
+}
+
+func TestPerceivingState_ListenAndObserve(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Rand = NewRNG(1)
+	ctx.Environment = NewEnvironment(NewTextStream("notes.txt", strings.NewReader("the window is open\n")))
+	ctx.receive(Perception{From: "AI-Alpha", Text: "meaning is ... not inherent", Clarity: 0.4})
+	state, _ := (&IdleState{}).HandleInput("e", ctx, []string{"perceive"})
+	assertStateType(t, &PerceivingState{}, state)
+
+	_, events := state.HandleInput("e", ctx, []string{"listen"})
+	if len(ctx.Inbox) != 0 || len(ctx.Thoughts) != 1 || events[0].Kind != EventPerceived {
+		t.Fatalf("listen: Expected the heard message to become a thought, got %+v (%+v)", ctx.Thoughts, events)
+	}
+	if heard := ctx.Thoughts[0]; heard.From != "AI-Alpha" || heard.Source != OriginHeard || heard.Clarity != 0.4 {
+		t.Errorf("listen: Expected attribution to the speaker at heard clarity, got %+v", heard)
+	}
+	if _, events := state.HandleInput("e", ctx, []string{"listen"}); events[0].Kind != EventInvalidCommand {
+		t.Errorf("listen: Expected nothing left to hear, got %+v", events)
+	}
+
+	state.HandleInput("e", ctx, []string{"observe"})
+	if line := ctx.Thoughts[1]; line.Text != "the window is open" || line.From != "stream:notes.txt" || line.Source != OriginPerceived || line.Clarity != ctx.Config.ObserveClarity {
+		t.Errorf("observe: Expected the stream line as a perceived thought, got %+v", line)
+	}
+	state.HandleInput("e", ctx, []string{"observe"})
+	if reading := ctx.Thoughts[2]; !strings.HasPrefix(reading.From, "sensor:") {
+		t.Errorf("observe: Expected a sensor reading once the stream is drained, got %+v", reading)
+	}
+}
+
 // All state tests added.
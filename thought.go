@@ -0,0 +1,140 @@
+// thought.go
+package main
+
+// ThoughtOrigin records how a thought came to be in a mind.
+type ThoughtOrigin string
+
+const (
+	OriginGenerated ThoughtOrigin = "generated" // Produced internally by 'generate'
+	OriginHeard     ThoughtOrigin = "heard"     // Received from another entity
+	OriginCombined  ThoughtOrigin = "combined"  // Derived from other thoughts
+	OriginPerceived ThoughtOrigin = "perceived" // Observed in the environment
+)
+
+// InitialClarity is the clarity a thought starts with when it enters a mind.
+const InitialClarity = 0.1
+
+// Thought is a single idea held in a mind. Clarity belongs to the thought, so
+// work done introspecting on it survives looking at something else.
+type Thought struct {
+	ID                int           `json:"id"` // Stable within a mind, never reused
+	Text              string        `json:"text"`
+	Clarity           float64       `json:"clarity"`  // 0.0 to 1.0
+	Salience          float64       `json:"salience"` // 0.0 to 1.0; fades while unfocused, see memory.go
+	CreatedTick       int           `json:"created_tick"`
+	Source            ThoughtOrigin `json:"source"`
+	From              string        `json:"from,omitempty"`    // Who or what a heard or perceived thought came from
+	Parents           []int         `json:"parents,omitempty"` // IDs of the thoughts a combined one came from
+	TimesIntrospected int           `json:"times_introspected"`
+	Qualia            Qualia        `json:"qualia"` // What it is like to have; see qualia.go
+}
+
+// AddThought appends a new thought to the mind, associated with any similar
+// ones, and returns it. It feels as familiar as the most similar thought
+// already held. The pointer is only valid until the thought list next
+// changes.
+func (ctx *MindContext) AddThought(text string, source ThoughtOrigin) *Thought {
+	ctx.NextThoughtID++
+	ctx.Thoughts = append(ctx.Thoughts, Thought{
+		ID:          ctx.NextThoughtID,
+		Text:        text,
+		Clarity:     InitialClarity,
+		Salience:    1,
+		CreatedTick: ctx.Tick,
+		Source:      source,
+		Qualia:      newQualia(source),
+	})
+	t := &ctx.Thoughts[len(ctx.Thoughts)-1]
+	t.Qualia.Familiarity = ctx.linkSimilar(*t)
+	return t
+}
+
+// AddChild adds a thought combined from a and b, recording them as its
+// parents. The child is associated with both parents and inherits their
+// other associations, so they outlive parents that are consumed, and it feels
+// like a blend of the two. Like AddThought, the pointer is only valid until
+// the list changes.
+func (ctx *MindContext) AddChild(text string, a, b Thought) *Thought {
+	var inherited []Link
+	for _, l := range ctx.Links {
+		if l.A == a.ID || l.B == a.ID || l.A == b.ID || l.B == b.ID {
+			inherited = append(inherited, l)
+		}
+	}
+	child := ctx.AddThought(text, OriginCombined)
+	child.Parents = []int{a.ID, b.ID}
+	child.Qualia = blend(a.Qualia, b.Qualia)
+	for _, l := range inherited {
+		for _, other := range []int{l.A, l.B} {
+			if other != a.ID && other != b.ID {
+				ctx.link(child.ID, other, l.Weight*ctx.Config.LinkCombineWeight)
+			}
+		}
+	}
+	ctx.link(child.ID, a.ID, ctx.Config.LinkCombineWeight)
+	ctx.link(child.ID, b.ID, ctx.Config.LinkCombineWeight)
+	return child
+}
+
+// AddHeard adds a thought taken from a perception, attributed to its speaker
+// and associated with the thought heard before it. It feels as the speaker
+// conveyed it, but only as familiar as the listener finds it.
+func (ctx *MindContext) AddHeard(p Perception) *Thought {
+	t := ctx.AddThought(p.Text, OriginHeard)
+	t.Clarity = p.Clarity
+	t.From = p.From
+	familiarity := t.Qualia.Familiarity
+	t.Qualia = p.Qualia
+	t.Qualia.Familiarity = familiarity
+	if ctx.ThoughtByID(ctx.LastHeardID) != nil {
+		ctx.link(t.ID, ctx.LastHeardID, ctx.Config.LinkHeardWeight)
+	}
+	ctx.LastHeardID = t.ID
+	return t
+}
+
+// ThoughtByID returns the thought with the given ID, or nil if the mind no
+// longer holds it.
+func (ctx *MindContext) ThoughtByID(id int) *Thought {
+	for i := range ctx.Thoughts {
+		if ctx.Thoughts[i].ID == id {
+			return &ctx.Thoughts[i]
+		}
+	}
+	return nil
+}
+
+// Focused returns the focused thought, or nil if there is none.
+func (ctx *MindContext) Focused() *Thought {
+	if ctx.CurrentFocusIndex < 0 || ctx.CurrentFocusIndex >= len(ctx.Thoughts) {
+		return nil
+	}
+	return &ctx.Thoughts[ctx.CurrentFocusIndex]
+}
+
+// FocusedClarity returns the clarity of the focused thought, or 0 without focus.
+func (ctx *MindContext) FocusedClarity() float64 {
+	if t := ctx.Focused(); t != nil {
+		return t.Clarity
+	}
+	return 0
+}
+
+// removeFocused drops the focused thought from the mind and clears focus.
+func (ctx *MindContext) removeFocused() {
+	ctx.removeThought(ctx.CurrentFocusIndex)
+}
+
+// removeThought drops the thought at index i and its associations, keeping the
+// focus on the same thought if it was elsewhere and clearing it if it was this
+// one.
+func (ctx *MindContext) removeThought(i int) {
+	ctx.unlink(ctx.Thoughts[i].ID)
+	ctx.Thoughts = append(ctx.Thoughts[:i], ctx.Thoughts[i+1:]...)
+	switch {
+	case ctx.CurrentFocusIndex == i:
+		ctx.CurrentFocusIndex = -1
+	case ctx.CurrentFocusIndex > i:
+		ctx.CurrentFocusIndex--
+	}
+}
@@ -0,0 +1,303 @@
+// thoughtsource.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ThoughtSource supplies the text of the thoughts 'generate' produces. Each
+// mind has its own, so entities can be given different backgrounds.
+type ThoughtSource interface {
+	Kind() string // Registered name, used in save files
+	Name() string // Where the thoughts come from, for display
+	Generate(ctx *MindContext) string
+}
+
+// thoughtSourceKinds maps the kinds used in save files to empty sources for
+// their saved data to be decoded into.
+var thoughtSourceKinds = map[string]func() ThoughtSource{
+	"corpus": func() ThoughtSource { return &Corpus{} },
+	"markov": func() ThoughtSource { return &MarkovModel{} },
+	"llm":    func() ThoughtSource { return &LLMSource{} },
+}
+
+// Corpus is a fixed list of phrases, one of which is picked at random.
+type Corpus struct {
+	Label   string   `json:"name"`
+	Phrases []string `json:"phrases"`
+}
+
+func (c *Corpus) Kind() string { return "corpus" }
+func (c *Corpus) Name() string { return c.Label }
+
+func (c *Corpus) Generate(ctx *MindContext) string {
+	return c.Phrases[ctx.Rand.IntN(len(c.Phrases))]
+}
+
+func (c *Corpus) validate() error {
+	if len(c.Phrases) == 0 {
+		return errors.New("corpus has no phrases")
+	}
+	return nil
+}
+
+var defaultThoughtSource = &Corpus{Label: "built-in", Phrases: potentialThoughts}
+
+// DefaultThoughtSource returns the built-in phrases every mind starts with.
+func DefaultThoughtSource() ThoughtSource { return defaultThoughtSource }
+
+// LoadCorpus reads phrases from a file, choosing the format by extension:
+// ".jsonl" is one text record per line, ".json" is a ChatGPT conversation
+// export, and anything else is plain text. Every format is split into
+// sentences.
+func LoadCorpus(filename string) (*Corpus, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var texts []string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".jsonl":
+		texts, err = parseJSONLCorpus(data)
+	case ".json":
+		texts, err = parseChatGPTExport(data)
+	default:
+		texts = []string{string(data)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	corpus := &Corpus{Label: filepath.Base(filename), Phrases: extractSentences(texts...)}
+	if len(corpus.Phrases) == 0 {
+		return nil, fmt.Errorf("%s: no usable sentences", filename)
+	}
+	return corpus, nil
+}
+
+// parseJSONLCorpus reads one record per line: either a JSON string or an
+// object with a "text" field.
+func parseJSONLCorpus(data []byte) ([]string, error) {
+	var texts []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var text string
+		if raw[0] != '"' {
+			var record struct {
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			text = record.Text
+		} else if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		texts = append(texts, text)
+	}
+	return texts, scanner.Err()
+}
+
+// chatGPTConversation is the part of a ChatGPT export we read: the message
+// tree of one conversation.
+type chatGPTConversation struct {
+	Mapping map[string]struct {
+		Message *struct {
+			Author struct {
+				Role string `json:"role"`
+			} `json:"author"`
+			CreateTime float64 `json:"create_time"`
+			Content    struct {
+				ContentType string `json:"content_type"`
+				Parts       []any  `json:"parts"` // Strings for text; objects for attachments
+			} `json:"content"`
+		} `json:"message"`
+	} `json:"mapping"`
+}
+
+// parseChatGPTExport returns the text the user and the assistant wrote, in
+// the order it was written. The export is either a list of conversations, as
+// in conversations.json, or a single one.
+func parseChatGPTExport(data []byte) ([]string, error) {
+	var conversations []chatGPTConversation
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		conversations = make([]chatGPTConversation, 1)
+		if err := json.Unmarshal(trimmed, &conversations[0]); err != nil {
+			return nil, fmt.Errorf("not a ChatGPT export: %w", err)
+		}
+	} else if err := json.Unmarshal(data, &conversations); err != nil {
+		return nil, fmt.Errorf("not a ChatGPT export: %w", err)
+	}
+
+	type message struct {
+		time float64
+		id   string
+		text string
+	}
+	var texts []string
+	for _, conversation := range conversations {
+		var messages []message
+		for id, node := range conversation.Mapping {
+			m := node.Message
+			if m == nil || m.Content.ContentType != "text" || (m.Author.Role != "user" && m.Author.Role != "assistant") {
+				continue
+			}
+			for _, part := range m.Content.Parts {
+				if text, ok := part.(string); ok && strings.TrimSpace(text) != "" {
+					messages = append(messages, message{m.CreateTime, id, text})
+				}
+			}
+		}
+		// The mapping is a map; sort so the same export always gives the same corpus.
+		sort.SliceStable(messages, func(i, j int) bool {
+			if messages[i].time != messages[j].time {
+				return messages[i].time < messages[j].time
+			}
+			return messages[i].id < messages[j].id
+		})
+		for _, m := range messages {
+			texts = append(texts, m.text)
+		}
+	}
+	if len(texts) == 0 {
+		return nil, errors.New("export has no user or assistant text")
+	}
+	return texts, nil
+}
+
+var (
+	codeBlock      = regexp.MustCompile("(?s)```.*?```")
+	citation       = regexp.MustCompile(`\x{E200}[^\x{E201}]*\x{E201}`) // ChatGPT's inline source markers
+	markdownPrefix = regexp.MustCompile(`^\s*(#+|>+|[-*+]|\d+[.)])\s+`)
+	markdownMarks  = strings.NewReplacer("**", "", "__", "", "`", "", "*", "", "“", "", "”", "", "\"", "")
+	sentenceEnd    = regexp.MustCompile(`[.!?]+(\s+|$)`)
+)
+
+// Sentences shorter or longer than this many words don't read as thoughts.
+const (
+	minSentenceWords = 4
+	maxSentenceWords = 24
+)
+
+// extractSentences splits prose into distinct sentences that read as
+// thoughts, dropping markdown, code and links.
+func extractSentences(texts ...string) []string {
+	var sentences []string
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		text = citation.ReplaceAllString(codeBlock.ReplaceAllString(text, "\n"), "")
+		for _, line := range strings.Split(text, "\n") {
+			line = markdownMarks.Replace(markdownPrefix.ReplaceAllString(line, ""))
+			for _, s := range sentenceEnd.Split(line, -1) {
+				s = strings.TrimLeftFunc(strings.Join(strings.Fields(s), " "), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+				s = strings.TrimRight(s, " ,;:-'(")
+				words := len(strings.Fields(s))
+				if words < minSentenceWords || words > maxSentenceWords || strings.Contains(s, "http") || seen[s] {
+					continue
+				}
+				seen[s] = true
+				sentences = append(sentences, s)
+			}
+		}
+	}
+	return sentences
+}
+
+// SavedThoughtSource is a ThoughtSource as written in save files.
+type SavedThoughtSource struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+// saveThoughtSource wraps a source for saving, or returns nil for the
+// built-in one.
+func saveThoughtSource(source ThoughtSource) (*SavedThoughtSource, error) {
+	if source == nil || source == DefaultThoughtSource() {
+		return nil, nil
+	}
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedThoughtSource{Kind: source.Kind(), Data: data}, nil
+}
+
+// restore rebuilds the saved source.
+func (s *SavedThoughtSource) restore() (ThoughtSource, error) {
+	newSource, ok := thoughtSourceKinds[s.Kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown thought source kind %q", s.Kind)
+	}
+	source := newSource()
+	if err := json.Unmarshal(s.Data, source); err != nil {
+		return nil, fmt.Errorf("thought source: %w", err)
+	}
+	if v, ok := source.(interface{ validate() error }); ok {
+		if err := v.validate(); err != nil {
+			return nil, fmt.Errorf("thought source: %w", err)
+		}
+	}
+	return source, nil
+}
+
+// corpusFlags collects repeated --corpus [entity=]file flags.
+type corpusFlags []string
+
+func (c *corpusFlags) String() string { return strings.Join(*c, ",") }
+
+func (c *corpusFlags) Set(s string) error {
+	*c = append(*c, s)
+	return nil
+}
+
+// apply loads each corpus and gives it to the named entity, or to every
+// entity if no name is given. Later flags win.
+func (c corpusFlags) apply(entities []*Entity) error {
+	for _, spec := range c {
+		err := assignThoughtSource(entities, spec, func(filename string) (ThoughtSource, error) {
+			return LoadCorpus(filename)
+		})
+		if err != nil {
+			return fmt.Errorf("--corpus %s: %w", spec, err)
+		}
+	}
+	return nil
+}
+
+// assignThoughtSource loads the source an [entity=]file flag names and gives
+// it to that entity, or to every entity if no name is given.
+func assignThoughtSource(entities []*Entity, spec string, load func(filename string) (ThoughtSource, error)) error {
+	id, filename, ok := strings.Cut(spec, "=")
+	if !ok {
+		id, filename = "", spec
+	}
+	source, err := load(filename)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, e := range entities {
+		if id == "" || e.ID == id {
+			e.Mind.ThoughtSource = source
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no entity %q", id)
+	}
+	return nil
+}
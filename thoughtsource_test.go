@@ -0,0 +1,120 @@
+// thoughtsource_test.go
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeCorpus(t *testing.T, name, contents string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestExtractSentences(t *testing.T) {
+	got := extractSentences("## Embodiment\n\n* **The body shapes** what the mind can hold. Short one!\n```go\nfmt.Println(\"code is not a thought at all\")\n```\n2. Meaning is built in conversation? See https://example.com for more on this.\nThe body shapes what the mind can hold.")
+	want := []string{"The body shapes what the mind can hold", "Meaning is built in conversation"}
+	if !slices.Equal(got, want) {
+		t.Errorf("extractSentences: Expected %q, got %q", want, got)
+	}
+}
+
+func TestLoadCorpus_Formats(t *testing.T) {
+	text := writeCorpus(t, "notes.txt", "Attention is a kind of hunger. It never stops.\nSilence can be full of meaning.\n")
+	jsonl := writeCorpus(t, "notes.jsonl", "\"attention is a kind of hunger\"\n{\"text\": \"silence can be full of meaning\", \"id\": 2}\n")
+	for _, filename := range []string{text, jsonl} {
+		corpus, err := LoadCorpus(filename)
+		if err != nil {
+			t.Fatalf("LoadCorpus(%s): %v", filepath.Base(filename), err)
+		}
+		if len(corpus.Phrases) != 2 || !strings.EqualFold(corpus.Phrases[1], "silence can be full of meaning") || corpus.Name() != filepath.Base(filename) {
+			t.Errorf("LoadCorpus(%s): Unexpected corpus %+v", filepath.Base(filename), corpus)
+		}
+	}
+	if _, err := LoadCorpus(writeCorpus(t, "empty.txt", "Too short.")); err == nil {
+		t.Errorf("LoadCorpus: Expected an error for a file without usable sentences")
+	}
+	if _, err := LoadCorpus(writeCorpus(t, "bad.jsonl", "{\"text\": 1}\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("LoadCorpus: Expected a JSONL error naming the line, got %v", err)
+	}
+}
+
+func TestLoadCorpus_ChatGPTExport(t *testing.T) {
+	corpus, err := LoadCorpus("ChatGPT-Consciousness_and_Embodiment.json")
+	if err != nil {
+		t.Fatalf("LoadCorpus: %v", err)
+	}
+	if len(corpus.Phrases) < 100 {
+		t.Errorf("LoadCorpus: Expected hundreds of sentences from the export, got %d", len(corpus.Phrases))
+	}
+	if !strings.HasPrefix(corpus.Phrases[0], "i would like to discuss reality") {
+		t.Errorf("LoadCorpus: Expected the conversation in order, starting with the user, got %q", corpus.Phrases[0])
+	}
+	for _, p := range corpus.Phrases {
+		if strings.ContainsRune(p, '\uE200') {
+			t.Errorf("LoadCorpus: Citation marker left in %q", p)
+		}
+	}
+	again, _ := LoadCorpus("ChatGPT-Consciousness_and_Embodiment.json")
+	if !slices.Equal(corpus.Phrases, again.Phrases) {
+		t.Errorf("LoadCorpus: Expected the same export to give the same corpus")
+	}
+}
+
+func TestCorpusFlags_PerEntity(t *testing.T) {
+	filename := writeCorpus(t, "alpha.txt", "Alpha only ever thinks this one thought.")
+	entities := NewDefaultEntities()
+	if err := (corpusFlags{"AI-Alpha=" + filename}).apply(entities); err != nil {
+		t.Fatalf("corpusFlags: %v", err)
+	}
+	player, ai := entities[0], entities[1]
+	if player.Mind.ThoughtSource != DefaultThoughtSource() || ai.Mind.ThoughtSource.Name() != "alpha.txt" {
+		t.Errorf("corpusFlags: Expected only AI-Alpha to get the corpus")
+	}
+	generateEffect(ai.ID, ai.Mind, nil)
+	if got := ai.Mind.Thoughts[0].Text; got != "Alpha only ever thinks this one thought" {
+		t.Errorf("generate: Expected a thought from the corpus, got %q", got)
+	}
+	if err := (corpusFlags{"Nobody=" + filename}).apply(entities); err == nil {
+		t.Errorf("corpusFlags: Expected an error for an unknown entity")
+	}
+}
+
+func TestSaveGame_KeepsThoughtSources(t *testing.T) {
+	entities := NewDefaultEntities()
+	entities[1].Mind.ThoughtSource = &Corpus{Label: "alpha.txt", Phrases: []string{"one", "two", "three"}}
+	original := NewSimulation(9, entities)
+	original.SetAutopilot(true)
+	original.Run(context.Background(), 20)
+
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, original); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	if loaded.Entities()[0].Mind.ThoughtSource != DefaultThoughtSource() || loaded.Entities()[1].Mind.ThoughtSource.Name() != "alpha.txt" {
+		t.Fatalf("loadGame: Expected thought sources restored per entity")
+	}
+	original.Run(context.Background(), 100)
+	loaded.Run(context.Background(), 100)
+	if mindsJSON(t, original) != mindsJSON(t, loaded) {
+		t.Errorf("Loaded game with a corpus diverged from the original")
+	}
+
+	data, _ := os.ReadFile(filename)
+	broken := strings.Replace(string(data), `"kind": "corpus"`, `"kind": "oracle"`, 1)
+	if _, err := loadGame(writeSave(t, broken)); err == nil || !strings.Contains(err.Error(), `unknown thought source kind "oracle"`) {
+		t.Errorf("loadGame: Expected an unknown thought source error, got %v", err)
+	}
+}
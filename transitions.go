@@ -0,0 +1,301 @@
+// transitions.go
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TransitionRule says what a command does in a state: the guard it must pass,
+// the energy it costs, the effects it runs and the state it leads to.
+type TransitionRule struct {
+	From    string   `json:"from"`
+	Command string   `json:"command"`
+	Usage   string   `json:"usage,omitempty"` // Shown in prompts and when arguments are missing; defaults to Command
+	Guard   Guard    `json:"guard,omitzero"`
+	Cost    Amount   `json:"cost,omitzero"`     // Charged only if every effect succeeds
+	Effects []string `json:"effects,omitempty"` // Names from the effects registry, run in order
+	To      string   `json:"to,omitempty"`      // Empty to stay in From
+}
+
+// Guard lists the conditions a command must meet before any effect runs.
+// They are checked in field order and the first failure is reported.
+type Guard struct {
+	MinEnergy     Amount `json:"min_energy,omitzero"` // Raised to the rule's Cost if lower
+	MinArgs       int    `json:"min_args,omitempty"`
+	RequiresFocus bool   `json:"requires_focus,omitempty"`
+}
+
+// Amount is a rule quantity: either a literal number or the name of an
+// integer Config parameter such as "generate_cost", looked up when the rule
+// is applied.
+type Amount struct {
+	Value int
+	Param string
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.Param != "" {
+		return json.Marshal(a.Param)
+	}
+	return json.Marshal(a.Value)
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	*a = Amount{}
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &a.Param)
+	}
+	return json.Unmarshal(data, &a.Value)
+}
+
+// resolve returns the amount under cfg.
+func (a Amount) resolve(cfg *Config) int {
+	if a.Param == "" {
+		return a.Value
+	}
+	n, _ := cfg.Int(a.Param) // Checked by init
+	return n
+}
+
+// validate checks that a named amount refers to an integer parameter.
+func (a Amount) validate() error {
+	if a.Param != "" {
+		if _, ok := DefaultConfig().Int(a.Param); !ok {
+			return fmt.Errorf("%q is not an integer config parameter", a.Param)
+		}
+	} else if a.Value < 0 {
+		return errors.New("must not be negative")
+	}
+	return nil
+}
+
+// TransitionTable is the cognitive architecture: every state's commands as
+// data. States hand their input to it, so researchers can change the rules
+// without recompiling.
+type TransitionTable struct {
+	Rules []TransitionRule `json:"rules"`
+
+	index map[string]map[string]*TransitionRule // From -> Command -> rule
+}
+
+// Effect carries out the part of a command that goes beyond energy and state
+// changes. It returns the events it produced and whether it succeeded; a
+// failed effect aborts the command without charging its cost.
+type Effect func(entityID string, ctx *MindContext, args []string) ([]Event, bool)
+
+// registeredEffect is an effect and what it relies on its rule's guard for:
+// the arguments it reads and whether it needs a focused thought. Rules whose
+// guard falls short of this are rejected when a table is loaded.
+type registeredEffect struct {
+	run     Effect
+	needs   Guard
+	indices []string // What each leading argument indexes: thoughtIndex or perceptionIndex
+}
+
+// The lists an effect's arguments can index into.
+const (
+	thoughtIndex    = "thought"
+	perceptionIndex = "perception"
+)
+
+// effects maps the names used in transition tables to their implementations.
+var effects = map[string]registeredEffect{
+	"recharge":   {run: rechargeEffect},
+	"generate":   {run: generateEffect},
+	"focus":      {run: focusEffect, needs: Guard{MinArgs: 1}, indices: []string{thoughtIndex}},
+	"accept":     {run: acceptEffect, needs: Guard{MinArgs: 1}, indices: []string{perceptionIndex}},
+	"ignore":     {run: ignoreEffect, needs: Guard{MinArgs: 1}, indices: []string{perceptionIndex}},
+	"integrate":  {run: integrateEffect, needs: Guard{MinArgs: 1, RequiresFocus: true}, indices: []string{perceptionIndex}},
+	"combine":    {run: combineEffect, needs: Guard{MinArgs: 2}, indices: []string{thoughtIndex, thoughtIndex}},
+	"introspect": {run: introspectEffect, needs: Guard{RequiresFocus: true}},
+	"unfocus":    {run: unfocusEffect, needs: Guard{RequiresFocus: true}},
+	"express":    {run: expressEffect, needs: Guard{RequiresFocus: true}},
+	"evolve":     {run: evolveEffect, needs: Guard{MinArgs: 2}},
+	"dream":      {run: dreamEffect},
+	"listen":     {run: listenEffect},
+	"observe":    {run: observeEffect},
+	"qualia":     {run: qualiaEffect, needs: Guard{MinArgs: 1}, indices: []string{thoughtIndex}},
+	"goal":       {run: goalEffect, needs: Guard{MinArgs: 1}},
+}
+
+//go:embed transitions.json
+var defaultTransitionsJSON []byte
+
+var defaultTransitions = func() *TransitionTable {
+	t, err := ParseTransitions(defaultTransitionsJSON)
+	if err != nil {
+		panic("built-in transitions.json: " + err.Error())
+	}
+	return t
+}()
+
+// DefaultTransitions returns the built-in table, the one in transitions.json.
+func DefaultTransitions() *TransitionTable { return defaultTransitions }
+
+// LoadTransitions reads a transition table from a JSON file.
+func LoadTransitions(filename string) (*TransitionTable, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ParseTransitions(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// ParseTransitions decodes and validates a transition table.
+func ParseTransitions(data []byte) (*TransitionTable, error) {
+	var t TransitionTable
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("invalid transition table: %w", err)
+	}
+	if err := t.init(); err != nil {
+		return nil, fmt.Errorf("invalid transition table: %w", err)
+	}
+	return &t, nil
+}
+
+// init validates the rules, reporting every problem, and indexes them.
+func (t *TransitionTable) init() error {
+	var errs []error
+	t.index = make(map[string]map[string]*TransitionRule)
+	for i := range t.Rules {
+		rule := &t.Rules[i]
+		where := fmt.Sprintf("rules[%d] (%s %s)", i, rule.From, rule.Command)
+		if _, err := getStateByName(rule.From); err != nil {
+			errs = append(errs, fmt.Errorf("%s: from: %w", where, err))
+		}
+		if rule.To != "" {
+			if _, err := getStateByName(rule.To); err != nil {
+				errs = append(errs, fmt.Errorf("%s: to: %w", where, err))
+			}
+		}
+		if rule.Command == "" || strings.ContainsAny(rule.Command, " \t") {
+			errs = append(errs, fmt.Errorf("%s: command must be a single word", where))
+		}
+		if err := rule.Cost.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: cost: %w", where, err))
+		}
+		if err := rule.Guard.MinEnergy.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: min_energy: %w", where, err))
+		}
+		if rule.Guard.MinArgs < 0 {
+			errs = append(errs, fmt.Errorf("%s: min_args must not be negative", where))
+		}
+		for _, name := range rule.Effects {
+			effect, ok := effects[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown effect %q", where, name))
+				continue
+			}
+			if rule.Guard.MinArgs < effect.needs.MinArgs {
+				errs = append(errs, fmt.Errorf("%s: effect %q needs min_args of at least %d", where, name, effect.needs.MinArgs))
+			}
+			if effect.needs.RequiresFocus && !rule.Guard.RequiresFocus {
+				errs = append(errs, fmt.Errorf("%s: effect %q needs requires_focus", where, name))
+			}
+		}
+		if t.index[rule.From] == nil {
+			t.index[rule.From] = make(map[string]*TransitionRule)
+		}
+		if t.index[rule.From][rule.Command] != nil {
+			errs = append(errs, fmt.Errorf("%s: duplicate rule", where))
+		}
+		t.index[rule.From][rule.Command] = rule
+	}
+	return errors.Join(errs...)
+}
+
+// indices says what each of the rule's leading arguments indexes, as its
+// effects read them. Where effects disagree the first one wins.
+func (rule *TransitionRule) indices() []string {
+	var indices []string
+	for _, name := range rule.Effects {
+		for i, kind := range effects[name].indices {
+			if i == len(indices) {
+				indices = append(indices, kind)
+			}
+		}
+	}
+	return indices
+}
+
+// Lookup returns the rule for a command in a state, or nil if there is none.
+func (t *TransitionTable) Lookup(state, command string) *TransitionRule {
+	return t.index[state][command]
+}
+
+// Commands lists the usages of a state's commands in table order, for prompts.
+func (t *TransitionTable) Commands(state string) []string {
+	var usages []string
+	for _, rule := range t.Rules {
+		if rule.From != state {
+			continue
+		}
+		if rule.Usage != "" {
+			usages = append(usages, rule.Usage)
+		} else {
+			usages = append(usages, rule.Command)
+		}
+	}
+	return usages
+}
+
+// Apply runs a command typed in state from: it checks the rule's guard, runs
+// its effects, charges its cost and moves to its target state.
+func (t *TransitionTable) Apply(from State, entityID string, ctx *MindContext, parts []string) (State, []Event) {
+	command := parts[0]
+	rule := t.Lookup(from.GetName(), command)
+	if rule == nil {
+		return from, []Event{unknown(entityID, command, from)}
+	}
+	if failed, ok := rule.check(entityID, ctx, parts[1:]); !ok {
+		return from, []Event{failed}
+	}
+
+	var events []Event
+	for _, name := range rule.Effects {
+		produced, ok := effects[name].run(entityID, ctx, parts[1:])
+		events = append(events, produced...)
+		if !ok {
+			return from, events
+		}
+	}
+	ctx.Energy -= rule.Cost.resolve(ctx.Config)
+
+	if rule.To == "" || rule.To == from.GetName() {
+		return from, events
+	}
+	to, _ := getStateByName(rule.To) // Checked by init
+	return to, append(events, transition(entityID, from, to))
+}
+
+// check evaluates the rule's guard, returning the event explaining the first
+// condition that fails.
+func (rule *TransitionRule) check(entityID string, ctx *MindContext, args []string) (Event, bool) {
+	minEnergy := max(rule.Guard.MinEnergy.resolve(ctx.Config), rule.Cost.resolve(ctx.Config))
+	if ctx.Energy < minEnergy {
+		return lowEnergy(entityID, rule.Command, ctx, minEnergy), false
+	}
+	if len(args) < rule.Guard.MinArgs {
+		usage := rule.Usage
+		if usage == "" {
+			usage = rule.Command
+		}
+		return invalid(entityID, rule.Command, "usage is "+usage), false
+	}
+	if rule.Guard.RequiresFocus && ctx.Focused() == nil {
+		return invalid(entityID, rule.Command, "no thought is focused"), false
+	}
+	return Event{}, true
+}
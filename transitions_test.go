@@ -0,0 +1,137 @@
+// transitions_test.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cheapThinking is a custom architecture: thinking is free, and generating is
+// possible straight from Idle.
+const cheapThinking = `{"rules": [
+	{"from": "Idle", "command": "think", "to": "Thinking"},
+	{"from": "Idle", "command": "generate", "cost": 3, "effects": ["generate"]},
+	{"from": "Thinking", "command": "idle", "to": "Idle"}
+]}`
+
+func TestTransitionTable_DrivesStates(t *testing.T) {
+	table, err := ParseTransitions([]byte(cheapThinking))
+	if err != nil {
+		t.Fatalf("ParseTransitions: %v", err)
+	}
+	ctx := NewMindContext(DefaultConfig())
+	ctx.Rules = table
+	ctx.Energy = 10
+
+	next, _ := (&IdleState{}).HandleInput("e", ctx, []string{"think"})
+	assertStateType(t, &ThinkingState{}, next)
+	if ctx.Energy != 10 {
+		t.Errorf("think: Expected no cost, energy is %d", ctx.Energy)
+	}
+	_, events := next.HandleInput("e", ctx, []string{"generate"})
+	if len(events) != 1 || events[0].Kind != EventUnknownCommand {
+		t.Errorf("generate in Thinking: Expected UnknownCommand without a rule, got %+v", events)
+	}
+	next, events = (&IdleState{}).HandleInput("e", ctx, []string{"generate"})
+	assertStateType(t, &IdleState{}, next)
+	if len(ctx.Thoughts) != 1 || ctx.Energy != 7 || events[0].Kind != EventThoughtGenerated {
+		t.Errorf("generate in Idle: Expected a thought for 3 energy, got %d thoughts, energy %d, %+v", len(ctx.Thoughts), ctx.Energy, events)
+	}
+	if got := (&IdleState{}).GetPrompt(&Entity{ID: "e", Mind: ctx}); !strings.Contains(got, "[think | generate | view | quit]") {
+		t.Errorf("GetPrompt: Expected commands from the table, got %q", got)
+	}
+}
+
+func TestTransitionTable_GuardsAndCosts(t *testing.T) {
+	ctx := NewMindContext(DefaultConfig())
+	ctx.AddThought("a thought", OriginGenerated).Clarity = 0.2
+	ctx.CurrentFocusIndex = 0
+	ctx.Energy = 30
+
+	// A failing effect charges nothing.
+	_, events := (&ActingState{}).HandleInput("e", ctx, []string{"express"})
+	if events[0].Kind != EventExpressFailed || ctx.Energy != 30 {
+		t.Errorf("express: Expected ExpressFailed at no cost, got %+v with energy %d", events, ctx.Energy)
+	}
+	// Missing arguments are reported with the rule's usage.
+	_, events = (&ThinkingState{}).HandleInput("e", ctx, []string{"focus"})
+	if events[0].Kind != EventInvalidCommand || !strings.Contains(events[0].Reason, "focus <index>") {
+		t.Errorf("focus: Expected usage, got %+v", events)
+	}
+	// The guard's energy floor applies even to cheaper commands.
+	ctx.Energy = 8
+	_, events = (&ThinkingState{}).HandleInput("e", ctx, []string{"ignore", "0"})
+	if events[0].Kind != EventEnergyInsufficient || events[0].Required != 10 {
+		t.Errorf("ignore: Expected EnergyInsufficient 10, got %+v", events)
+	}
+}
+
+func TestParseTransitions_ReportsEveryProblem(t *testing.T) {
+	_, err := ParseTransitions([]byte(`{"rules": [
+		{"from": "Sleeping", "command": "think", "to": "Thinking"},
+		{"from": "Idle", "command": "think", "to": "Nowhere", "effects": ["teleport"]},
+		{"from": "Idle", "command": "think", "cost": -1}
+	]}`))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{`"Sleeping"`, `"Nowhere"`, `"teleport"`, "negative", "duplicate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+	if _, err := ParseTransitions([]byte(`{"rules": [{"from": "Idle", "command": "think", "price": 5}]}`)); err == nil {
+		t.Error("Expected unknown fields to be rejected")
+	}
+}
+
+func TestParseTransitions_RequiresTheGuardsEffectsRelyOn(t *testing.T) {
+	_, err := ParseTransitions([]byte(`{"rules": [
+		{"from": "Reflecting", "command": "introspect", "effects": ["introspect"]},
+		{"from": "Acting", "command": "express", "effects": ["express"]},
+		{"from": "Thinking", "command": "focus", "effects": ["focus"]},
+		{"from": "Idle", "command": "goal", "effects": ["goal"]},
+		{"from": "Thinking", "command": "combine", "guard": {"min_args": 1}, "effects": ["combine"]}
+	]}`))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{
+		`(Reflecting introspect): effect "introspect" needs requires_focus`,
+		`(Acting express): effect "express" needs requires_focus`,
+		`(Thinking focus): effect "focus" needs min_args of at least 1`,
+		`(Idle goal): effect "goal" needs min_args of at least 1`,
+		`(Thinking combine): effect "combine" needs min_args of at least 2`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestSaveGame_KeepsCustomTransitions(t *testing.T) {
+	table, _ := ParseTransitions([]byte(cheapThinking))
+	sim := NewSimulation(1, NewDefaultEntities())
+	sim.SetTransitions(table)
+	filename := filepath.Join(t.TempDir(), "save.json")
+	if err := saveGame(filename, sim); err != nil {
+		t.Fatalf("saveGame: %v", err)
+	}
+	loaded, err := loadGame(filename)
+	if err != nil {
+		t.Fatalf("loadGame: %v", err)
+	}
+	if rule := loaded.Transitions().Lookup("Idle", "generate"); rule == nil || rule.Cost.Value != 3 {
+		t.Errorf("Expected the custom table back, got rule %+v", rule)
+	}
+	if loaded.Entities()[0].Mind.Rules != loaded.Transitions() {
+		t.Errorf("Expected loaded minds to use the loaded table")
+	}
+
+	plain := filepath.Join(t.TempDir(), "plain.json")
+	saveGame(plain, NewSimulation(1, NewDefaultEntities()))
+	if loaded, _ := loadGame(plain); loaded.Transitions() != DefaultTransitions() {
+		t.Errorf("Expected a save without a table to use the built-in one")
+	}
+}